@@ -2,16 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/bigelle/ratebucket"
 	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/grpcapi"
 	"github.com/bigelle/warehouse/internal/handlers"
-	"github.com/jackc/pgx/v5"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -27,14 +37,40 @@ func main() {
 		logger.Fatal("failed to load environment variables", zap.Error(err))
 	}
 
+	// stopped by SIGINT or SIGTERM, the background work goes with it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// DATABASE:
-	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, os.Getenv("DATABASE_URL"))
+	// a pool, handlers run concurrently and a single connection can't be shared
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
 	if err != nil {
 		logger.Fatal("failed to connect to the database", zap.Error(err))
 	}
-	defer conn.Close(ctx)
-	queries := database.New(conn)
+	defer pool.Close()
+	if err := pool.Ping(ctx); err != nil {
+		logger.Fatal("failed to connect to the database", zap.Error(err))
+	}
+	queries := database.New(pool)
+
+	// STORAGE:
+	attachmentsDir := os.Getenv("ATTACHMENTS_DIR")
+	if attachmentsDir == "" {
+		attachmentsDir = "attachments"
+	}
+	store, err := storage.NewLocal(attachmentsDir)
+	if err != nil {
+		logger.Fatal("failed to prepare attachment storage", zap.Error(err))
+	}
+
+	var attachmentMaxSize int64
+	if v := os.Getenv("ATTACHMENT_MAX_SIZE"); v != "" {
+		// in bytes
+		attachmentMaxSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			logger.Fatal("invalid ATTACHMENT_MAX_SIZE", zap.Error(err))
+		}
+	}
 
 	// RATE LIMITER:
 	authRL := handlers.RateLimiter{
@@ -53,49 +89,94 @@ func main() {
 		}),
 	}
 
+	// IDEMPOTENCY:
+	var idempotencyTTL time.Duration
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		idempotencyTTL, err = time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid IDEMPOTENCY_KEY_TTL", zap.Error(err))
+		}
+	}
+
+	// EVENTS:
+	var eventsRetention time.Duration
+	if v := os.Getenv("EVENTS_RETENTION"); v != "" {
+		eventsRetention, err = time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid EVENTS_RETENTION", zap.Error(err))
+		}
+	}
+
+	// WEBHOOKS:
+	var webhookAllowPrivate bool
+	if v := os.Getenv("WEBHOOKS_ALLOW_PRIVATE"); v != "" {
+		webhookAllowPrivate, err = strconv.ParseBool(v)
+		if err != nil {
+			logger.Fatal("invalid WEBHOOKS_ALLOW_PRIVATE", zap.Error(err))
+		}
+	}
+
 	// APP:
 	app := handlers.App{
 		DB: handlers.Database{
-			Conn:    conn,
+			Pool:    pool,
 			Queries: queries,
 		},
-		Logger: logger,
+		Logger:  logger,
+		Storage: store,
+		Config: handlers.Config{
+			AttachmentMaxSize:   attachmentMaxSize,
+			IdempotencyKeyTTL:   idempotencyTTL,
+			EventsRetention:     eventsRetention,
+			WebhookAllowPrivate: webhookAllowPrivate,
+		},
+		Events: handlers.NewEventHub(pool, logger),
+	}
+	var background sync.WaitGroup
+	background.Go(func() { app.PruneIdempotencyKeys(ctx, time.Hour) })
+	background.Go(func() { app.PruneEvents(ctx, time.Hour) })
+	background.Go(func() { app.Events.Listen(ctx) })
+	background.Go(func() { app.DeliverWebhooks(ctx, 5*time.Second) })
+	background.Go(func() { app.RunImportJobs(ctx, 2*time.Second) })
+
+	// GRPC:
+	// optional, on a port of its own next to the REST API
+	var grpcServer *grpc.Server
+	if addr := os.Getenv("GRPC_LISTEN_ADDR"); addr != "" {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			logger.Fatal("failed to listen for grpc", zap.Error(err))
+		}
+		grpcServer = grpcapi.New(app, authRL, RL)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Fatal("grpc server error", zap.Error(err))
+			}
+		}()
 	}
 
 	// ROUTER:
-	r := echo.New()
-	r.Use(
-		middleware.Recover(),
-		app.LoggingMiddleware,
-	)
-	r.Pre(middleware.RemoveTrailingSlash())
-
-	// Unprotected routes:
-	auth := r.Group("/auth", authRL.Middleware)
-	auth.POST("/register", app.HandleRegister)
-	auth.POST("/login", app.HandleLogin)
-	auth.POST("/refresh", app.HandleRefresh)
-
-	// Protected routes:
-
-	items := r.Group("/items", RL.Middleware, app.JWTMiddleware)
-	// user or higher:
-	items.GET("", app.HandleGetItems)
-	items.GET("/:uuid", app.HandleGetSingleItem)
-	// admin only:
-	items.POST("", app.HandleCreateItem)
-	items.PATCH("/:uuid", app.HandlePatchItem)
-	items.DELETE("/:uuid", app.HandleDeleteItem)
-
-	transactions := r.Group("/transactions", app.JWTMiddleware)
-	// user or higher
-	transactions.GET("", app.HandleGetAllTransactions)
-	transactions.GET("/:uuid", app.HandleGetTransaction)
-	// stocker or higher
-	transactions.POST("", app.HandleCreateTransaction)
+	r := router.New(app, authRL, RL)
 
 	// RUN:
-	if err := r.Start(os.Getenv("SERVER_LISTEN_ADDR")); err != nil {
-		logger.Fatal("server error", zap.Error(err))
+	go func() {
+		if err := r.Start(os.Getenv("SERVER_LISTEN_ADDR")); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("server error", zap.Error(err))
+		}
+	}()
+
+	// SHUTDOWN:
+	<-ctx.Done()
+	stop()
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down the server", zap.Error(err))
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
 	}
+	// the pool is closed after the workers are done with it
+	background.Wait()
 }
@@ -0,0 +1,93 @@
+// Command reconcile compares the items.quantity projections with the stock
+// ledger and reports every item that drifted. With -fix it recomputes them
+// from the ledger. Exits with 1 when there is unfixed drift.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "recompute the drifted quantities from the ledger")
+	flag.Parse()
+
+	// LOGGER:
+	logger, _ := zap.NewProduction(
+		zap.AddStacktrace(zap.FatalLevel),
+		zap.WithCaller(false),
+	)
+	defer logger.Sync()
+
+	// ENVIRONMENT:
+	if err := godotenv.Load(".env"); err != nil {
+		logger.Fatal("failed to load environment variables", zap.Error(err))
+	}
+
+	// DATABASE:
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		logger.Fatal("failed to connect to the database", zap.Error(err))
+	}
+	defer pool.Close()
+	queries := database.New(pool)
+
+	drifted, err := queries.GetStockDrift(ctx)
+	if err != nil {
+		logger.Fatal("failed to compare the stock with the ledger", zap.Error(err))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tNAME\tPROJECTED\tLEDGER\tDRIFT\tFIXED")
+	nFixed := 0
+	for _, d := range drifted {
+		fixed := "-"
+		if *fix {
+			qty, err := recompute(ctx, pool, queries, d.Uuid)
+			if err != nil {
+				logger.Error("failed to recompute the quantity", zap.String("item", d.Uuid.String()), zap.Error(err))
+				fixed = "error"
+			} else {
+				fixed = fmt.Sprint(qty)
+				nFixed++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%+d\t%s\n", d.Uuid.String(), d.Name, d.Projected, d.Ledger, d.Projected-d.Ledger, fixed)
+	}
+	w.Flush()
+	fmt.Printf("%d items drifted, %d fixed\n", len(drifted), nFixed)
+
+	if nFixed < len(drifted) {
+		os.Exit(1)
+	}
+}
+
+// recompute sets the quantity to the sum of the ledger. The item is locked
+// first, so that the sum includes the movements committed while waiting.
+func recompute(ctx context.Context, pool *pgxpool.Pool, queries *database.Queries, uuid pgtype.UUID) (int32, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	q := queries.WithTx(tx)
+
+	if _, err := q.LockItems(ctx, []pgtype.UUID{uuid}); err != nil {
+		return 0, err
+	}
+	qty, err := q.RecomputeItemQuantity(ctx, uuid)
+	if err != nil {
+		return 0, err
+	}
+	return qty, tx.Commit(ctx)
+}
@@ -0,0 +1,13 @@
+-- migrate:up
+CREATE TABLE item_barcodes (
+    id SERIAL PRIMARY KEY NOT NULL,
+    item_id UUID NOT NULL REFERENCES items(uuid) ON DELETE CASCADE,
+    code TEXT NOT NULL UNIQUE,
+    format TEXT NOT NULL CHECK (format IN ('ean8', 'upca', 'ean13', 'gtin14', 'code128')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX item_barcodes_item_id_idx ON item_barcodes (item_id);
+
+-- migrate:down
+DROP TABLE item_barcodes;
@@ -0,0 +1,27 @@
+-- migrate:up
+CREATE TABLE products (
+    id SERIAL PRIMARY KEY NOT NULL,
+    uuid UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    sku TEXT UNIQUE,
+    options JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE items
+ADD COLUMN sku TEXT UNIQUE,
+ADD COLUMN product_id UUID REFERENCES products(uuid),
+ADD COLUMN variant_options JSONB;
+
+CREATE INDEX items_product_id_idx ON items (product_id);
+
+-- migrate:down
+DROP INDEX items_product_id_idx;
+
+ALTER TABLE items
+DROP COLUMN variant_options,
+DROP COLUMN product_id,
+DROP COLUMN sku;
+
+DROP TABLE products;
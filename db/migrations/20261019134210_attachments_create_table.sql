@@ -0,0 +1,21 @@
+-- migrate:up
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    item_id UUID REFERENCES items(uuid) ON DELETE CASCADE,
+    transaction_id UUID REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT attachments_owner_check CHECK (num_nonnulls(item_id, transaction_id) = 1)
+);
+
+CREATE INDEX attachments_item_id_idx ON attachments (item_id);
+CREATE INDEX attachments_transaction_id_idx ON attachments (transaction_id);
+
+-- migrate:down
+DROP TABLE attachments;
@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE items
+ADD COLUMN archived_at TIMESTAMPTZ DEFAULT NULL;
+
+-- migrate:down
+ALTER TABLE items
+DROP COLUMN archived_at;
@@ -0,0 +1,23 @@
+-- migrate:up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- has to match the expression the items search is using
+CREATE INDEX items_search_idx ON items
+USING gin (to_tsvector('simple', name || ' ' || COALESCE(sku, '')));
+
+-- substring filters and typo-tolerant search
+CREATE INDEX items_name_trgm_idx ON items USING gin (name gin_trgm_ops);
+CREATE INDEX items_sku_trgm_idx ON items USING gin (sku gin_trgm_ops);
+
+-- range filters and sorting
+CREATE INDEX items_quantity_idx ON items (quantity);
+CREATE INDEX items_created_at_idx ON items (created_at);
+CREATE INDEX items_updated_at_idx ON items (updated_at);
+
+-- migrate:down
+DROP INDEX items_updated_at_idx;
+DROP INDEX items_created_at_idx;
+DROP INDEX items_quantity_idx;
+DROP INDEX items_sku_trgm_idx;
+DROP INDEX items_name_trgm_idx;
+DROP INDEX items_search_idx;
@@ -0,0 +1,6 @@
+-- migrate:up
+-- keyset pagination goes through (created_at, id)
+CREATE INDEX transactions_created_at_id_idx ON transactions (created_at, id);
+
+-- migrate:down
+DROP INDEX transactions_created_at_id_idx;
@@ -0,0 +1,8 @@
+-- migrate:up
+-- per-item and per-user history, in the same order as the full list
+CREATE INDEX transactions_item_id_created_at_idx ON transactions (item_id, created_at, id);
+CREATE INDEX transactions_user_id_created_at_idx ON transactions (user_id, created_at, id);
+
+-- migrate:down
+DROP INDEX transactions_user_id_created_at_idx;
+DROP INDEX transactions_item_id_created_at_idx;
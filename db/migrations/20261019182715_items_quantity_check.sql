@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE items
+ADD CONSTRAINT items_quantity_check CHECK (quantity >= 0);
+
+-- migrate:down
+ALTER TABLE items
+DROP CONSTRAINT items_quantity_check;
@@ -0,0 +1,8 @@
+-- migrate:up
+-- bumped by every update, for optimistic locking through ETags
+ALTER TABLE items
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- migrate:down
+ALTER TABLE items
+DROP COLUMN version;
@@ -0,0 +1,17 @@
+-- migrate:up
+ALTER TABLE transactions
+ADD COLUMN reversal_of UUID UNIQUE REFERENCES transactions(id);
+
+ALTER TABLE transactions
+DROP CONSTRAINT transactions_status_check,
+ADD CONSTRAINT transactions_status_check CHECK (status IN ('failed', 'succeeded', 'reversed'));
+
+-- migrate:down
+UPDATE transactions SET status = 'succeeded' WHERE status = 'reversed';
+
+ALTER TABLE transactions
+DROP CONSTRAINT transactions_status_check,
+ADD CONSTRAINT transactions_status_check CHECK (status IN ('failed', 'succeeded'));
+
+ALTER TABLE transactions
+DROP COLUMN reversal_of;
@@ -0,0 +1,47 @@
+-- migrate:up
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    item_id UUID NOT NULL REFERENCES items(uuid),
+    delta INTEGER NOT NULL CHECK (delta <> 0),
+    kind TEXT NOT NULL CHECK (kind IN ('restock', 'withdraw', 'adjustment')),
+    transaction_id UUID REFERENCES transactions(id),
+    user_id UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_movements_item_id_created_at_idx ON stock_movements (item_id, created_at, id);
+CREATE INDEX stock_movements_transaction_id_idx ON stock_movements (transaction_id);
+
+CREATE FUNCTION stock_movements_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$;
+
+CREATE TRIGGER stock_movements_append_only
+BEFORE UPDATE OR DELETE ON stock_movements
+FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+CREATE TRIGGER stock_movements_no_truncate
+BEFORE TRUNCATE ON stock_movements
+FOR EACH STATEMENT EXECUTE FUNCTION stock_movements_append_only();
+
+-- the history we have: every transaction that moved stock, reversed ones
+-- included since their reversal is a transaction of its own
+INSERT INTO stock_movements (item_id, delta, kind, transaction_id, user_id, created_at)
+SELECT item_id, CASE type WHEN 'restock' THEN amount ELSE -amount END, type, id, user_id, created_at
+FROM transactions
+WHERE type IN ('restock', 'withdraw') AND status IN ('succeeded', 'reversed') AND amount <> 0;
+
+-- and whatever PATCH and the old 'set' transactions did on top of it
+INSERT INTO stock_movements (item_id, delta, kind)
+SELECT items.uuid, items.quantity - COALESCE(SUM(m.delta), 0), 'adjustment'
+FROM items
+LEFT JOIN stock_movements m ON m.item_id = items.uuid
+GROUP BY items.id
+HAVING items.quantity <> COALESCE(SUM(m.delta), 0);
+
+-- migrate:down
+DROP TABLE stock_movements;
+DROP FUNCTION stock_movements_append_only();
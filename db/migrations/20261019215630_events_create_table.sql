@@ -0,0 +1,86 @@
+-- migrate:up
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('item.created', 'item.updated', 'item.deleted', 'transaction.created')),
+    item_id UUID NOT NULL,
+    -- the item or transaction as the REST API shows it
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX events_item_id_idx ON events (item_id, id);
+CREATE INDEX events_created_at_idx ON events (created_at);
+
+-- the triggers record every change, whatever made it, and wake up the
+-- servers listening with the id of the event
+CREATE FUNCTION items_events() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (type, item_id, data)
+        VALUES ('item.deleted', OLD.uuid, jsonb_build_object('uuid', OLD.uuid))
+        RETURNING id INTO event_id;
+    ELSIF TG_OP = 'UPDATE' AND OLD IS NOT DISTINCT FROM NEW THEN
+        RETURN NULL;
+    ELSE
+        INSERT INTO events (type, item_id, data)
+        VALUES (
+            CASE TG_OP WHEN 'INSERT' THEN 'item.created' ELSE 'item.updated' END,
+            NEW.uuid,
+            jsonb_strip_nulls(jsonb_build_object(
+                'uuid', NEW.uuid,
+                'name', NEW.name,
+                'sku', NULLIF(NEW.sku, ''),
+                'quantity', NEW.quantity,
+                'product_uuid', NEW.product_id,
+                'options', NEW.variant_options,
+                'archived_at', extract(epoch FROM NEW.archived_at)::bigint,
+                'version', NEW.version
+            ))
+        )
+        RETURNING id INTO event_id;
+    END IF;
+    PERFORM pg_notify('events', event_id::text);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER items_events
+AFTER INSERT OR UPDATE OR DELETE ON items
+FOR EACH ROW EXECUTE FUNCTION items_events();
+
+CREATE FUNCTION transactions_events() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO events (type, item_id, data)
+    VALUES ('transaction.created', NEW.item_id, jsonb_strip_nulls(jsonb_build_object(
+        'type', NEW.type,
+        'uuid', NEW.id,
+        'owner_uuid', NEW.user_id,
+        'item_uuid', NEW.item_id,
+        'amount', NEW.amount,
+        'status', NEW.status,
+        'reason', NULLIF(NEW.reason, ''),
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        'reversal_of', NEW.reversal_of
+    )))
+    RETURNING id INTO event_id;
+    PERFORM pg_notify('events', event_id::text);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER transactions_events
+AFTER INSERT ON transactions
+FOR EACH ROW EXECUTE FUNCTION transactions_events();
+
+-- migrate:down
+DROP TRIGGER transactions_events ON transactions;
+DROP TRIGGER items_events ON items;
+DROP FUNCTION transactions_events();
+DROP FUNCTION items_events();
+DROP TABLE events;
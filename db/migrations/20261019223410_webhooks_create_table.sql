@@ -0,0 +1,64 @@
+-- migrate:up
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    url TEXT NOT NULL,
+    -- empty for all of them
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    secret TEXT NOT NULL,
+    -- failed attempts in a row, it's disabled after too many
+    failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    -- the event as the streams send it, kept for replays
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- of the last attempt
+    response_status INTEGER,
+    error TEXT,
+    replay_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- every event is queued for the enabled webhooks that want it, in the
+-- transaction of the change
+CREATE FUNCTION webhooks_enqueue() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+    SELECT id, NEW.id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'item_uuid', NEW.item_id,
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        CASE WHEN NEW.type LIKE 'transaction.%' THEN 'transaction' ELSE 'item' END, NEW.data
+    )
+    FROM webhooks
+    WHERE disabled_at IS NULL
+      AND (cardinality(event_types) = 0 OR NEW.type = ANY(event_types));
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER webhooks_enqueue
+AFTER INSERT ON events
+FOR EACH ROW EXECUTE FUNCTION webhooks_enqueue();
+
+-- migrate:down
+DROP TRIGGER webhooks_enqueue ON events;
+DROP FUNCTION webhooks_enqueue();
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
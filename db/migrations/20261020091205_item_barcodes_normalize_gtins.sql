@@ -0,0 +1,23 @@
+-- migrate:up
+-- GTINs are kept as GTIN-14, a UPC-A and its EAN-13 are the same code.
+-- the codes that would end up twice go first: the 14 digits are already
+-- there, or an earlier barcode has the same ones
+DELETE FROM item_barcodes b
+WHERE b.format IN ('ean8', 'upca', 'ean13')
+  AND EXISTS (
+    SELECT 1
+    FROM item_barcodes o
+    WHERE o.id <> b.id
+      AND (o.code = lpad(b.code, 14, '0')
+        OR (o.format IN ('ean8', 'upca', 'ean13') AND lpad(o.code, 14, '0') = lpad(b.code, 14, '0') AND o.id < b.id))
+  );
+
+UPDATE item_barcodes
+SET code = lpad(code, 14, '0')
+WHERE format IN ('ean8', 'upca', 'ean13');
+
+-- migrate:down
+-- the duplicates aren't restored
+UPDATE item_barcodes
+SET code = right(code, CASE format WHEN 'ean8' THEN 8 WHEN 'upca' THEN 12 ELSE 13 END)
+WHERE format IN ('ean8', 'upca', 'ean13');
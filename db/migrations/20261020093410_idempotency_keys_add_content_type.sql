@@ -0,0 +1,5 @@
+-- migrate:up
+ALTER TABLE idempotency_keys ADD COLUMN content_type TEXT;
+
+-- migrate:down
+ALTER TABLE idempotency_keys DROP COLUMN content_type;
@@ -0,0 +1,14 @@
+-- migrate:up
+-- the parsed rows, so that any instance can pick the job up
+ALTER TABLE import_jobs
+    ADD COLUMN items JSONB,
+    ADD COLUMN started_at TIMESTAMPTZ;
+
+CREATE INDEX import_jobs_unfinished_idx ON import_jobs (created_at) WHERE status IN ('pending', 'running');
+
+-- migrate:down
+DROP INDEX import_jobs_unfinished_idx;
+
+ALTER TABLE import_jobs
+    DROP COLUMN items,
+    DROP COLUMN started_at;
@@ -0,0 +1,15 @@
+-- migrate:up
+-- the ids are taken before the transactions commit, so a later id may
+-- be visible first: the streams go by the transaction the events were
+-- written in, see GetEvents
+ALTER TABLE events ADD COLUMN tx_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX events_tx_id_idx ON events (tx_id, id);
+DROP INDEX events_item_id_idx;
+CREATE INDEX events_item_id_idx ON events (item_id, tx_id, id);
+
+-- migrate:down
+DROP INDEX events_item_id_idx;
+CREATE INDEX events_item_id_idx ON events (item_id, id);
+DROP INDEX events_tx_id_idx;
+ALTER TABLE events DROP COLUMN tx_id;
@@ -0,0 +1,7 @@
+-- migrate:up
+-- the deliveries of a webhook go out one at a time, in event order, see
+-- ClaimWebhookDeliveries
+CREATE INDEX webhook_deliveries_queue_idx ON webhook_deliveries (webhook_id, event_id, created_at, id) WHERE status = 'pending';
+
+-- migrate:down
+DROP INDEX webhook_deliveries_queue_idx;
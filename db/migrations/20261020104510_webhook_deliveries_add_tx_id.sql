@@ -0,0 +1,52 @@
+-- migrate:up
+-- the deliveries of a webhook go out in the order of the events stream,
+-- by the transaction of the event, see ClaimWebhookDeliveries
+ALTER TABLE webhook_deliveries ADD COLUMN tx_id BIGINT;
+UPDATE webhook_deliveries d
+SET tx_id = COALESCE((SELECT e.tx_id FROM events e WHERE e.id = d.event_id), 0);
+ALTER TABLE webhook_deliveries ALTER COLUMN tx_id SET NOT NULL;
+
+DROP INDEX webhook_deliveries_queue_idx;
+CREATE INDEX webhook_deliveries_queue_idx ON webhook_deliveries (webhook_id, tx_id, event_id, created_at, id) WHERE status = 'pending';
+
+CREATE OR REPLACE FUNCTION webhooks_enqueue() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event_id, tx_id, event_type, payload)
+    SELECT id, NEW.id, NEW.tx_id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'item_uuid', NEW.item_id,
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        CASE WHEN NEW.type LIKE 'transaction.%' THEN 'transaction' ELSE 'item' END, NEW.data
+    )
+    FROM webhooks
+    WHERE disabled_at IS NULL
+      AND (cardinality(event_types) = 0 OR NEW.type = ANY(event_types));
+    RETURN NULL;
+END;
+$$;
+
+-- migrate:down
+CREATE OR REPLACE FUNCTION webhooks_enqueue() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+    SELECT id, NEW.id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'item_uuid', NEW.item_id,
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        CASE WHEN NEW.type LIKE 'transaction.%' THEN 'transaction' ELSE 'item' END, NEW.data
+    )
+    FROM webhooks
+    WHERE disabled_at IS NULL
+      AND (cardinality(event_types) = 0 OR NEW.type = ANY(event_types));
+    RETURN NULL;
+END;
+$$;
+
+DROP INDEX webhook_deliveries_queue_idx;
+CREATE INDEX webhook_deliveries_queue_idx ON webhook_deliveries (webhook_id, event_id, created_at, id) WHERE status = 'pending';
+
+ALTER TABLE webhook_deliveries DROP COLUMN tx_id;
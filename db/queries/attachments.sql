@@ -0,0 +1,31 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (item_id, transaction_id, user_id, filename, content_type, size, storage_key, thumbnail_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetAttachment :one
+SELECT *
+FROM attachments
+WHERE id = $1;
+
+-- name: GetItemAttachments :many
+SELECT *
+FROM attachments
+WHERE item_id = $1
+ORDER BY created_at;
+
+-- name: GetTransactionAttachments :many
+SELECT *
+FROM attachments
+WHERE transaction_id = $1
+ORDER BY created_at;
+
+-- name: GetItemAttachmentKeys :many
+SELECT storage_key, thumbnail_key
+FROM attachments
+WHERE item_id = $1;
+
+-- name: DeleteAttachment :one
+DELETE FROM attachments
+WHERE id = $1
+RETURNING storage_key, thumbnail_key;
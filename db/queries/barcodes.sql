@@ -0,0 +1,29 @@
+-- name: CreateItemBarcode :one
+INSERT INTO item_barcodes (item_id, code, format)
+VALUES ($1, $2, $3)
+RETURNING item_id, code, format, created_at;
+
+-- name: GetItemBarcodes :many
+SELECT item_id, code, format, created_at
+FROM item_barcodes
+WHERE item_id = $1
+ORDER BY id;
+
+-- name: GetItemByBarcode :one
+SELECT items.uuid, items.name, items.sku, items.quantity, items.product_id, items.variant_options, items.archived_at, items.created_at, items.updated_at, items.version
+FROM items
+JOIN item_barcodes ON item_barcodes.item_id = items.uuid
+WHERE item_barcodes.code = ANY(@codes::text[])
+ORDER BY item_barcodes.id
+LIMIT 1;
+
+-- name: GetItemUUIDByBarcode :one
+SELECT item_id
+FROM item_barcodes
+WHERE code = ANY(@codes::text[])
+ORDER BY id
+LIMIT 1;
+
+-- name: DeleteItemBarcode :execrows
+DELETE FROM item_barcodes
+WHERE item_id = @item_id AND code = ANY(@codes::text[]);
@@ -0,0 +1,33 @@
+-- name: GetEvents :many
+-- only the events of finished transactions, by transaction: the ones
+-- still running are above pg_snapshot_xmin, their events can't end up
+-- before the ones read already.
+SELECT id, type, item_id, data, created_at, tx_id
+FROM events
+WHERE (tx_id, id) > (sqlc.arg('after_tx_id')::bigint, sqlc.arg('after_id')::bigint)
+  AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+  AND (sqlc.narg('item_id')::uuid IS NULL OR item_id = sqlc.narg('item_id')::uuid)
+  AND (sqlc.narg('types')::text[] IS NULL OR type = ANY(sqlc.narg('types')::text[]))
+ORDER BY tx_id, id
+LIMIT $1;
+
+-- name: HasEventsAfter :one
+-- whether GetEvents holds events back for the transactions still running.
+SELECT EXISTS (
+    SELECT 1
+    FROM events
+    WHERE (tx_id, id) > (sqlc.arg('after_tx_id')::bigint, sqlc.arg('after_id')::bigint)
+) AS pending;
+
+-- name: GetEventTxID :one
+SELECT tx_id
+FROM events
+WHERE id = $1;
+
+-- name: GetFinishedTxID :one
+-- the transactions below it are over, GetEvents returns all their events.
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS tx_id;
+
+-- name: DeleteEventsBefore :execrows
+DELETE FROM events
+WHERE created_at < $1;
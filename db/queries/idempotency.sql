@@ -0,0 +1,34 @@
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, key) DO UPDATE
+SET
+    request_hash = EXCLUDED.request_hash,
+    status_code = NULL,
+    response_body = NULL,
+    content_type = NULL,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now()
+    OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < sqlc.arg('stale_before'));
+
+-- name: GetIdempotencyKey :one
+SELECT request_hash, status_code, response_body, content_type
+FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET
+    status_code = sqlc.arg('status_code')::int,
+    response_body = sqlc.arg('response_body'),
+    content_type = sqlc.arg('content_type')
+WHERE user_id = $1 AND key = $2;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2 AND status_code IS NULL;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= now();
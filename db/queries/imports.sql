@@ -0,0 +1,34 @@
+-- name: CreateImportJob :one
+INSERT INTO import_jobs (user_id, filename, dry_run, n_rows, items)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetImportJob :one
+SELECT *
+FROM import_jobs
+WHERE id = $1;
+
+-- name: ClaimImportJob :one
+-- the oldest pending job, or a running one whose instance is gone
+UPDATE import_jobs
+SET status = 'running', started_at = now()
+WHERE id = (
+    SELECT id
+    FROM import_jobs
+    WHERE status = 'pending'
+       OR (status = 'running' AND started_at < sqlc.arg('stale_before'))
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET
+    status = $2,
+    result = $3,
+    error = $4,
+    items = NULL,
+    finished_at = now()
+WHERE id = $1;
@@ -1,40 +1,95 @@
 -- name: CreateItem :one
-INSERT INTO items (name)
-VALUES ($1)
-RETURNING uuid, name, created_at;
-
--- name: GetNItemsOffset :many
-SELECT uuid, name, quantity, created_at, updated_at
-FROM items
-ORDER BY id
-LIMIT $1 OFFSET $2;
+INSERT INTO items (name, sku)
+VALUES ($1, $2)
+RETURNING uuid, name, sku, created_at;
 
 -- name: GetItem :one
-SELECT uuid, name, quantity, created_at, updated_at
+SELECT uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
 FROM items
 WHERE uuid = $1;
 
+-- name: GetItems :many
+SELECT uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
+FROM items
+WHERE uuid = ANY(sqlc.arg('uuids')::uuid[]);
+
+-- name: GetItemBySKUOrName :one
+SELECT uuid, name, sku
+FROM items
+WHERE sku = sqlc.narg('sku') OR name = sqlc.arg('name')
+ORDER BY sku = sqlc.narg('sku') DESC NULLS LAST
+LIMIT 1;
+
 -- name: GetItemQuantity :one
-SELECT uuid, quantity
+SELECT uuid, quantity, archived_at
 FROM items
 WHERE uuid = $1;
 
--- name: SetItemQuantity :exec
+-- name: LockItems :many
+SELECT uuid
+FROM items
+WHERE uuid = ANY(sqlc.arg('uuids')::uuid[])
+ORDER BY uuid
+FOR UPDATE;
+
+-- name: RestockItem :one
+UPDATE items
+SET
+    quantity = quantity + sqlc.arg('amount')::int,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = sqlc.arg('uuid') AND archived_at IS NULL
+RETURNING quantity;
+
+-- name: WithdrawItem :one
 UPDATE items
 SET
-    quantity = $1,
-    updated_at = now()
-WHERE uuid = $2;
+    quantity = quantity - sqlc.arg('amount')::int,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = sqlc.arg('uuid') AND archived_at IS NULL AND quantity >= sqlc.arg('amount')::int
+RETURNING quantity;
 
 -- name: PatchItem :one
+WITH previous AS (
+    SELECT id, quantity AS previous_quantity
+    FROM items
+    WHERE uuid = $1
+    FOR UPDATE
+)
 UPDATE items
 SET
     name = COALESCE(sqlc.narg('name'), name),
+    sku = COALESCE(sqlc.narg('sku'), sku),
     quantity = COALESCE(sqlc.narg('quantity'), quantity),
-    updated_at = now()
-WHERE uuid = $1 
-RETURNING uuid, name, quantity, created_at, updated_at;
+    updated_at = now(),
+    version = version + 1
+FROM previous
+WHERE items.id = previous.id AND (sqlc.narg('versions')::int[] IS NULL OR version = ANY(sqlc.narg('versions')::int[]))
+RETURNING items.uuid, items.name, items.sku, items.quantity, items.product_id, items.variant_options, items.archived_at, items.created_at, items.updated_at, items.version, previous.previous_quantity;
+
+-- name: ArchiveItem :one
+UPDATE items
+SET
+    archived_at = COALESCE(archived_at, now()),
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $1 AND (sqlc.narg('versions')::int[] IS NULL OR version = ANY(sqlc.narg('versions')::int[]))
+RETURNING uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version;
 
--- name: DeleteItem :exec
+-- name: UnarchiveItem :one
+UPDATE items
+SET
+    archived_at = NULL,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $1 AND (sqlc.narg('versions')::int[] IS NULL OR version = ANY(sqlc.narg('versions')::int[]))
+RETURNING uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version;
+
+-- name: PurgeItem :execrows
 DELETE FROM items
-WHERE uuid = $1;
+WHERE uuid = $1 AND (sqlc.narg('versions')::int[] IS NULL OR version = ANY(sqlc.narg('versions')::int[])) AND NOT EXISTS (
+    SELECT 1 FROM transactions WHERE transactions.item_id = items.uuid
+) AND NOT EXISTS (
+    SELECT 1 FROM stock_movements WHERE stock_movements.item_id = items.uuid
+);
@@ -0,0 +1,23 @@
+-- name: GetItemLabels :many
+SELECT items.uuid, items.name, first_barcode.code AS barcode
+FROM items
+LEFT JOIN LATERAL (
+    SELECT code
+    FROM item_barcodes
+    WHERE item_barcodes.item_id = items.uuid
+    ORDER BY id
+    LIMIT 1
+) first_barcode ON true
+WHERE items.uuid = ANY(sqlc.arg('uuids')::uuid[]);
+
+-- name: GetItemLabelBySKU :one
+SELECT items.uuid, items.name, first_barcode.code AS barcode
+FROM items
+LEFT JOIN LATERAL (
+    SELECT code
+    FROM item_barcodes
+    WHERE item_barcodes.item_id = items.uuid
+    ORDER BY id
+    LIMIT 1
+) first_barcode ON true
+WHERE items.sku = $1;
@@ -0,0 +1,36 @@
+-- name: CreateProduct :one
+INSERT INTO products (name, sku, options)
+VALUES ($1, $2, $3)
+RETURNING uuid, name, sku, options, created_at;
+
+-- name: CreateVariantItem :one
+INSERT INTO items (name, sku, product_id, variant_options)
+VALUES ($1, $2, $3, $4)
+RETURNING uuid, name, sku, quantity, product_id, variant_options;
+
+-- name: GetProduct :one
+SELECT uuid, name, sku, options, created_at
+FROM products
+WHERE uuid = $1;
+
+-- name: GetNProductsOffset :many
+SELECT uuid, name, sku, options, created_at
+FROM products
+ORDER BY id
+LIMIT $1 OFFSET $2;
+
+-- name: GetProductVariants :many
+-- archived variants are no longer sold, they're left out like in GET /items.
+SELECT uuid, name, sku, quantity, product_id, variant_options
+FROM items
+WHERE product_id = ANY(sqlc.arg('product_ids')::uuid[]) AND archived_at IS NULL
+ORDER BY id;
+
+-- name: GetProductsStock :many
+-- the variants that aren't archived, like GetProductVariants.
+SELECT products.uuid, products.name, count(items.id) AS n_variants, COALESCE(sum(items.quantity), 0)::int AS quantity
+FROM products
+LEFT JOIN items ON items.product_id = products.uuid AND items.archived_at IS NULL
+WHERE products.uuid = ANY(sqlc.arg('product_ids')::uuid[])
+GROUP BY products.id
+ORDER BY products.id;
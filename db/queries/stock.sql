@@ -0,0 +1,28 @@
+-- name: AppendStockMovement :exec
+INSERT INTO stock_movements (item_id, delta, kind, transaction_id, user_id)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetItemStockAsOf :one
+SELECT
+    COALESCE(SUM(delta), 0)::int AS quantity,
+    COUNT(*) AS n_movements,
+    MAX(created_at)::timestamptz AS last_movement_at
+FROM stock_movements
+WHERE item_id = $1 AND created_at <= sqlc.arg('as_of');
+
+-- name: GetStockDrift :many
+SELECT items.uuid, items.name, items.quantity AS projected, COALESCE(SUM(m.delta), 0)::int AS ledger
+FROM items
+LEFT JOIN stock_movements m ON m.item_id = items.uuid
+GROUP BY items.id
+HAVING items.quantity <> COALESCE(SUM(m.delta), 0)
+ORDER BY items.id;
+
+-- name: RecomputeItemQuantity :one
+UPDATE items
+SET
+    quantity = (SELECT COALESCE(SUM(delta), 0) FROM stock_movements WHERE item_id = items.uuid),
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $1
+RETURNING quantity;
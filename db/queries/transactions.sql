@@ -8,16 +8,27 @@ SELECT *
 FROM transactions
 WHERE id = $1;
 
--- name: GetAllTransactions :many
-SELECT * FROM transactions
-LIMIT $1 OFFSET $2;
+-- name: GetRecentTransactionsByItems :many
+-- reads at most n rows of each item from the index instead of ranking
+-- their whole history.
+SELECT t.id, t.user_id, t.item_id, t.type, t.amount, t.status, t.reason, t.created_at, t.reversal_of
+FROM unnest(sqlc.arg('item_ids')::uuid[]) AS ids(item_id)
+CROSS JOIN LATERAL (
+    SELECT *
+    FROM transactions
+    WHERE transactions.item_id = ids.item_id
+    ORDER BY created_at DESC, id DESC
+    LIMIT sqlc.arg('n')::int
+) t
+ORDER BY t.item_id, t.created_at DESC, t.id DESC;
 
--- name: GetTransactionsForItem :many
-SELECT * FROM transactions
-WHERE item_id = $1
-LIMIT $2 OFFSET $3;
+-- name: MarkTransactionReversed :one
+UPDATE transactions
+SET status = 'reversed'
+WHERE id = $1 AND status = 'succeeded' AND reversal_of IS NULL
+RETURNING *;
 
--- name: GetTransactionsForUser :many
-SELECT * FROM transactions
-WHERE user_id = $1
-LIMIT $2 OFFSET $3;
+-- name: CreateReversalTransaction :one
+INSERT INTO transactions (user_id, item_id, type, amount, status, reason, reversal_of)
+VALUES ($1, $2, $3, $4, 'succeeded', $5, $6)
+RETURNING *;
@@ -23,3 +23,8 @@ RETURNING id;
 SELECT id, role
 FROM users
 WHERE id = $1;
+
+-- name: GetUsers :many
+SELECT id, username, role, created_at
+FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,107 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, event_types, secret)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhooks :many
+SELECT * FROM webhooks
+ORDER BY created_at, id;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = COALESCE(sqlc.narg('url'), url),
+    -- an empty array means all the types, only NULL keeps them
+    event_types = COALESCE(sqlc.narg('event_types')::text[], event_types),
+    secret = COALESCE(sqlc.narg('secret'), secret),
+    disabled_at = CASE sqlc.narg('enabled')::boolean
+        WHEN true THEN NULL
+        WHEN false THEN COALESCE(disabled_at, now())
+        ELSE disabled_at
+    END,
+    failures = CASE WHEN sqlc.narg('enabled')::boolean THEN 0 ELSE failures END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhooks
+SET failures = 0
+WHERE id = $1 AND failures <> 0;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhooks
+SET failures = failures + 1,
+    disabled_at = CASE
+        WHEN sqlc.arg('disable')::boolean THEN COALESCE(disabled_at, now())
+        ELSE disabled_at
+    END
+WHERE id = sqlc.arg('id')
+RETURNING failures, disabled_at;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2;
+
+-- name: ReplayWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event_id, tx_id, event_type, payload, replay_of)
+SELECT webhook_id, event_id, tx_id, event_type, payload, id
+FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- only the first pending delivery of each webhook, in the order of the
+-- events stream: the next one waits until it's done, and while it's
+-- claimed no other server can take one of the same webhook. Like
+-- GetEvents, only the deliveries of finished transactions, the ones still
+-- running may have some to go first.
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg('lease_until')
+FROM webhooks
+WHERE webhooks.id = webhook_deliveries.webhook_id
+  AND webhook_deliveries.id IN (
+    SELECT d.id
+    FROM webhook_deliveries d
+    JOIN webhooks w ON w.id = d.webhook_id
+    WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND w.disabled_at IS NULL
+      AND d.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+      AND NOT EXISTS (
+        SELECT 1
+        FROM webhook_deliveries e
+        WHERE e.webhook_id = d.webhook_id AND e.status = 'pending'
+          AND (e.tx_id, e.event_id, e.created_at, e.id) < (d.tx_id, d.event_id, d.created_at, d.id)
+      )
+    ORDER BY d.next_attempt_at
+    LIMIT sqlc.arg('n')
+    FOR UPDATE OF d SKIP LOCKED
+  )
+RETURNING webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event_type,
+    webhook_deliveries.payload, webhook_deliveries.attempts, webhooks.url, webhooks.secret;
+
+-- name: FinishWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    status = sqlc.arg('status'),
+    next_attempt_at = sqlc.arg('next_attempt_at'),
+    response_status = sqlc.narg('response_status'),
+    error = sqlc.narg('error'),
+    delivered_at = CASE WHEN sqlc.arg('status') = 'succeeded' THEN now() END
+WHERE id = sqlc.arg('id');
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND created_at < $1;
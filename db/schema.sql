@@ -10,6 +10,20 @@ SET xmloption = content;
 SET client_min_messages = warning;
 SET row_security = off;
 
+--
+-- Name: pg_trgm; Type: EXTENSION; Schema: -; Owner: -
+--
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+
+
+--
+-- Name: EXTENSION pg_trgm; Type: COMMENT; Schema: -; Owner: -
+--
+
+COMMENT ON EXTENSION pg_trgm IS 'text similarity measurement and index searching based on trigrams';
+
+
 --
 -- Name: pgcrypto; Type: EXTENSION; Schema: -; Owner: -
 --
@@ -24,10 +38,240 @@ CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
 COMMENT ON EXTENSION pgcrypto IS 'cryptographic functions';
 
 
+--
+-- Name: items_events(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.items_events() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (type, item_id, data)
+        VALUES ('item.deleted', OLD.uuid, jsonb_build_object('uuid', OLD.uuid))
+        RETURNING id INTO event_id;
+    ELSIF TG_OP = 'UPDATE' AND OLD IS NOT DISTINCT FROM NEW THEN
+        RETURN NULL;
+    ELSE
+        INSERT INTO events (type, item_id, data)
+        VALUES (
+            CASE TG_OP WHEN 'INSERT' THEN 'item.created' ELSE 'item.updated' END,
+            NEW.uuid,
+            jsonb_strip_nulls(jsonb_build_object(
+                'uuid', NEW.uuid,
+                'name', NEW.name,
+                'sku', NULLIF(NEW.sku, ''),
+                'quantity', NEW.quantity,
+                'product_uuid', NEW.product_id,
+                'options', NEW.variant_options,
+                'archived_at', extract(epoch FROM NEW.archived_at)::bigint,
+                'version', NEW.version
+            ))
+        )
+        RETURNING id INTO event_id;
+    END IF;
+    PERFORM pg_notify('events', event_id::text);
+    RETURN NULL;
+END;
+$$;
+
+
+--
+-- Name: stock_movements_append_only(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.stock_movements_append_only() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$;
+
+
+--
+-- Name: transactions_events(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.transactions_events() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO events (type, item_id, data)
+    VALUES ('transaction.created', NEW.item_id, jsonb_strip_nulls(jsonb_build_object(
+        'type', NEW.type,
+        'uuid', NEW.id,
+        'owner_uuid', NEW.user_id,
+        'item_uuid', NEW.item_id,
+        'amount', NEW.amount,
+        'status', NEW.status,
+        'reason', NULLIF(NEW.reason, ''),
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        'reversal_of', NEW.reversal_of
+    )))
+    RETURNING id INTO event_id;
+    PERFORM pg_notify('events', event_id::text);
+    RETURN NULL;
+END;
+$$;
+
+
+--
+-- Name: webhooks_enqueue(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.webhooks_enqueue() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event_id, tx_id, event_type, payload)
+    SELECT id, NEW.id, NEW.tx_id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'item_uuid', NEW.item_id,
+        'created_at', extract(epoch FROM NEW.created_at)::bigint,
+        CASE WHEN NEW.type LIKE 'transaction.%' THEN 'transaction' ELSE 'item' END, NEW.data
+    )
+    FROM webhooks
+    WHERE disabled_at IS NULL
+      AND (cardinality(event_types) = 0 OR NEW.type = ANY(event_types));
+    RETURN NULL;
+END;
+$$;
+
+
 SET default_tablespace = '';
 
 SET default_table_access_method = heap;
 
+--
+-- Name: attachments; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.attachments (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    item_id uuid,
+    transaction_id uuid,
+    user_id uuid NOT NULL,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    storage_key text NOT NULL,
+    thumbnail_key text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT attachments_owner_check CHECK ((num_nonnulls(item_id, transaction_id) = 1))
+);
+
+
+--
+-- Name: events; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.events (
+    id bigint NOT NULL,
+    type text NOT NULL,
+    item_id uuid NOT NULL,
+    data jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    tx_id bigint DEFAULT ((pg_current_xact_id())::text)::bigint NOT NULL,
+    CONSTRAINT events_type_check CHECK ((type = ANY (ARRAY['item.created'::text, 'item.updated'::text, 'item.deleted'::text, 'transaction.created'::text])))
+);
+
+
+--
+-- Name: events_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.events_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: events_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.events_id_seq OWNED BY public.events.id;
+
+
+--
+-- Name: idempotency_keys; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.idempotency_keys (
+    user_id uuid NOT NULL,
+    key text NOT NULL,
+    request_hash bytea NOT NULL,
+    status_code integer,
+    response_body bytea,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    content_type text
+);
+
+
+--
+-- Name: import_jobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.import_jobs (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    user_id uuid NOT NULL,
+    filename text NOT NULL,
+    dry_run boolean DEFAULT false NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    n_rows integer DEFAULT 0 NOT NULL,
+    result jsonb,
+    error text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    finished_at timestamp with time zone,
+    items jsonb,
+    started_at timestamp with time zone,
+    CONSTRAINT import_jobs_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'running'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: item_barcodes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.item_barcodes (
+    id integer NOT NULL,
+    item_id uuid NOT NULL,
+    code text NOT NULL,
+    format text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT item_barcodes_format_check CHECK ((format = ANY (ARRAY['ean8'::text, 'upca'::text, 'ean13'::text, 'gtin14'::text, 'code128'::text])))
+);
+
+
+--
+-- Name: item_barcodes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.item_barcodes_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: item_barcodes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.item_barcodes_id_seq OWNED BY public.item_barcodes.id;
+
+
 --
 -- Name: items; Type: TABLE; Schema: public; Owner: -
 --
@@ -38,7 +282,13 @@ CREATE TABLE public.items (
     name text NOT NULL,
     created_at timestamp with time zone DEFAULT now() NOT NULL,
     updated_at timestamp with time zone DEFAULT now() NOT NULL,
-    quantity integer DEFAULT 0 NOT NULL
+    quantity integer DEFAULT 0 NOT NULL,
+    sku text,
+    product_id uuid,
+    variant_options jsonb,
+    archived_at timestamp with time zone,
+    version integer DEFAULT 1 NOT NULL,
+    CONSTRAINT items_quantity_check CHECK ((quantity >= 0))
 );
 
 
@@ -62,6 +312,41 @@ CREATE SEQUENCE public.items_id_seq
 ALTER SEQUENCE public.items_id_seq OWNED BY public.items.id;
 
 
+--
+-- Name: products; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.products (
+    id integer NOT NULL,
+    uuid uuid DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    sku text,
+    options jsonb DEFAULT '[]'::jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: products_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.products_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: products_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.products_id_seq OWNED BY public.products.id;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
 --
@@ -71,6 +356,42 @@ CREATE TABLE public.schema_migrations (
 );
 
 
+--
+-- Name: stock_movements; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.stock_movements (
+    id bigint NOT NULL,
+    item_id uuid NOT NULL,
+    delta integer NOT NULL,
+    kind text NOT NULL,
+    transaction_id uuid,
+    user_id uuid,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT stock_movements_delta_check CHECK ((delta <> 0)),
+    CONSTRAINT stock_movements_kind_check CHECK ((kind = ANY (ARRAY['restock'::text, 'withdraw'::text, 'adjustment'::text])))
+);
+
+
+--
+-- Name: stock_movements_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.stock_movements_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: stock_movements_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.stock_movements_id_seq OWNED BY public.stock_movements.id;
+
+
 --
 -- Name: transactions; Type: TABLE; Schema: public; Owner: -
 --
@@ -84,7 +405,8 @@ CREATE TABLE public.transactions (
     status text NOT NULL,
     reason text,
     created_at timestamp with time zone DEFAULT now() NOT NULL,
-    CONSTRAINT transactions_status_check CHECK ((status = ANY (ARRAY['failed'::text, 'succeeded'::text]))),
+    reversal_of uuid,
+    CONSTRAINT transactions_status_check CHECK ((status = ANY (ARRAY['failed'::text, 'succeeded'::text, 'reversed'::text]))),
     CONSTRAINT transactions_type_check CHECK ((type = ANY (ARRAY['set'::text, 'restock'::text, 'withdraw'::text])))
 );
 
@@ -104,6 +426,59 @@ CREATE TABLE public.users (
 );
 
 
+--
+-- Name: webhook_deliveries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhook_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    webhook_id uuid NOT NULL,
+    event_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+    response_status integer,
+    error text,
+    replay_of uuid,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    delivered_at timestamp with time zone,
+    tx_id bigint NOT NULL,
+    CONSTRAINT webhook_deliveries_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: webhooks; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhooks (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    user_id uuid NOT NULL,
+    url text NOT NULL,
+    event_types text[] DEFAULT '{}'::text[] NOT NULL,
+    secret text NOT NULL,
+    failures integer DEFAULT 0 NOT NULL,
+    disabled_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.events ALTER COLUMN id SET DEFAULT nextval('public.events_id_seq'::regclass);
+
+
+--
+-- Name: item_barcodes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.item_barcodes ALTER COLUMN id SET DEFAULT nextval('public.item_barcodes_id_seq'::regclass);
+
+
 --
 -- Name: items id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -111,6 +486,68 @@ CREATE TABLE public.users (
 ALTER TABLE ONLY public.items ALTER COLUMN id SET DEFAULT nextval('public.items_id_seq'::regclass);
 
 
+--
+-- Name: products id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.products ALTER COLUMN id SET DEFAULT nextval('public.products_id_seq'::regclass);
+
+
+--
+-- Name: stock_movements id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stock_movements ALTER COLUMN id SET DEFAULT nextval('public.stock_movements_id_seq'::regclass);
+
+
+--
+-- Name: attachments attachments_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: events events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.events
+    ADD CONSTRAINT events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: idempotency_keys idempotency_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.idempotency_keys
+    ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_id, key);
+
+
+--
+-- Name: import_jobs import_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.import_jobs
+    ADD CONSTRAINT import_jobs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: item_barcodes item_barcodes_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.item_barcodes
+    ADD CONSTRAINT item_barcodes_code_key UNIQUE (code);
+
+
+--
+-- Name: item_barcodes item_barcodes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.item_barcodes
+    ADD CONSTRAINT item_barcodes_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: items items_name_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -127,6 +564,46 @@ ALTER TABLE ONLY public.items
     ADD CONSTRAINT items_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: items items_sku_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.items
+    ADD CONSTRAINT items_sku_key UNIQUE (sku);
+
+
+--
+-- Name: products products_name_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.products
+    ADD CONSTRAINT products_name_key UNIQUE (name);
+
+
+--
+-- Name: products products_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.products
+    ADD CONSTRAINT products_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: products products_sku_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.products
+    ADD CONSTRAINT products_sku_key UNIQUE (sku);
+
+
+--
+-- Name: products products_uuid_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.products
+    ADD CONSTRAINT products_uuid_key UNIQUE (uuid);
+
+
 --
 -- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -135,6 +612,14 @@ ALTER TABLE ONLY public.schema_migrations
     ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
 
 
+--
+-- Name: stock_movements stock_movements_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stock_movements
+    ADD CONSTRAINT stock_movements_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: transactions transactions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -143,6 +628,14 @@ ALTER TABLE ONLY public.transactions
     ADD CONSTRAINT transactions_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: transactions transactions_reversal_of_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.transactions
+    ADD CONSTRAINT transactions_reversal_of_key UNIQUE (reversal_of);
+
+
 --
 -- Name: users unique_role_name; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -167,6 +660,298 @@ ALTER TABLE ONLY public.users
     ADD CONSTRAINT users_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: webhook_deliveries webhook_deliveries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhooks webhooks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: attachments_item_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX attachments_item_id_idx ON public.attachments USING btree (item_id);
+
+
+--
+-- Name: attachments_transaction_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX attachments_transaction_id_idx ON public.attachments USING btree (transaction_id);
+
+
+--
+-- Name: events_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX events_created_at_idx ON public.events USING btree (created_at);
+
+
+--
+-- Name: events_item_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX events_item_id_idx ON public.events USING btree (item_id, tx_id, id);
+
+
+--
+-- Name: events_tx_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX events_tx_id_idx ON public.events USING btree (tx_id, id);
+
+
+--
+-- Name: idempotency_keys_expires_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idempotency_keys_expires_at_idx ON public.idempotency_keys USING btree (expires_at);
+
+
+--
+-- Name: import_jobs_unfinished_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX import_jobs_unfinished_idx ON public.import_jobs USING btree (created_at) WHERE (status = ANY (ARRAY['pending'::text, 'running'::text]));
+
+
+--
+-- Name: item_barcodes_item_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX item_barcodes_item_id_idx ON public.item_barcodes USING btree (item_id);
+
+
+--
+-- Name: items_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_created_at_idx ON public.items USING btree (created_at);
+
+
+--
+-- Name: items_name_trgm_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_name_trgm_idx ON public.items USING gin (name public.gin_trgm_ops);
+
+
+--
+-- Name: items_product_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_product_id_idx ON public.items USING btree (product_id);
+
+
+--
+-- Name: items_quantity_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_quantity_idx ON public.items USING btree (quantity);
+
+
+--
+-- Name: items_search_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_search_idx ON public.items USING gin (to_tsvector('simple'::regconfig, ((name || ' '::text) || COALESCE(sku, ''::text))));
+
+
+--
+-- Name: items_sku_trgm_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_sku_trgm_idx ON public.items USING gin (sku public.gin_trgm_ops);
+
+
+--
+-- Name: items_updated_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX items_updated_at_idx ON public.items USING btree (updated_at);
+
+
+--
+-- Name: stock_movements_item_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX stock_movements_item_id_created_at_idx ON public.stock_movements USING btree (item_id, created_at, id);
+
+
+--
+-- Name: stock_movements_transaction_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX stock_movements_transaction_id_idx ON public.stock_movements USING btree (transaction_id);
+
+
+--
+-- Name: transactions_created_at_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX transactions_created_at_id_idx ON public.transactions USING btree (created_at, id);
+
+
+--
+-- Name: transactions_item_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX transactions_item_id_created_at_idx ON public.transactions USING btree (item_id, created_at, id);
+
+
+--
+-- Name: transactions_user_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX transactions_user_id_created_at_idx ON public.transactions USING btree (user_id, created_at, id);
+
+
+--
+-- Name: webhook_deliveries_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_pending_idx ON public.webhook_deliveries USING btree (next_attempt_at) WHERE (status = 'pending'::text);
+
+
+--
+-- Name: webhook_deliveries_queue_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_queue_idx ON public.webhook_deliveries USING btree (webhook_id, tx_id, event_id, created_at, id) WHERE (status = 'pending'::text);
+
+
+--
+-- Name: webhook_deliveries_webhook_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON public.webhook_deliveries USING btree (webhook_id, created_at);
+
+
+--
+-- Name: events webhooks_enqueue; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER webhooks_enqueue AFTER INSERT ON public.events FOR EACH ROW EXECUTE FUNCTION public.webhooks_enqueue();
+
+
+--
+-- Name: items items_events; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER items_events AFTER INSERT OR DELETE OR UPDATE ON public.items FOR EACH ROW EXECUTE FUNCTION public.items_events();
+
+
+--
+-- Name: stock_movements stock_movements_append_only; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER stock_movements_append_only BEFORE DELETE OR UPDATE ON public.stock_movements FOR EACH ROW EXECUTE FUNCTION public.stock_movements_append_only();
+
+
+--
+-- Name: stock_movements stock_movements_no_truncate; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER stock_movements_no_truncate BEFORE TRUNCATE ON public.stock_movements FOR EACH STATEMENT EXECUTE FUNCTION public.stock_movements_append_only();
+
+
+--
+-- Name: transactions transactions_events; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER transactions_events AFTER INSERT ON public.transactions FOR EACH ROW EXECUTE FUNCTION public.transactions_events();
+
+
+--
+-- Name: attachments attachments_item_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_item_id_fkey FOREIGN KEY (item_id) REFERENCES public.items(uuid) ON DELETE CASCADE;
+
+
+--
+-- Name: attachments attachments_transaction_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_transaction_id_fkey FOREIGN KEY (transaction_id) REFERENCES public.transactions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: attachments attachments_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+
+--
+-- Name: idempotency_keys idempotency_keys_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.idempotency_keys
+    ADD CONSTRAINT idempotency_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: import_jobs import_jobs_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.import_jobs
+    ADD CONSTRAINT import_jobs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+
+--
+-- Name: item_barcodes item_barcodes_item_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.item_barcodes
+    ADD CONSTRAINT item_barcodes_item_id_fkey FOREIGN KEY (item_id) REFERENCES public.items(uuid) ON DELETE CASCADE;
+
+
+--
+-- Name: items items_product_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.items
+    ADD CONSTRAINT items_product_id_fkey FOREIGN KEY (product_id) REFERENCES public.products(uuid);
+
+
+--
+-- Name: stock_movements stock_movements_item_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stock_movements
+    ADD CONSTRAINT stock_movements_item_id_fkey FOREIGN KEY (item_id) REFERENCES public.items(uuid);
+
+
+--
+-- Name: stock_movements stock_movements_transaction_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stock_movements
+    ADD CONSTRAINT stock_movements_transaction_id_fkey FOREIGN KEY (transaction_id) REFERENCES public.transactions(id);
+
+
+--
+-- Name: stock_movements stock_movements_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stock_movements
+    ADD CONSTRAINT stock_movements_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+
 --
 -- Name: transactions transactions_item_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -175,6 +960,14 @@ ALTER TABLE ONLY public.transactions
     ADD CONSTRAINT transactions_item_id_fkey FOREIGN KEY (item_id) REFERENCES public.items(uuid);
 
 
+--
+-- Name: transactions transactions_reversal_of_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.transactions
+    ADD CONSTRAINT transactions_reversal_of_fkey FOREIGN KEY (reversal_of) REFERENCES public.transactions(id);
+
+
 --
 -- Name: transactions transactions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -183,6 +976,30 @@ ALTER TABLE ONLY public.transactions
     ADD CONSTRAINT transactions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
 
 
+--
+-- Name: webhook_deliveries webhook_deliveries_replay_of_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_replay_of_fkey FOREIGN KEY (replay_of) REFERENCES public.webhook_deliveries(id) ON DELETE SET NULL;
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_webhook_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES public.webhooks(id) ON DELETE CASCADE;
+
+
+--
+-- Name: webhooks webhooks_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -196,4 +1013,25 @@ INSERT INTO public.schema_migrations (version) VALUES
     ('20250907141613'),
     ('20250907154301'),
     ('20250907162847'),
-    ('20250908064850');
+    ('20250908064850'),
+    ('20261019090512'),
+    ('20261019113045'),
+    ('20261019134210'),
+    ('20261019151533'),
+    ('20261019163020'),
+    ('20261019171405'),
+    ('20261019174250'),
+    ('20261019182715'),
+    ('20261019190348'),
+    ('20261019194512'),
+    ('20261019201230'),
+    ('20261019204105'),
+    ('20261019211540'),
+    ('20261019215630'),
+    ('20261019223410'),
+    ('20261020091205'),
+    ('20261020093410'),
+    ('20261020095120'),
+    ('20261020101530'),
+    ('20261020103045'),
+    ('20261020104510');
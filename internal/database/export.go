@@ -0,0 +1,57 @@
+package database
+
+// Not generated: sqlc only returns whole result sets, the exports go through
+// the rows one at a time as they are read from the connection.
+
+import (
+	"context"
+)
+
+// ForEachItem calls fn for every item matching the search, in the search
+// order. The pagination fields of arg are ignored.
+func (q *Queries) ForEachItem(ctx context.Context, arg SearchItemsParams, fn func(SearchItemsRow) error) error {
+	arg.Limit, arg.Offset, arg.Keyset = -1, 0, nil
+	query, args, err := buildSearchItems(arg)
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		i, err := scanSearchItemsRow(rows, arg.Query != nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ForEachTransaction calls fn for every transaction matching the filters,
+// oldest first. The pagination fields of arg are ignored.
+func (q *Queries) ForEachTransaction(ctx context.Context, arg SearchTransactionsParams, fn func(Transaction) error) error {
+	arg.Limit, arg.Offset, arg.Keyset = -1, 0, nil
+	query, args := buildSearchTransactions(arg)
+
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		i, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,140 @@
+package database
+
+// Not generated: with "(narg IS NULL OR col = narg)" filters postgres settles
+// on a generic plan that can't use the item and user indexes of the history,
+// so only the filters that are set make it into the query.
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const searchTransactions = `-- name: SearchTransactions :many
+SELECT id, user_id, item_id, type, amount, status, reason, created_at, reversal_of
+FROM transactions`
+
+type SearchTransactionsParams struct {
+	// negative for all the rows
+	Limit         int32
+	Offset        int32
+	Type          *string
+	Status        *string
+	UserID        pgtype.UUID
+	ItemID        pgtype.UUID
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	MinAmount     *int32
+	MaxAmount     *int32
+	// continues right after (or before, if Backward) this transaction,
+	// Offset should be zero then
+	Keyset *TransactionsKeyset
+}
+
+// TransactionsKeyset is the position of a transaction in the history.
+type TransactionsKeyset struct {
+	CreatedAt pgtype.Timestamptz
+	ID        pgtype.UUID
+	// for the previous page
+	Backward bool
+}
+
+// SearchTransactions finds the history matching arg, oldest first.
+func (q *Queries) SearchTransactions(ctx context.Context, arg SearchTransactionsParams) ([]Transaction, error) {
+	query, args := buildSearchTransactions(arg)
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		i, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if arg.Keyset != nil && arg.Keyset.Backward {
+		// fetched newest first
+		slices.Reverse(items)
+	}
+	return items, nil
+}
+
+func scanTransaction(rows pgx.Rows) (Transaction, error) {
+	var i Transaction
+	err := rows.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ItemID,
+		&i.Type,
+		&i.Amount,
+		&i.Status,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.ReversalOf,
+	)
+	return i, err
+}
+
+func buildSearchTransactions(arg SearchTransactionsParams) (string, []any) {
+	var b queryBuilder
+	var where []string
+
+	if arg.Type != nil {
+		where = append(where, "type = "+b.arg(*arg.Type))
+	}
+	if arg.Status != nil {
+		where = append(where, "status = "+b.arg(*arg.Status))
+	}
+	if arg.UserID.Valid {
+		where = append(where, "user_id = "+b.arg(arg.UserID))
+	}
+	if arg.ItemID.Valid {
+		where = append(where, "item_id = "+b.arg(arg.ItemID))
+	}
+	if arg.CreatedAfter.Valid {
+		where = append(where, "created_at >= "+b.arg(arg.CreatedAfter))
+	}
+	if arg.CreatedBefore.Valid {
+		where = append(where, "created_at < "+b.arg(arg.CreatedBefore))
+	}
+	if arg.MinAmount != nil {
+		where = append(where, "amount >= "+b.arg(*arg.MinAmount))
+	}
+	if arg.MaxAmount != nil {
+		where = append(where, "amount <= "+b.arg(*arg.MaxAmount))
+	}
+
+	backward := arg.Keyset != nil && arg.Keyset.Backward
+	if arg.Keyset != nil {
+		op := ">"
+		if backward {
+			op = "<"
+		}
+		where = append(where, "(created_at, id) "+op+" ("+b.arg(arg.Keyset.CreatedAt)+", "+b.arg(arg.Keyset.ID)+")")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(searchTransactions + "\n")
+	if len(where) > 0 {
+		sb.WriteString("WHERE " + strings.Join(where, "\n  AND ") + "\n")
+	}
+	if backward {
+		sb.WriteString("ORDER BY created_at DESC, id DESC\n")
+	} else {
+		sb.WriteString("ORDER BY created_at, id\n")
+	}
+	if arg.Limit >= 0 {
+		sb.WriteString("LIMIT " + b.arg(arg.Limit) + " OFFSET " + b.arg(arg.Offset))
+	}
+
+	return sb.String(), b.args
+}
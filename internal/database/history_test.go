@@ -0,0 +1,38 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSearchTransactions(t *testing.T) {
+	item := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	at := pgtype.Timestamptz{Time: time.Unix(1700000000, 0), Valid: true}
+	withdraw := "withdraw"
+
+	// only the filters that are set, so the item index can be used
+	query, args := buildSearchTransactions(SearchTransactionsParams{Limit: 10, ItemID: item, Type: &withdraw})
+	require.Equal(t, searchTransactions+`
+WHERE type = $1
+  AND item_id = $2
+ORDER BY created_at, id
+LIMIT $3 OFFSET $4`, query)
+	require.Equal(t, []any{withdraw, item, int32(10), int32(0)}, args)
+
+	query, args = buildSearchTransactions(SearchTransactionsParams{
+		Limit:  10,
+		Keyset: &TransactionsKeyset{CreatedAt: at, ID: item, Backward: true},
+	})
+	require.Equal(t, searchTransactions+`
+WHERE (created_at, id) < ($1, $2)
+ORDER BY created_at DESC, id DESC
+LIMIT $3 OFFSET $4`, query)
+	require.Equal(t, []any{at, item, int32(10), int32(0)}, args)
+
+	query, args = buildSearchTransactions(SearchTransactionsParams{Limit: -1})
+	require.Equal(t, searchTransactions+"\nORDER BY created_at, id\n", query)
+	require.Empty(t, args)
+}
@@ -11,47 +11,99 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const archiveItem = `-- name: ArchiveItem :one
+UPDATE items
+SET
+    archived_at = COALESCE(archived_at, now()),
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $1 AND ($2::int[] IS NULL OR version = ANY($2::int[]))
+RETURNING uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
+`
+
+type ArchiveItemParams struct {
+	Uuid     pgtype.UUID
+	Versions []int32
+}
+
+type ArchiveItemRow struct {
+	Uuid           pgtype.UUID
+	Name           string
+	Sku            *string
+	Quantity       int32
+	ProductID      pgtype.UUID
+	VariantOptions []byte
+	ArchivedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Version        int32
+}
+
+func (q *Queries) ArchiveItem(ctx context.Context, arg ArchiveItemParams) (ArchiveItemRow, error) {
+	row := q.db.QueryRow(ctx, archiveItem, arg.Uuid, arg.Versions)
+	var i ArchiveItemRow
+	err := row.Scan(
+		&i.Uuid,
+		&i.Name,
+		&i.Sku,
+		&i.Quantity,
+		&i.ProductID,
+		&i.VariantOptions,
+		&i.ArchivedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
 const createItem = `-- name: CreateItem :one
-INSERT INTO items (name)
-VALUES ($1)
-RETURNING uuid, name, created_at
+INSERT INTO items (name, sku)
+VALUES ($1, $2)
+RETURNING uuid, name, sku, created_at
 `
 
+type CreateItemParams struct {
+	Name string
+	Sku  *string
+}
+
 type CreateItemRow struct {
 	Uuid      pgtype.UUID
 	Name      string
+	Sku       *string
 	CreatedAt pgtype.Timestamptz
 }
 
-func (q *Queries) CreateItem(ctx context.Context, name string) (CreateItemRow, error) {
-	row := q.db.QueryRow(ctx, createItem, name)
+func (q *Queries) CreateItem(ctx context.Context, arg CreateItemParams) (CreateItemRow, error) {
+	row := q.db.QueryRow(ctx, createItem, arg.Name, arg.Sku)
 	var i CreateItemRow
-	err := row.Scan(&i.Uuid, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.Uuid,
+		&i.Name,
+		&i.Sku,
+		&i.CreatedAt,
+	)
 	return i, err
 }
 
-const deleteItem = `-- name: DeleteItem :exec
-DELETE FROM items
-WHERE uuid = $1
-`
-
-func (q *Queries) DeleteItem(ctx context.Context, uuid pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteItem, uuid)
-	return err
-}
-
 const getItem = `-- name: GetItem :one
-SELECT uuid, name, quantity, created_at, updated_at
+SELECT uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
 FROM items
 WHERE uuid = $1
 `
 
 type GetItemRow struct {
-	Uuid      pgtype.UUID
-	Name      string
-	Quantity  int32
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+	Uuid           pgtype.UUID
+	Name           string
+	Sku            *string
+	Quantity       int32
+	ProductID      pgtype.UUID
+	VariantOptions []byte
+	ArchivedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Version        int32
 }
 
 func (q *Queries) GetItem(ctx context.Context, uuid pgtype.UUID) (GetItemRow, error) {
@@ -60,66 +112,102 @@ func (q *Queries) GetItem(ctx context.Context, uuid pgtype.UUID) (GetItemRow, er
 	err := row.Scan(
 		&i.Uuid,
 		&i.Name,
+		&i.Sku,
 		&i.Quantity,
+		&i.ProductID,
+		&i.VariantOptions,
+		&i.ArchivedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
+const getItemBySKUOrName = `-- name: GetItemBySKUOrName :one
+SELECT uuid, name, sku
+FROM items
+WHERE sku = $1 OR name = $2
+ORDER BY sku = $1 DESC NULLS LAST
+LIMIT 1
+`
+
+type GetItemBySKUOrNameParams struct {
+	Sku  *string
+	Name string
+}
+
+type GetItemBySKUOrNameRow struct {
+	Uuid pgtype.UUID
+	Name string
+	Sku  *string
+}
+
+func (q *Queries) GetItemBySKUOrName(ctx context.Context, arg GetItemBySKUOrNameParams) (GetItemBySKUOrNameRow, error) {
+	row := q.db.QueryRow(ctx, getItemBySKUOrName, arg.Sku, arg.Name)
+	var i GetItemBySKUOrNameRow
+	err := row.Scan(&i.Uuid, &i.Name, &i.Sku)
+	return i, err
+}
+
 const getItemQuantity = `-- name: GetItemQuantity :one
-SELECT uuid, quantity
+SELECT uuid, quantity, archived_at
 FROM items
 WHERE uuid = $1
 `
 
 type GetItemQuantityRow struct {
-	Uuid     pgtype.UUID
-	Quantity int32
+	Uuid       pgtype.UUID
+	Quantity   int32
+	ArchivedAt pgtype.Timestamptz
 }
 
 func (q *Queries) GetItemQuantity(ctx context.Context, uuid pgtype.UUID) (GetItemQuantityRow, error) {
 	row := q.db.QueryRow(ctx, getItemQuantity, uuid)
 	var i GetItemQuantityRow
-	err := row.Scan(&i.Uuid, &i.Quantity)
+	err := row.Scan(&i.Uuid, &i.Quantity, &i.ArchivedAt)
 	return i, err
 }
 
-const getNItemsOffset = `-- name: GetNItemsOffset :many
-SELECT uuid, name, quantity, created_at, updated_at
+const getItems = `-- name: GetItems :many
+SELECT uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
 FROM items
-ORDER BY id
-LIMIT $1 OFFSET $2
+WHERE uuid = ANY($1::uuid[])
 `
 
-type GetNItemsOffsetParams struct {
-	Limit  int32
-	Offset int32
+type GetItemsRow struct {
+	Uuid           pgtype.UUID
+	Name           string
+	Sku            *string
+	Quantity       int32
+	ProductID      pgtype.UUID
+	VariantOptions []byte
+	ArchivedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Version        int32
 }
 
-type GetNItemsOffsetRow struct {
-	Uuid      pgtype.UUID
-	Name      string
-	Quantity  int32
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
-}
-
-func (q *Queries) GetNItemsOffset(ctx context.Context, arg GetNItemsOffsetParams) ([]GetNItemsOffsetRow, error) {
-	rows, err := q.db.Query(ctx, getNItemsOffset, arg.Limit, arg.Offset)
+func (q *Queries) GetItems(ctx context.Context, uuids []pgtype.UUID) ([]GetItemsRow, error) {
+	rows, err := q.db.Query(ctx, getItems, uuids)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetNItemsOffsetRow
+	var items []GetItemsRow
 	for rows.Next() {
-		var i GetNItemsOffsetRow
+		var i GetItemsRow
 		if err := rows.Scan(
 			&i.Uuid,
 			&i.Name,
+			&i.Sku,
 			&i.Quantity,
+			&i.ProductID,
+			&i.VariantOptions,
+			&i.ArchivedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -131,57 +219,208 @@ func (q *Queries) GetNItemsOffset(ctx context.Context, arg GetNItemsOffsetParams
 	return items, nil
 }
 
+const lockItems = `-- name: LockItems :many
+SELECT uuid
+FROM items
+WHERE uuid = ANY($1::uuid[])
+ORDER BY uuid
+FOR UPDATE
+`
+
+func (q *Queries) LockItems(ctx context.Context, uuids []pgtype.UUID) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, lockItems, uuids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var uuid pgtype.UUID
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const patchItem = `-- name: PatchItem :one
+WITH previous AS (
+    SELECT id, quantity AS previous_quantity
+    FROM items
+    WHERE uuid = $1
+    FOR UPDATE
+)
 UPDATE items
 SET
     name = COALESCE($2, name),
-    quantity = COALESCE($3, quantity),
-    updated_at = now()
-WHERE uuid = $1 
-RETURNING uuid, name, quantity, created_at, updated_at
+    sku = COALESCE($3, sku),
+    quantity = COALESCE($4, quantity),
+    updated_at = now(),
+    version = version + 1
+FROM previous
+WHERE items.id = previous.id AND ($5::int[] IS NULL OR version = ANY($5::int[]))
+RETURNING items.uuid, items.name, items.sku, items.quantity, items.product_id, items.variant_options, items.archived_at, items.created_at, items.updated_at, items.version, previous.previous_quantity
 `
 
 type PatchItemParams struct {
 	Uuid     pgtype.UUID
 	Name     *string
+	Sku      *string
 	Quantity *int32
+	Versions []int32
 }
 
 type PatchItemRow struct {
-	Uuid      pgtype.UUID
-	Name      string
-	Quantity  int32
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+	Uuid             pgtype.UUID
+	Name             string
+	Sku              *string
+	Quantity         int32
+	ProductID        pgtype.UUID
+	VariantOptions   []byte
+	ArchivedAt       pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+	Version          int32
+	PreviousQuantity int32
 }
 
 func (q *Queries) PatchItem(ctx context.Context, arg PatchItemParams) (PatchItemRow, error) {
-	row := q.db.QueryRow(ctx, patchItem, arg.Uuid, arg.Name, arg.Quantity)
+	row := q.db.QueryRow(ctx, patchItem,
+		arg.Uuid,
+		arg.Name,
+		arg.Sku,
+		arg.Quantity,
+		arg.Versions,
+	)
 	var i PatchItemRow
 	err := row.Scan(
 		&i.Uuid,
 		&i.Name,
+		&i.Sku,
 		&i.Quantity,
+		&i.ProductID,
+		&i.VariantOptions,
+		&i.ArchivedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.PreviousQuantity,
 	)
 	return i, err
 }
 
-const setItemQuantity = `-- name: SetItemQuantity :exec
+const purgeItem = `-- name: PurgeItem :execrows
+DELETE FROM items
+WHERE uuid = $1 AND ($2::int[] IS NULL OR version = ANY($2::int[])) AND NOT EXISTS (
+    SELECT 1 FROM transactions WHERE transactions.item_id = items.uuid
+) AND NOT EXISTS (
+    SELECT 1 FROM stock_movements WHERE stock_movements.item_id = items.uuid
+)
+`
+
+type PurgeItemParams struct {
+	Uuid     pgtype.UUID
+	Versions []int32
+}
+
+func (q *Queries) PurgeItem(ctx context.Context, arg PurgeItemParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeItem, arg.Uuid, arg.Versions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restockItem = `-- name: RestockItem :one
+UPDATE items
+SET
+    quantity = quantity + $1::int,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $2 AND archived_at IS NULL
+RETURNING quantity
+`
+
+type RestockItemParams struct {
+	Amount int32
+	Uuid   pgtype.UUID
+}
+
+func (q *Queries) RestockItem(ctx context.Context, arg RestockItemParams) (int32, error) {
+	row := q.db.QueryRow(ctx, restockItem, arg.Amount, arg.Uuid)
+	var quantity int32
+	err := row.Scan(&quantity)
+	return quantity, err
+}
+
+const unarchiveItem = `-- name: UnarchiveItem :one
 UPDATE items
 SET
-    quantity = $1,
-    updated_at = now()
-WHERE uuid = $2
+    archived_at = NULL,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $1 AND ($2::int[] IS NULL OR version = ANY($2::int[]))
+RETURNING uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version
 `
 
-type SetItemQuantityParams struct {
-	Quantity int32
+type UnarchiveItemParams struct {
 	Uuid     pgtype.UUID
+	Versions []int32
+}
+
+type UnarchiveItemRow struct {
+	Uuid           pgtype.UUID
+	Name           string
+	Sku            *string
+	Quantity       int32
+	ProductID      pgtype.UUID
+	VariantOptions []byte
+	ArchivedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Version        int32
+}
+
+func (q *Queries) UnarchiveItem(ctx context.Context, arg UnarchiveItemParams) (UnarchiveItemRow, error) {
+	row := q.db.QueryRow(ctx, unarchiveItem, arg.Uuid, arg.Versions)
+	var i UnarchiveItemRow
+	err := row.Scan(
+		&i.Uuid,
+		&i.Name,
+		&i.Sku,
+		&i.Quantity,
+		&i.ProductID,
+		&i.VariantOptions,
+		&i.ArchivedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const withdrawItem = `-- name: WithdrawItem :one
+UPDATE items
+SET
+    quantity = quantity - $1::int,
+    updated_at = now(),
+    version = version + 1
+WHERE uuid = $2 AND archived_at IS NULL AND quantity >= $1::int
+RETURNING quantity
+`
+
+type WithdrawItemParams struct {
+	Amount int32
+	Uuid   pgtype.UUID
 }
 
-func (q *Queries) SetItemQuantity(ctx context.Context, arg SetItemQuantityParams) error {
-	_, err := q.db.Exec(ctx, setItemQuantity, arg.Quantity, arg.Uuid)
-	return err
+func (q *Queries) WithdrawItem(ctx context.Context, arg WithdrawItemParams) (int32, error) {
+	row := q.db.QueryRow(ctx, withdrawItem, arg.Amount, arg.Uuid)
+	var quantity int32
+	err := row.Scan(&quantity)
+	return quantity, err
 }
@@ -8,28 +8,110 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Attachment struct {
+	ID            pgtype.UUID
+	ItemID        pgtype.UUID
+	TransactionID pgtype.UUID
+	UserID        pgtype.UUID
+	Filename      string
+	ContentType   string
+	Size          int64
+	StorageKey    string
+	ThumbnailKey  *string
+	CreatedAt     pgtype.Timestamptz
+}
+
+type Event struct {
+	ID        int64
+	Type      string
+	ItemID    pgtype.UUID
+	Data      []byte
+	CreatedAt pgtype.Timestamptz
+	TxID      int64
+}
+
+type IdempotencyKey struct {
+	UserID       pgtype.UUID
+	Key          string
+	RequestHash  []byte
+	StatusCode   *int32
+	ResponseBody []byte
+	CreatedAt    pgtype.Timestamptz
+	ExpiresAt    pgtype.Timestamptz
+	ContentType  *string
+}
+
+type ImportJob struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Filename   string
+	DryRun     bool
+	Status     string
+	NRows      int32
+	Result     []byte
+	Error      *string
+	CreatedAt  pgtype.Timestamptz
+	FinishedAt pgtype.Timestamptz
+	Items      []byte
+	StartedAt  pgtype.Timestamptz
+}
+
 type Item struct {
+	ID             int32
+	Uuid           pgtype.UUID
+	Name           string
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Quantity       int32
+	Sku            *string
+	ProductID      pgtype.UUID
+	VariantOptions []byte
+	ArchivedAt     pgtype.Timestamptz
+	Version        int32
+}
+
+type ItemBarcode struct {
+	ID        int32
+	ItemID    pgtype.UUID
+	Code      string
+	Format    string
+	CreatedAt pgtype.Timestamptz
+}
+
+type Product struct {
 	ID        int32
 	Uuid      pgtype.UUID
 	Name      string
+	Sku       *string
+	Options   []byte
 	CreatedAt pgtype.Timestamptz
 	UpdatedAt pgtype.Timestamptz
-	Quantity  int32
 }
 
 type SchemaMigration struct {
 	Version string
 }
 
+type StockMovement struct {
+	ID            int64
+	ItemID        pgtype.UUID
+	Delta         int32
+	Kind          string
+	TransactionID pgtype.UUID
+	UserID        pgtype.UUID
+	CreatedAt     pgtype.Timestamptz
+}
+
 type Transaction struct {
-	ID        pgtype.UUID
-	UserID    pgtype.UUID
-	ItemID    pgtype.UUID
-	Type      string
-	Amount    int32
-	Status    string
-	Reason    *string
-	CreatedAt pgtype.Timestamptz
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	ItemID     pgtype.UUID
+	Type       string
+	Amount     int32
+	Status     string
+	Reason     *string
+	CreatedAt  pgtype.Timestamptz
+	ReversalOf pgtype.UUID
 }
 
 type User struct {
@@ -40,3 +122,31 @@ type User struct {
 	RefreshToken string
 	CreatedAt    pgtype.Timestamptz
 }
+
+type Webhook struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Url        string
+	EventTypes []string
+	Secret     string
+	Failures   int32
+	DisabledAt pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID             pgtype.UUID
+	WebhookID      pgtype.UUID
+	EventID        int64
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamptz
+	ResponseStatus *int32
+	Error          *string
+	ReplayOf       pgtype.UUID
+	CreatedAt      pgtype.Timestamptz
+	DeliveredAt    pgtype.Timestamptz
+	TxID           int64
+}
@@ -0,0 +1,327 @@
+package database
+
+// Not generated: sqlc can't express optional filters combined with a
+// client-chosen ORDER BY, so the items search is assembled here. Values only
+// ever reach the query as parameters, column names come from ItemSortColumns.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ItemSortColumns maps the sort keys accepted from clients to columns.
+var ItemSortColumns = map[string]string{
+	"name":       "name",
+	"sku":        "sku",
+	"quantity":   "quantity",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// itemsSearchVector has to stay the same as the expression of items_search_idx.
+const itemsSearchVector = `to_tsvector('simple', name || ' ' || COALESCE(sku, ''))`
+
+const searchItems = `-- name: SearchItems :many
+SELECT id, uuid, name, sku, quantity, product_id, variant_options, archived_at, created_at, updated_at, version`
+
+type ItemSort struct {
+	// one of the ItemSortColumns keys
+	Column string
+	Desc   bool
+}
+
+type SearchItemsParams struct {
+	// negative for all the rows
+	Limit  int32
+	Offset int32
+	// nil for both archived and active items
+	Archived *bool
+	// substrings, case-insensitive
+	Name *string
+	Sku  *string
+	// full-text search with a trigram fallback for typos
+	Query         *string
+	MinQuantity   *int32
+	MaxQuantity   *int32
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	UpdatedAfter  pgtype.Timestamptz
+	UpdatedBefore pgtype.Timestamptz
+	Sort          []ItemSort
+	// continues right after (or before, if Backward) the row with these sort keys,
+	// Offset should be zero then
+	Keyset *ItemsKeyset
+}
+
+// ItemsKeyset holds the sort keys of the row a page starts from. Only the
+// keys of the current sort have to be set, ID is always used.
+type ItemsKeyset struct {
+	ID         int64
+	Name       string
+	Sku        *string
+	Quantity   int32
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Rank       float32
+	Similarity float32
+	// for the previous page
+	Backward bool
+}
+
+type SearchItemsRow struct {
+	ID int64
+	// the same columns as GetItem
+	GetItemRow
+	// only set when searching by Query
+	Rank       float32
+	Similarity float32
+}
+
+func (q *Queries) SearchItems(ctx context.Context, arg SearchItemsParams) ([]SearchItemsRow, error) {
+	query, args, err := buildSearchItems(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchItemsRow
+	for rows.Next() {
+		i, err := scanSearchItemsRow(rows, arg.Query != nil)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if arg.Keyset != nil && arg.Keyset.Backward {
+		// fetched in the reverse order
+		slices.Reverse(items)
+	}
+	return items, nil
+}
+
+func scanSearchItemsRow(rows pgx.Rows, ranked bool) (SearchItemsRow, error) {
+	var i SearchItemsRow
+	dest := []any{
+		&i.ID,
+		&i.Uuid,
+		&i.Name,
+		&i.Sku,
+		&i.Quantity,
+		&i.ProductID,
+		&i.VariantOptions,
+		&i.ArchivedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	}
+	if ranked {
+		dest = append(dest, &i.Rank, &i.Similarity)
+	}
+	err := rows.Scan(dest...)
+	return i, err
+}
+
+type queryBuilder struct {
+	args []any
+}
+
+// arg registers the value and returns its placeholder.
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// orderKey is a single expression of ORDER BY.
+type orderKey struct {
+	expr     string
+	desc     bool
+	nullable bool
+	// value of the expression in the keyset
+	value any
+}
+
+func buildSearchItems(arg SearchItemsParams) (string, []any, error) {
+	var b queryBuilder
+	var where []string
+
+	if arg.Archived != nil {
+		where = append(where, "(archived_at IS NOT NULL) = "+b.arg(*arg.Archived))
+	}
+	if arg.Name != nil {
+		where = append(where, "name ILIKE '%' || "+b.arg(EscapeLike(*arg.Name))+" || '%'")
+	}
+	if arg.Sku != nil {
+		where = append(where, "sku ILIKE '%' || "+b.arg(EscapeLike(*arg.Sku))+" || '%'")
+	}
+	var rank, similarity string
+	if arg.Query != nil {
+		search := b.arg(*arg.Query)
+		where = append(where, fmt.Sprintf(
+			"(%s @@ websearch_to_tsquery('simple', %s) OR name %% %s OR sku %% %s)",
+			itemsSearchVector, search, search, search,
+		))
+		rank = fmt.Sprintf("ts_rank(%s, websearch_to_tsquery('simple', %s))", itemsSearchVector, search)
+		similarity = fmt.Sprintf("similarity(name, %s)", search)
+	}
+	if arg.MinQuantity != nil {
+		where = append(where, "quantity >= "+b.arg(*arg.MinQuantity))
+	}
+	if arg.MaxQuantity != nil {
+		where = append(where, "quantity <= "+b.arg(*arg.MaxQuantity))
+	}
+	if arg.CreatedAfter.Valid {
+		where = append(where, "created_at >= "+b.arg(arg.CreatedAfter))
+	}
+	if arg.CreatedBefore.Valid {
+		where = append(where, "created_at < "+b.arg(arg.CreatedBefore))
+	}
+	if arg.UpdatedAfter.Valid {
+		where = append(where, "updated_at >= "+b.arg(arg.UpdatedAfter))
+	}
+	if arg.UpdatedBefore.Valid {
+		where = append(where, "updated_at < "+b.arg(arg.UpdatedBefore))
+	}
+
+	var ks ItemsKeyset
+	if arg.Keyset != nil {
+		ks = *arg.Keyset
+	}
+
+	var order []orderKey
+	for _, s := range arg.Sort {
+		col, ok := ItemSortColumns[s.Column]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown sort column %q", s.Column)
+		}
+		key := orderKey{expr: col, desc: s.Desc}
+		switch col {
+		case "name":
+			key.value = ks.Name
+		case "sku":
+			key.value, key.nullable = ks.Sku, true
+		case "quantity":
+			key.value = ks.Quantity
+		case "created_at":
+			key.value = pgtype.Timestamptz{Time: ks.CreatedAt, Valid: true}
+		case "updated_at":
+			key.value = pgtype.Timestamptz{Time: ks.UpdatedAt, Valid: true}
+		}
+		order = append(order, key)
+	}
+	if len(order) == 0 && rank != "" {
+		// best matches first, typo matches after the exact ones
+		order = append(order,
+			orderKey{expr: rank, desc: true, value: ks.Rank},
+			orderKey{expr: similarity, desc: true, value: ks.Similarity},
+		)
+	}
+	// stable pages for equal keys
+	order = append(order, orderKey{expr: "id", value: ks.ID})
+
+	backward := arg.Keyset != nil && arg.Keyset.Backward
+	if arg.Keyset != nil {
+		where = append(where, keysetCondition(&b, order, backward))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(searchItems)
+	if rank != "" {
+		sb.WriteString(", " + rank + ", " + similarity)
+	}
+	sb.WriteString("\nFROM items\n")
+	if len(where) > 0 {
+		sb.WriteString("WHERE " + strings.Join(where, "\n  AND ") + "\n")
+	}
+	terms := make([]string, len(order))
+	for i, k := range order {
+		// reading the page backwards when going to the previous one
+		if k.desc != backward {
+			terms[i] = k.expr + " DESC"
+		} else {
+			terms[i] = k.expr + " ASC"
+		}
+		if k.nullable {
+			if backward {
+				terms[i] += " NULLS FIRST"
+			} else {
+				terms[i] += " NULLS LAST"
+			}
+		}
+	}
+	sb.WriteString("ORDER BY " + strings.Join(terms, ", ") + "\n")
+	if arg.Limit >= 0 {
+		sb.WriteString("LIMIT " + b.arg(arg.Limit) + " OFFSET " + b.arg(arg.Offset))
+	}
+
+	return sb.String(), b.args, nil
+}
+
+// keysetCondition matches the rows that come after the keyset in the given
+// order, or before it if backward. NULLs are sorted last.
+func keysetCondition(b *queryBuilder, order []orderKey, backward bool) string {
+	// (k1 after v1) OR (k1 = v1 AND k2 after v2) OR ...
+	var alts []string
+	var equal []string
+	for _, k := range order {
+		isNull := k.value == nil || k.value == (*string)(nil)
+		var ph string
+		if !isNull {
+			ph = b.arg(k.value)
+		}
+
+		var after string
+		switch {
+		case !backward && isNull:
+			// nothing comes after NULLs
+		case !backward:
+			op := ">"
+			if k.desc {
+				op = "<"
+			}
+			after = k.expr + " " + op + " " + ph
+			if k.nullable {
+				after = "(" + after + " OR " + k.expr + " IS NULL)"
+			}
+		case isNull:
+			after = k.expr + " IS NOT NULL"
+		default:
+			op := "<"
+			if k.desc {
+				op = ">"
+			}
+			after = k.expr + " " + op + " " + ph
+		}
+		if after != "" {
+			alts = append(alts, strings.Join(append(slices.Clone(equal), after), " AND "))
+		}
+
+		if isNull {
+			equal = append(equal, k.expr+" IS NULL")
+		} else {
+			equal = append(equal, k.expr+" = "+ph)
+		}
+	}
+	if len(alts) == 0 {
+		return "false"
+	}
+	return "((" + strings.Join(alts, ") OR (") + "))"
+}
+
+// EscapeLike makes s match literally inside a LIKE pattern.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
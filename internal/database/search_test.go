@@ -0,0 +1,115 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	sku := "GL-L"
+	tests := []struct {
+		name     string
+		order    []orderKey
+		backward bool
+		want     string
+		args     []any
+	}{
+		{
+			name:  "ascending",
+			order: []orderKey{{expr: "name", value: "glove"}, {expr: "id", value: int64(7)}},
+			want:  "((name > $1) OR (name = $1 AND id > $2))",
+			args:  []any{"glove", int64(7)},
+		},
+		{
+			name:  "descending",
+			order: []orderKey{{expr: "quantity", desc: true, value: int32(3)}, {expr: "id", value: int64(7)}},
+			want:  "((quantity < $1) OR (quantity = $1 AND id > $2))",
+			args:  []any{int32(3), int64(7)},
+		},
+		{
+			name:     "ascending backward",
+			order:    []orderKey{{expr: "name", value: "glove"}, {expr: "id", value: int64(7)}},
+			backward: true,
+			want:     "((name < $1) OR (name = $1 AND id < $2))",
+			args:     []any{"glove", int64(7)},
+		},
+		{
+			name:     "descending backward",
+			order:    []orderKey{{expr: "quantity", desc: true, value: int32(3)}, {expr: "id", value: int64(7)}},
+			backward: true,
+			want:     "((quantity > $1) OR (quantity = $1 AND id < $2))",
+			args:     []any{int32(3), int64(7)},
+		},
+		{
+			name:  "sku, NULLs come after it",
+			order: []orderKey{{expr: "sku", nullable: true, value: &sku}, {expr: "id", value: int64(7)}},
+			want:  "(((sku > $1 OR sku IS NULL)) OR (sku = $1 AND id > $2))",
+			args:  []any{&sku, int64(7)},
+		},
+		{
+			name:  "descending sku, NULLs still last",
+			order: []orderKey{{expr: "sku", desc: true, nullable: true, value: &sku}, {expr: "id", value: int64(7)}},
+			want:  "(((sku < $1 OR sku IS NULL)) OR (sku = $1 AND id > $2))",
+			args:  []any{&sku, int64(7)},
+		},
+		{
+			name:     "sku backward, NULLs aren't before it",
+			order:    []orderKey{{expr: "sku", nullable: true, value: &sku}, {expr: "id", value: int64(7)}},
+			backward: true,
+			want:     "((sku < $1) OR (sku = $1 AND id < $2))",
+			args:     []any{&sku, int64(7)},
+		},
+		{
+			name:  "no sku, only NULLs after it",
+			order: []orderKey{{expr: "sku", nullable: true, value: (*string)(nil)}, {expr: "id", value: int64(7)}},
+			want:  "((sku IS NULL AND id > $1))",
+			args:  []any{int64(7)},
+		},
+		{
+			name:     "no sku backward, every sku is before it",
+			order:    []orderKey{{expr: "sku", nullable: true, value: (*string)(nil)}, {expr: "id", value: int64(7)}},
+			backward: true,
+			want:     "((sku IS NOT NULL) OR (sku IS NULL AND id < $1))",
+			args:     []any{int64(7)},
+		},
+		{
+			name:  "nothing after the last NULL",
+			order: []orderKey{{expr: "sku", nullable: true, value: (*string)(nil)}},
+			want:  "false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b queryBuilder
+			require.Equal(t, tt.want, keysetCondition(&b, tt.order, tt.backward))
+			require.Equal(t, tt.args, b.args)
+		})
+	}
+}
+
+func TestBuildSearchItemsOrder(t *testing.T) {
+	orderBy := func(arg SearchItemsParams) string {
+		t.Helper()
+		query, _, err := buildSearchItems(arg)
+		require.NoError(t, err)
+		_, order, ok := strings.Cut(query, "ORDER BY ")
+		require.True(t, ok)
+		order, _, _ = strings.Cut(order, "\n")
+		return order
+	}
+	sort := []ItemSort{{Column: "sku", Desc: true}}
+
+	require.Equal(t, "id ASC", orderBy(SearchItemsParams{Limit: -1}))
+	require.Equal(t, "sku DESC NULLS LAST, id ASC", orderBy(SearchItemsParams{Limit: -1, Sort: sort}))
+	// the previous page is read in the reverse order
+	require.Equal(t, "sku ASC NULLS FIRST, id DESC", orderBy(SearchItemsParams{
+		Limit:  -1,
+		Sort:   sort,
+		Keyset: &ItemsKeyset{ID: 7, Backward: true},
+	}))
+
+	_, _, err := buildSearchItems(SearchItemsParams{Sort: []ItemSort{{Column: "secret"}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package database
+
+// Not generated: pgx has no type for pg_snapshot, it's read as text.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Snapshot is what a transaction sees of the others: the ones below Xmin
+// were over, the ones from Xmax on hadn't started and the Running ones
+// hadn't committed.
+type Snapshot struct {
+	Xmin, Xmax int64
+	Running    []int64
+}
+
+// Sees tells if what the transaction txID wrote is in the snapshot, like
+// pg_visible_in_snapshot.
+func (s Snapshot) Sees(txID int64) bool {
+	return txID < s.Xmin || txID < s.Xmax && !slices.Contains(s.Running, txID)
+}
+
+const getSnapshot = `SELECT pg_current_snapshot()::text`
+
+// GetSnapshot is the snapshot of the current transaction, the one of the
+// statement outside of one.
+func (q *Queries) GetSnapshot(ctx context.Context) (Snapshot, error) {
+	var s string
+	if err := q.db.QueryRow(ctx, getSnapshot).Scan(&s); err != nil {
+		return Snapshot{}, err
+	}
+	return parseSnapshot(s)
+}
+
+// parseSnapshot reads "xmin:xmax:running,...".
+func parseSnapshot(s string) (Snapshot, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %q", s)
+	}
+	var snap Snapshot
+	var err error
+	if snap.Xmin, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %q: %w", s, err)
+	}
+	if snap.Xmax, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %q: %w", s, err)
+	}
+	if parts[2] == "" {
+		return snap, nil
+	}
+	for _, v := range strings.Split(parts[2], ",") {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("invalid snapshot %q: %w", s, err)
+		}
+		snap.Running = append(snap.Running, id)
+	}
+	return snap, nil
+}
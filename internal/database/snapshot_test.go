@@ -0,0 +1,30 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSnapshot(t *testing.T) {
+	snap, err := parseSnapshot("10:20:12,15")
+	require.NoError(t, err)
+	require.Equal(t, Snapshot{Xmin: 10, Xmax: 20, Running: []int64{12, 15}}, snap)
+
+	require.True(t, snap.Sees(9))
+	require.True(t, snap.Sees(10))
+	require.False(t, snap.Sees(12))
+	require.True(t, snap.Sees(13))
+	require.False(t, snap.Sees(20))
+
+	snap, err = parseSnapshot("7:7:")
+	require.NoError(t, err)
+	require.Equal(t, Snapshot{Xmin: 7, Xmax: 7}, snap)
+	require.True(t, snap.Sees(6))
+	require.False(t, snap.Sees(7))
+
+	for _, s := range []string{"", "7:7", "a:7:", "7:7:x"} {
+		_, err := parseSnapshot(s)
+		require.Error(t, err, s)
+	}
+}
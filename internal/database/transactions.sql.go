@@ -45,25 +45,86 @@ func (q *Queries) CreateNewTransaction(ctx context.Context, arg CreateNewTransac
 	return i, err
 }
 
-const getAllTransactions = `-- name: GetAllTransactions :many
-SELECT id, user_id, item_id, type, amount, status, reason, created_at FROM transactions
-LIMIT $1 OFFSET $2
+const createReversalTransaction = `-- name: CreateReversalTransaction :one
+INSERT INTO transactions (user_id, item_id, type, amount, status, reason, reversal_of)
+VALUES ($1, $2, $3, $4, 'succeeded', $5, $6)
+RETURNING id, user_id, item_id, type, amount, status, reason, created_at, reversal_of
 `
 
-type GetAllTransactionsParams struct {
-	Limit  int32
-	Offset int32
+type CreateReversalTransactionParams struct {
+	UserID     pgtype.UUID
+	ItemID     pgtype.UUID
+	Type       string
+	Amount     int32
+	Reason     *string
+	ReversalOf pgtype.UUID
 }
 
-func (q *Queries) GetAllTransactions(ctx context.Context, arg GetAllTransactionsParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, getAllTransactions, arg.Limit, arg.Offset)
+func (q *Queries) CreateReversalTransaction(ctx context.Context, arg CreateReversalTransactionParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, createReversalTransaction,
+		arg.UserID,
+		arg.ItemID,
+		arg.Type,
+		arg.Amount,
+		arg.Reason,
+		arg.ReversalOf,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ItemID,
+		&i.Type,
+		&i.Amount,
+		&i.Status,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.ReversalOf,
+	)
+	return i, err
+}
+
+const getRecentTransactionsByItems = `-- name: GetRecentTransactionsByItems :many
+SELECT t.id, t.user_id, t.item_id, t.type, t.amount, t.status, t.reason, t.created_at, t.reversal_of
+FROM unnest($1::uuid[]) AS ids(item_id)
+CROSS JOIN LATERAL (
+    SELECT *
+    FROM transactions
+    WHERE transactions.item_id = ids.item_id
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2::int
+) t
+ORDER BY t.item_id, t.created_at DESC, t.id DESC
+`
+
+type GetRecentTransactionsByItemsParams struct {
+	ItemIds []pgtype.UUID
+	N       int32
+}
+
+type GetRecentTransactionsByItemsRow struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	ItemID     pgtype.UUID
+	Type       string
+	Amount     int32
+	Status     string
+	Reason     *string
+	CreatedAt  pgtype.Timestamptz
+	ReversalOf pgtype.UUID
+}
+
+// reads at most n rows of each item from the index instead of ranking
+// their whole history.
+func (q *Queries) GetRecentTransactionsByItems(ctx context.Context, arg GetRecentTransactionsByItemsParams) ([]GetRecentTransactionsByItemsRow, error) {
+	rows, err := q.db.Query(ctx, getRecentTransactionsByItems, arg.ItemIds, arg.N)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Transaction
+	var items []GetRecentTransactionsByItemsRow
 	for rows.Next() {
-		var i Transaction
+		var i GetRecentTransactionsByItemsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
@@ -73,6 +134,7 @@ func (q *Queries) GetAllTransactions(ctx context.Context, arg GetAllTransactions
 			&i.Status,
 			&i.Reason,
 			&i.CreatedAt,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -85,7 +147,7 @@ func (q *Queries) GetAllTransactions(ctx context.Context, arg GetAllTransactions
 }
 
 const getTransaction = `-- name: GetTransaction :one
-SELECT id, user_id, item_id, type, amount, status, reason, created_at
+SELECT id, user_id, item_id, type, amount, status, reason, created_at, reversal_of
 FROM transactions
 WHERE id = $1
 `
@@ -102,88 +164,31 @@ func (q *Queries) GetTransaction(ctx context.Context, id pgtype.UUID) (Transacti
 		&i.Status,
 		&i.Reason,
 		&i.CreatedAt,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
-const getTransactionsForItem = `-- name: GetTransactionsForItem :many
-SELECT id, user_id, item_id, type, amount, status, reason, created_at FROM transactions
-WHERE item_id = $1
-LIMIT $2 OFFSET $3
-`
-
-type GetTransactionsForItemParams struct {
-	ItemID pgtype.UUID
-	Limit  int32
-	Offset int32
-}
-
-func (q *Queries) GetTransactionsForItem(ctx context.Context, arg GetTransactionsForItemParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, getTransactionsForItem, arg.ItemID, arg.Limit, arg.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Transaction
-	for rows.Next() {
-		var i Transaction
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.ItemID,
-			&i.Type,
-			&i.Amount,
-			&i.Status,
-			&i.Reason,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const getTransactionsForUser = `-- name: GetTransactionsForUser :many
-SELECT id, user_id, item_id, type, amount, status, reason, created_at FROM transactions
-WHERE user_id = $1
-LIMIT $2 OFFSET $3
+const markTransactionReversed = `-- name: MarkTransactionReversed :one
+UPDATE transactions
+SET status = 'reversed'
+WHERE id = $1 AND status = 'succeeded' AND reversal_of IS NULL
+RETURNING id, user_id, item_id, type, amount, status, reason, created_at, reversal_of
 `
 
-type GetTransactionsForUserParams struct {
-	UserID pgtype.UUID
-	Limit  int32
-	Offset int32
-}
-
-func (q *Queries) GetTransactionsForUser(ctx context.Context, arg GetTransactionsForUserParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, getTransactionsForUser, arg.UserID, arg.Limit, arg.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Transaction
-	for rows.Next() {
-		var i Transaction
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.ItemID,
-			&i.Type,
-			&i.Amount,
-			&i.Status,
-			&i.Reason,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) MarkTransactionReversed(ctx context.Context, id pgtype.UUID) (Transaction, error) {
+	row := q.db.QueryRow(ctx, markTransactionReversed, id)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ItemID,
+		&i.Type,
+		&i.Amount,
+		&i.Status,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.ReversalOf,
+	)
+	return i, err
 }
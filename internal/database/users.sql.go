@@ -106,6 +106,44 @@ func (q *Queries) GetUserRole(ctx context.Context, id pgtype.UUID) (GetUserRoleR
 	return i, err
 }
 
+const getUsers = `-- name: GetUsers :many
+SELECT id, username, role, created_at
+FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+type GetUsersRow struct {
+	ID        pgtype.UUID
+	Username  string
+	Role      string
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetUsers(ctx context.Context, ids []pgtype.UUID) ([]GetUsersRow, error) {
+	rows, err := q.db.Query(ctx, getUsers, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersRow
+	for rows.Next() {
+		var i GetUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const setRefreshToken = `-- name: SetRefreshToken :one
 UPDATE users
 SET refresh_token = $1
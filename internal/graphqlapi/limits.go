@@ -0,0 +1,143 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// levels of nested fields, Query.items.nodes.recentTransactions.owner
+	// is 4
+	MaxDepth = 8
+	// every field costs 1, and what's under a field with a limit argument
+	// costs as many times as the limit
+	MaxComplexity = 5000
+)
+
+// checkLimits refuses the operation if it's nested deeper than MaxDepth or
+// may cost more than MaxComplexity. The document has to be validated first,
+// so there are no fragment cycles. Introspection isn't counted.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]any) error {
+	var op *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if op == nil {
+		// graphql-go reports it
+		return nil
+	}
+
+	root := schema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	w := limitsWalker{fragments: fragments, variables: variables}
+	depth, cost := w.selectionSet(op.SelectionSet, root)
+	if depth > MaxDepth {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("the query is %d levels deep, at most %d are allowed", depth, MaxDepth))
+	}
+	if cost > MaxComplexity {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("the query may cost %d, at most %d is allowed", cost, MaxComplexity))
+	}
+	return nil
+}
+
+type limitsWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func (w limitsWalker) selectionSet(set *ast.SelectionSet, parent *graphql.Object) (depth, cost int) {
+	if set == nil || parent == nil {
+		return 0, 0
+	}
+
+	for _, sel := range set.Selections {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d, c = w.field(sel, parent)
+		case *ast.InlineFragment:
+			d, c = w.selectionSet(sel.SelectionSet, parent)
+		case *ast.FragmentSpread:
+			if frag, ok := w.fragments[sel.Name.Value]; ok {
+				d, c = w.selectionSet(frag.SelectionSet, parent)
+			}
+		}
+		depth = max(depth, d)
+		cost = min(cost+c, MaxComplexity+1)
+	}
+	return depth, cost
+}
+
+func (w limitsWalker) field(f *ast.Field, parent *graphql.Object) (depth, cost int) {
+	name := f.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0, 0
+	}
+	def, ok := parent.Fields()[name]
+	if !ok {
+		return 1, 1
+	}
+
+	times := 1
+	for _, arg := range def.Args {
+		if arg.Name() == "limit" {
+			if n, ok := arg.DefaultValue.(int); ok {
+				times = n
+			}
+		}
+	}
+	for _, arg := range f.Arguments {
+		if arg.Name.Value == "limit" {
+			if n, ok := w.int(arg.Value); ok {
+				times = n
+			}
+		}
+	}
+
+	d, c := w.selectionSet(f.SelectionSet, objectOf(def.Type))
+	return d + 1, min(1+max(times, 1)*c, MaxComplexity+1)
+}
+
+func (w limitsWalker) int(v ast.Value) (int, bool) {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		// decoded from JSON
+		f, ok := w.variables[v.Name.Value].(float64)
+		return int(f), ok
+	}
+	return 0, false
+}
+
+func objectOf(t graphql.Type) *graphql.Object {
+	for {
+		switch tt := t.(type) {
+		case *graphql.NonNull:
+			t = tt.OfType
+		case *graphql.List:
+			t = tt.OfType
+		case *graphql.Object:
+			return tt
+		default:
+			return nil
+		}
+	}
+}
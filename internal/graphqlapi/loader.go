@@ -0,0 +1,154 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// loader batches the keys the resolvers ask for into one query. graphql-go
+// resolves thunks breadth first, so every element of a list has asked for its
+// relations before the first thunk runs and fetches them all.
+type loader[K comparable, V any] struct {
+	fetch func(context.Context, []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:   fetch,
+		queued:  map[K]bool{},
+		results: map[K]V{},
+		errs:    map[K]error{},
+	}
+}
+
+// load queues the key and returns a thunk for graphql-go, which gives nil
+// when there is nothing under the key.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (any, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		v, ok, err := l.get(ctx, key)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+func (l *loader[K, V]) get(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) > 0 {
+		keys := l.pending
+		l.pending = nil
+		found, err := l.fetch(ctx, keys)
+		for _, k := range keys {
+			if err != nil {
+				l.errs[k] = err
+			} else if v, ok := found[k]; ok {
+				l.results[k] = v
+			}
+		}
+	}
+
+	if err := l.errs[key]; err != nil {
+		var none V
+		return none, false, err
+	}
+	v, ok := l.results[key]
+	return v, ok, nil
+}
+
+// loaders live as long as a request, so nothing is cached for longer.
+type loaders struct {
+	app   handlers.App
+	items *loader[string, schemas.Item]
+	users *loader[string, schemas.User]
+
+	mu sync.Mutex
+	// by the number of transactions asked for
+	recent map[int]*loader[string, []schemas.Transaction]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, app handlers.App) context.Context {
+	l := &loaders{app: app, recent: map[int]*loader[string, []schemas.Transaction]{}}
+	l.items = newLoader(func(ctx context.Context, keys []string) (map[string]schemas.Item, error) {
+		items, err := app.GetItemsByUUID(ctx, uuids(keys))
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]schemas.Item, len(items))
+		for _, item := range items {
+			found[item.UUID] = item
+		}
+		return found, nil
+	})
+	l.users = newLoader(func(ctx context.Context, keys []string) (map[string]schemas.User, error) {
+		users, err := app.GetUsers(ctx, uuids(keys))
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]schemas.User, len(users))
+		for _, u := range users {
+			found[u.UUID] = u
+		}
+		return found, nil
+	})
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// recentTransactions is the loader of the last n transactions of items.
+func (l *loaders) recentTransactions(n int) *loader[string, []schemas.Transaction] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ld, ok := l.recent[n]; ok {
+		return ld
+	}
+	ld := newLoader(func(ctx context.Context, keys []string) (map[string][]schemas.Transaction, error) {
+		trs, err := l.app.GetRecentTransactions(ctx, uuids(keys), n)
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string][]schemas.Transaction, len(keys))
+		for _, tr := range trs {
+			found[tr.ItemUUID] = append(found[tr.ItemUUID], tr)
+		}
+		return found, nil
+	})
+	l.recent[n] = ld
+	return ld
+}
+
+// uuids skips the keys that aren't UUIDs, there is nothing under them.
+func uuids(keys []string) []pgtype.UUID {
+	res := make([]pgtype.UUID, 0, len(keys))
+	for _, k := range keys {
+		if u, err := handlers.UUIDFromString(k); err == nil {
+			res = append(res, u)
+		}
+	}
+	return res
+}
@@ -0,0 +1,423 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DefaultListLimit = 20
+	// of Item.recentTransactions
+	DefaultRecentLimit = 5
+	MaxRecentLimit     = 20
+)
+
+var timestampType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Timestamp",
+	Description: "Unix time in seconds.",
+	Serialize: func(v any) any {
+		if n, ok := v.(int64); ok {
+			return n
+		}
+		return nil
+	},
+	ParseValue: func(v any) any {
+		// variables are decoded from JSON
+		if f, ok := v.(float64); ok && f >= 0 && f <= math.MaxInt64 && f == math.Trunc(f) {
+			return int64(f)
+		}
+		return nil
+	},
+	ParseLiteral: func(v ast.Value) any {
+		if iv, ok := v.(*ast.IntValue); ok {
+			if n, err := strconv.ParseInt(iv.Value, 10, 64); err == nil && n >= 0 {
+				return n
+			}
+		}
+		return nil
+	},
+})
+
+var roleType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Role",
+	Values: graphql.EnumValueConfigMap{
+		"USER":    {Value: schemas.RoleUser},
+		"STOCKER": {Value: schemas.RoleStocker},
+		"ADMIN":   {Value: schemas.RoleAdmin},
+	},
+})
+
+var transactionTypeType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "TransactionType",
+	Values: graphql.EnumValueConfigMap{
+		"RESTOCK":  {Value: schemas.TransactionTypeRestock},
+		"WITHDRAW": {Value: schemas.TransactionTypeWithdraw},
+	},
+})
+
+var transactionStatusType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "TransactionStatus",
+	Values: graphql.EnumValueConfigMap{
+		"SUCCEEDED": {Value: schemas.TransactionStatusSucceeded},
+		"FAILED":    {Value: schemas.TransactionStatusFailed},
+		"REVERSED":  {Value: schemas.TransactionStatusReversed},
+	},
+})
+
+var archivedType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ArchivedFilter",
+	Values: graphql.EnumValueConfigMap{
+		"EXCLUDE": {Value: "exclude"},
+		"INCLUDE": {Value: "include"},
+		"ONLY":    {Value: "only"},
+	},
+})
+
+var itemFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "ItemFilter",
+	Description: "The filters of GET /items.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"archived":      {Type: archivedType, DefaultValue: "exclude"},
+		"name":          {Type: graphql.String, Description: "case-insensitive substring"},
+		"sku":           {Type: graphql.String, Description: "case-insensitive substring"},
+		"q":             {Type: graphql.String, Description: "full-text search over name and SKU"},
+		"minQuantity":   {Type: graphql.Int},
+		"maxQuantity":   {Type: graphql.Int},
+		"createdAfter":  {Type: timestampType, Description: "inclusive"},
+		"createdBefore": {Type: timestampType, Description: "exclusive"},
+		"updatedAfter":  {Type: timestampType, Description: "inclusive"},
+		"updatedBefore": {Type: timestampType, Description: "exclusive"},
+	},
+})
+
+var transactionFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "TransactionFilter",
+	Description: "The filters of GET /transactions.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"type":          {Type: transactionTypeType},
+		"status":        {Type: transactionStatusType},
+		"userUuid":      {Type: graphql.ID},
+		"itemUuid":      {Type: graphql.ID},
+		"createdAfter":  {Type: timestampType, Description: "inclusive"},
+		"createdBefore": {Type: timestampType, Description: "exclusive"},
+		"minAmount":     {Type: graphql.Int},
+		"maxAmount":     {Type: graphql.Int},
+	},
+})
+
+// listArgs are the pagination of the lists, the cursors are the ones of the
+// REST API.
+func listArgs(filter *graphql.InputObject) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"filter": {Type: filter},
+		"limit":  {Type: graphql.Int, DefaultValue: DefaultListLimit, Description: "at most 100"},
+		"cursor": {Type: graphql.String, Description: "nextCursor or prevCursor of a previous page"},
+	}
+}
+
+func (s *Server) newSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"uuid":      {Type: graphql.NewNonNull(graphql.ID), Resolve: prop(func(u schemas.User) any { return u.UUID })},
+			"username":  {Type: graphql.NewNonNull(graphql.String), Resolve: prop(func(u schemas.User) any { return u.Username })},
+			"role":      {Type: graphql.NewNonNull(roleType), Resolve: prop(func(u schemas.User) any { return u.Role })},
+			"createdAt": {Type: graphql.NewNonNull(timestampType), Resolve: prop(func(u schemas.User) any { return u.CreatedAt })},
+		},
+	})
+
+	var itemType *graphql.Object
+	transactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transaction",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"uuid":      {Type: graphql.NewNonNull(graphql.ID), Resolve: prop(func(tr schemas.Transaction) any { return tr.UUID })},
+				"type":      {Type: graphql.NewNonNull(transactionTypeType), Resolve: prop(func(tr schemas.Transaction) any { return tr.Type })},
+				"status":    {Type: graphql.NewNonNull(transactionStatusType), Resolve: prop(func(tr schemas.Transaction) any { return tr.Status })},
+				"amount":    {Type: graphql.NewNonNull(graphql.Int), Resolve: prop(func(tr schemas.Transaction) any { return tr.Amount })},
+				"reason":    {Type: graphql.String, Resolve: prop(func(tr schemas.Transaction) any { return nullString(tr.Reason) })},
+				"createdAt": {Type: graphql.NewNonNull(timestampType), Resolve: prop(func(tr schemas.Transaction) any { return tr.CreatedAt })},
+				"reversalOf": {
+					Type:        graphql.ID,
+					Description: "the transaction this one compensates",
+					Resolve:     prop(func(tr schemas.Transaction) any { return nullString(tr.ReversalOf) }),
+				},
+				"item": {
+					Type: itemType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						tr := p.Source.(schemas.Transaction)
+						return loadersFrom(p.Context).items.load(p.Context, tr.ItemUUID), nil
+					},
+				},
+				"ownerUuid": {Type: graphql.NewNonNull(graphql.ID), Resolve: prop(func(tr schemas.Transaction) any { return tr.OwnerUUID })},
+				"owner": {
+					Type:        userType,
+					Description: "who made the transaction, null unless it's the caller or the caller is an admin",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						tr := p.Source.(schemas.Transaction)
+						if !canSeeUser(p.Context, tr.OwnerUUID) {
+							return nil, nil
+						}
+						return loadersFrom(p.Context).users.load(p.Context, tr.OwnerUUID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	itemType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"uuid":       {Type: graphql.NewNonNull(graphql.ID), Resolve: prop(func(item schemas.Item) any { return item.UUID })},
+			"name":       {Type: graphql.NewNonNull(graphql.String), Resolve: prop(func(item schemas.Item) any { return item.Name })},
+			"sku":        {Type: graphql.String, Resolve: prop(func(item schemas.Item) any { return nullString(item.SKU) })},
+			"quantity":   {Type: graphql.NewNonNull(graphql.Int), Resolve: prop(func(item schemas.Item) any { return item.Quantity })},
+			"version":    {Type: graphql.NewNonNull(graphql.Int), Resolve: prop(func(item schemas.Item) any { return item.Version })},
+			"archivedAt": {Type: timestampType, Resolve: prop(func(item schemas.Item) any { return nullTimestamp(item.ArchivedAt) })},
+			"productUuid": {
+				Type:        graphql.ID,
+				Description: "set for variants only",
+				Resolve:     prop(func(item schemas.Item) any { return nullString(item.ProductUUID) }),
+			},
+			"recentTransactions": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+				Description: "newest first",
+				Args: graphql.FieldConfigArgument{
+					"limit": {Type: graphql.Int, DefaultValue: DefaultRecentLimit, Description: "at most " + strconv.Itoa(MaxRecentLimit)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					item := p.Source.(schemas.Item)
+					limit, _ := p.Args["limit"].(int)
+					if limit < 1 || limit > MaxRecentLimit {
+						return nil, echo.NewHTTPError(http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(MaxRecentLimit))
+					}
+					thunk := loadersFrom(p.Context).recentTransactions(limit).load(p.Context, item.UUID)
+					return func() (any, error) {
+						trs, err := thunk()
+						if trs == nil && err == nil {
+							return []schemas.Transaction{}, nil
+						}
+						return trs, err
+					}, nil
+				},
+			},
+		},
+	})
+
+	itemConnection := connectionType("ItemConnection", itemType, func(v any) (any, string, string) {
+		res := v.(schemas.GetItemsResponse)
+		return res.Items, res.NextCursor, res.PrevCursor
+	})
+	transactionConnection := connectionType("TransactionConnection", transactionType, func(v any) (any, string, string) {
+		res := v.(schemas.GetAllTransactionsResponse)
+		return res.Transactions, res.NextCursor, res.PrevCursor
+	})
+
+	itemsArgs := listArgs(itemFilterType)
+	itemsArgs["sort"] = &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: `the sort of GET /items, e.g. "-quantity,name"`,
+	}
+
+	query := graphql.Fields{
+		"items": {
+			Type:    graphql.NewNonNull(itemConnection),
+			Args:    itemsArgs,
+			Resolve: s.resolveItems,
+		},
+		"item": {
+			Type: itemType,
+			Args: graphql.FieldConfigArgument{"uuid": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				uuid, err := uuidArg(p)
+				if err != nil {
+					return nil, err
+				}
+				return loadersFrom(p.Context).items.load(p.Context, uuid.String()), nil
+			},
+		},
+		"transactions": {
+			Type:    graphql.NewNonNull(transactionConnection),
+			Args:    listArgs(transactionFilterType),
+			Resolve: s.resolveTransactions,
+		},
+		"transaction": {
+			Type: transactionType,
+			Args: graphql.FieldConfigArgument{"uuid": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				uuid, err := uuidArg(p)
+				if err != nil {
+					return nil, err
+				}
+				tr, err := s.app.GetTransaction(p.Context, uuid)
+				if errors.Is(err, echo.ErrNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return tr, nil
+			},
+		},
+		"user": {
+			Type:        userType,
+			Description: "the caller, or anyone for admins",
+			Args:        graphql.FieldConfigArgument{"uuid": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				uuid, err := uuidArg(p)
+				if err != nil {
+					return nil, err
+				}
+				if !canSeeUser(p.Context, uuid.String()) {
+					return nil, echo.ErrForbidden
+				}
+				return loadersFrom(p.Context).users.load(p.Context, uuid.String()), nil
+			},
+		},
+	}
+	for name, f := range query {
+		f.Resolve = authorize("Query."+name, f.Resolve)
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: query}),
+	})
+}
+
+// canSeeUser reports whether the caller may see the name and role of the
+// user, only admins see the others'.
+func canSeeUser(ctx context.Context, uuid string) bool {
+	self, _ := ctx.Value(userIDKey{}).(string)
+	return uuid == self || handlers.IsAppropriateRole(ctx.Value(roleKey{}), schemas.RoleAdmin)
+}
+
+// connectionType is a page of a list, page splits the response of the handler
+// into its nodes and cursors.
+func connectionType(name string, node *graphql.Object, page func(any) (any, string, string)) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"nodes": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					nodes, _, _ := page(p.Source)
+					return nodes, nil
+				},
+			},
+			"nextCursor": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					_, next, _ := page(p.Source)
+					return nullString(next), nil
+				},
+			},
+			"prevCursor": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					_, _, prev := page(p.Source)
+					return nullString(prev), nil
+				},
+			},
+		},
+	})
+}
+
+func (s *Server) resolveItems(p graphql.ResolveParams) (any, error) {
+	req := schemas.GetItemsRequest{Cursor: stringArg(p.Args, "cursor"), Sort: stringArg(p.Args, "sort")}
+	req.Limit, _ = p.Args["limit"].(int)
+	if filter, ok := p.Args["filter"].(map[string]any); ok {
+		req.Archived = stringArg(filter, "archived")
+		req.Name = stringArg(filter, "name")
+		req.SKU = stringArg(filter, "sku")
+		req.Query = stringArg(filter, "q")
+		req.MinQuantity = intArg(filter, "minQuantity")
+		req.MaxQuantity = intArg(filter, "maxQuantity")
+		req.CreatedAfter, _ = filter["createdAfter"].(int64)
+		req.CreatedBefore, _ = filter["createdBefore"].(int64)
+		req.UpdatedAfter, _ = filter["updatedAfter"].(int64)
+		req.UpdatedBefore, _ = filter["updatedBefore"].(int64)
+	}
+	if req.Limit < 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "limit must be positive")
+	}
+	if err := s.validator.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	return s.app.GetItems(p.Context, req)
+}
+
+func (s *Server) resolveTransactions(p graphql.ResolveParams) (any, error) {
+	req := schemas.GetAllTransactionsRequest{Cursor: stringArg(p.Args, "cursor")}
+	req.Limit, _ = p.Args["limit"].(int)
+	if filter, ok := p.Args["filter"].(map[string]any); ok {
+		req.Type, _ = filter["type"].(schemas.TransactionType)
+		req.Status, _ = filter["status"].(schemas.TransactionStatus)
+		req.UserUUID = stringArg(filter, "userUuid")
+		req.ItemUUID = stringArg(filter, "itemUuid")
+		req.CreatedAfter, _ = filter["createdAfter"].(int64)
+		req.CreatedBefore, _ = filter["createdBefore"].(int64)
+		req.MinAmount = intArg(filter, "minAmount")
+		req.MaxAmount = intArg(filter, "maxAmount")
+	}
+	if req.Limit < 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "limit must be positive")
+	}
+	if err := s.validator.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	return s.app.GetTransactions(p.Context, req, pgtype.UUID{}, pgtype.UUID{})
+}
+
+// prop resolves a field of the schemas struct the parent resolved to.
+func prop[T any](get func(T) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(T)), nil
+	}
+}
+
+func uuidArg(p graphql.ResolveParams) (pgtype.UUID, error) {
+	uuid, err := handlers.UUIDFromString(stringArg(p.Args, "uuid"))
+	if err != nil {
+		return pgtype.UUID{}, echo.NewHTTPError(http.StatusBadRequest, "uuid is not a UUID")
+	}
+	return uuid, nil
+}
+
+func stringArg(args map[string]any, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func intArg(args map[string]any, name string) *int {
+	if n, ok := args[name].(int); ok {
+		return &n
+	}
+	return nil
+}
+
+// nullString is for the fields the REST API omits when empty.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullTimestamp(ts int64) any {
+	if ts == 0 {
+		return nil
+	}
+	return ts
+}
@@ -0,0 +1,156 @@
+// Package graphqlapi serves POST /graphql for dashboards that need items,
+// transactions and users with their relations in one round-trip. Like
+// grpcapi, the resolvers leave the work to the handlers.App methods.
+package graphqlapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// FieldRoles is the least role a root field needs, the same as the REST
+// route it mirrors. Fields missing here are refused.
+var FieldRoles = map[string]schemas.Role{
+	"Query.items":        schemas.RoleUser,
+	"Query.item":         schemas.RoleUser,
+	"Query.transactions": schemas.RoleUser,
+	"Query.transaction":  schemas.RoleUser,
+	"Query.user":         schemas.RoleUser,
+}
+
+type Server struct {
+	app       handlers.App
+	validator *handlers.Validator
+	schema    graphql.Schema
+}
+
+func New(app handlers.App) *Server {
+	s := &Server{app: app, validator: handlers.NewValidator()}
+	schema, err := s.newSchema()
+	if err != nil {
+		// the schema doesn't depend on anything, it's a bug
+		panic(err)
+	}
+	s.schema = schema
+	return s
+}
+
+// Handle answers POST /graphql. As usual for GraphQL, errors of the query
+// come with 200 in the errors of the body, each with the problem the REST
+// API would have answered with.
+func (s *Server) Handle(c echo.Context) error {
+	if !handlers.IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.GraphQLRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	role, _ := c.Get("userRole").(schemas.Role)
+	userID, _ := c.Get("userID").(string)
+	ctx := context.WithValue(c.Request().Context(), roleKey{}, role)
+	ctx = context.WithValue(ctx, userIDKey{}, userID)
+	return c.JSON(http.StatusOK, s.Execute(ctx, req))
+}
+
+type (
+	roleKey   struct{}
+	userIDKey struct{}
+)
+
+// authorize checks FieldRoles before resolving a root field.
+func authorize(field string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		expected, ok := FieldRoles[field]
+		if !ok || !handlers.IsAppropriateRole(p.Context.Value(roleKey{}), expected) {
+			return nil, echo.ErrForbidden
+		}
+		return resolve(p)
+	}
+}
+
+// Execute runs a query for the caller whose role and uuid are in ctx.
+func (s *Server) Execute(ctx context.Context, req schemas.GraphQLRequest) schemas.GraphQLResponse {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return schemas.GraphQLResponse{Errors: s.errors(gqlerrors.FormatErrors(err))}
+	}
+	if res := graphql.ValidateDocument(&s.schema, doc, nil); !res.IsValid {
+		return schemas.GraphQLResponse{Errors: s.errors(res.Errors)}
+	}
+	if err := checkLimits(&s.schema, doc, req.OperationName, req.Variables); err != nil {
+		return schemas.GraphQLResponse{Errors: s.errors(gqlerrors.FormatErrors(err))}
+	}
+
+	res := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(ctx, s.app),
+	})
+	return schemas.GraphQLResponse{Data: res.Data, Errors: s.errors(res.Errors)}
+}
+
+// errors turns the errors of the resolvers into the problems of the REST
+// API, hiding the internal ones, and those of the query into 400s.
+func (s *Server) errors(errs []gqlerrors.FormattedError) []schemas.GraphQLError {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	res := make([]schemas.GraphQLError, len(errs))
+	for i, e := range errs {
+		orig := originalError(e)
+		if _, ok := orig.(*gqlerrors.Error); ok || orig == nil {
+			orig = echo.NewHTTPError(http.StatusBadRequest, e.Message)
+		}
+
+		p := handlers.ProblemFromError(orig)
+		if p.Status >= http.StatusInternalServerError {
+			s.app.Logger.Error("internal error", zap.Error(orig), zap.Any("path", e.Path))
+		}
+		msg := p.Detail
+		if msg == "" {
+			msg = p.Title
+		}
+		res[i] = schemas.GraphQLError{Message: msg, Path: e.Path, Extensions: &p}
+	}
+	return res
+}
+
+// originalError unwraps what graphql-go wraps the errors of the resolvers in.
+func originalError(err error) error {
+	for {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			if e.OriginalError() == nil {
+				return err
+			}
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			if e.OriginalError == nil {
+				return err
+			}
+			err = e.OriginalError
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package graphqlapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/graphqlapi"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/bigelle/warehouse/internal/testdb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testSecret = []byte("access")
+
+// query posts to /graphql of the real routes as a user with the role.
+func query(t *testing.T, app handlers.App, role, q string, variables map[string]any) (int, schemas.GraphQLResponse) {
+	t.Helper()
+
+	app.Config.JWTAccessSecret = testSecret
+	r := router.New(app, handlers.RateLimiter{}, handlers.RateLimiter{})
+	body, err := json.Marshal(schemas.GraphQLRequest{Query: q, Variables: variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		token, err := handlers.GenerateAccessJWT("0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b", role, testSecret, time.Minute)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var res schemas.GraphQLResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	}
+	return rec.Code, res
+}
+
+func TestFieldsHaveRoles(t *testing.T) {
+	code, res := query(t, handlers.App{Logger: zap.NewNop()}, "user", `{ __schema { queryType { fields { name } } } }`, nil)
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, res.Errors)
+
+	fields := res.Data.(map[string]any)["__schema"].(map[string]any)["queryType"].(map[string]any)["fields"].([]any)
+	require.NotEmpty(t, fields)
+	for _, f := range fields {
+		name := f.(map[string]any)["name"].(string)
+		_, ok := graphqlapi.FieldRoles["Query."+name]
+		require.True(t, ok, "Query.%s has no role", name)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	app := handlers.App{Logger: zap.NewNop()}
+
+	code, _ := query(t, app, "", `{ items { nodes { uuid } } }`, nil)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	_, res := query(t, app, "user", `{ items { nodes { uuid } `, nil)
+	require.Len(t, res.Errors, 1)
+	require.Nil(t, res.Data)
+	require.Equal(t, http.StatusBadRequest, res.Errors[0].Extensions.Status)
+
+	_, res = query(t, app, "user", `{ items { nodes { nope } } }`, nil)
+	require.Len(t, res.Errors, 1)
+	require.Contains(t, res.Errors[0].Message, "nope")
+
+	// checked before anything is loaded
+	_, res = query(t, app, "user", `{ item(uuid: "nope") { name } }`, nil)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, []any{"item"}, res.Errors[0].Path)
+	require.Equal(t, http.StatusBadRequest, res.Errors[0].Extensions.Status)
+	require.Equal(t, map[string]any{"item": nil}, res.Data)
+
+	// only admins look up other users
+	other := `{ user(uuid: "0198f5a8-0000-7000-8000-000000000000") { username } }`
+	for _, role := range []string{"user", "stocker"} {
+		_, res = query(t, app, role, other, nil)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, http.StatusForbidden, res.Errors[0].Extensions.Status)
+		require.Equal(t, map[string]any{"user": nil}, res.Data)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	app := handlers.App{Logger: zap.NewNop()}
+
+	deep := `query($uuid: ID!) {
+		item(uuid: $uuid) { recentTransactions { item { recentTransactions { item { recentTransactions { item { recentTransactions { uuid } } } } } } } }
+	}`
+	_, res := query(t, app, "user", deep, map[string]any{"uuid": "nope"})
+	require.Len(t, res.Errors, 1)
+	require.Contains(t, res.Errors[0].Message, "levels deep")
+	require.Nil(t, res.Data)
+
+	costly := `query($n: Int) { items(limit: 100) { nodes { recentTransactions(limit: $n) { owner { username } item { name } } } } }`
+	_, res = query(t, app, "user", costly, map[string]any{"n": 20})
+	require.Len(t, res.Errors, 1)
+	require.Contains(t, res.Errors[0].Message, "may cost")
+	require.Equal(t, http.StatusBadRequest, res.Errors[0].Extensions.Status)
+
+	// introspection isn't counted
+	_, res = query(t, app, "user", `{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name } } } } } } } }`, nil)
+	require.Empty(t, res.Errors)
+}
+
+// queryCounter counts the queries sent to the database.
+type queryCounter struct {
+	n atomic.Int32
+}
+
+func (c *queryCounter) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	c.n.Add(1)
+	return ctx
+}
+
+func (c *queryCounter) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func TestRelations(t *testing.T) {
+	cfg, err := pgxpool.ParseConfig(testdb.URL(t))
+	require.NoError(t, err)
+	counter := &queryCounter{}
+	cfg.ConnConfig.Tracer = counter
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	app := handlers.App{
+		DB:     handlers.Database{Pool: pool, Queries: database.New(pool)},
+		Logger: zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	usr, err := app.DB.Queries.CreateUser(ctx, database.CreateUserParams{
+		Username:     "graphql-" + suffix,
+		PasswordHash: "-",
+		Role:         "stocker",
+	})
+	require.NoError(t, err)
+	var items []string
+	for i := range 3 {
+		item, err := app.DB.Queries.CreateItem(ctx, database.CreateItemParams{Name: fmt.Sprintf("graphql-%s-%d", suffix, i)})
+		require.NoError(t, err)
+		items = append(items, item.Uuid.String())
+
+		for amount := 1; amount <= 3; amount++ {
+			_, err := app.CreateTransaction(ctx, usr.ID, schemas.CreateTransactionRequest{
+				Type:     schemas.TransactionTypeRestock,
+				ItemUUID: item.Uuid.String(),
+				Amount:   amount,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	recentQuery := `query($name: String) {
+		items(filter: {name: $name}, sort: "name", limit: 10) {
+			nodes {
+				name
+				quantity
+				recentTransactions(limit: 2) { amount ownerUuid owner { username role } item { uuid } }
+			}
+			nextCursor
+		}
+	}`
+	counter.n.Store(0)
+	_, res := query(t, app, "admin", recentQuery, map[string]any{"name": "graphql-" + suffix})
+	require.Empty(t, res.Errors)
+
+	conn := res.Data.(map[string]any)["items"].(map[string]any)
+	require.Nil(t, conn["nextCursor"])
+	nodes := conn["nodes"].([]any)
+	require.Len(t, nodes, 3)
+	for i, n := range nodes {
+		node := n.(map[string]any)
+		require.EqualValues(t, 6, node["quantity"])
+		recent := node["recentTransactions"].([]any)
+		require.Len(t, recent, 2)
+		newest := recent[0].(map[string]any)
+		require.EqualValues(t, 3, newest["amount"])
+		require.Equal(t, usr.ID.String(), newest["ownerUuid"])
+		require.Equal(t, map[string]any{"username": usr.Username, "role": "STOCKER"}, newest["owner"])
+		require.Equal(t, items[i], newest["item"].(map[string]any)["uuid"])
+	}
+	// the items, then one batch each for transactions, owners and items
+	require.EqualValues(t, 4, counter.n.Load())
+
+	// users only see who made the transactions, not their names and roles
+	_, res = query(t, app, "user", recentQuery, map[string]any{"name": "graphql-" + suffix})
+	require.Empty(t, res.Errors)
+	for _, n := range res.Data.(map[string]any)["items"].(map[string]any)["nodes"].([]any) {
+		for _, tr := range n.(map[string]any)["recentTransactions"].([]any) {
+			require.Equal(t, usr.ID.String(), tr.(map[string]any)["ownerUuid"])
+			require.Nil(t, tr.(map[string]any)["owner"])
+		}
+	}
+
+	_, res = query(t, app, "admin", `query($uuid: ID!) { user(uuid: $uuid) { username } }`, map[string]any{"uuid": usr.ID.String()})
+	require.Empty(t, res.Errors)
+	require.Equal(t, map[string]any{"user": map[string]any{"username": usr.Username}}, res.Data)
+}
@@ -0,0 +1,204 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/pb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// MethodRoles is the least role a method needs, the same as its REST
+// route. Methods missing here are refused, except for PublicMethods.
+var MethodRoles = map[string]schemas.Role{
+	pb.ItemService_ListItems_FullMethodName:     schemas.RoleUser,
+	pb.ItemService_GetItem_FullMethodName:       schemas.RoleUser,
+	pb.ItemService_GetItemStock_FullMethodName:  schemas.RoleUser,
+	pb.ItemService_CreateItem_FullMethodName:    schemas.RoleAdmin,
+	pb.ItemService_UpdateItem_FullMethodName:    schemas.RoleAdmin,
+	pb.ItemService_ArchiveItem_FullMethodName:   schemas.RoleAdmin,
+	pb.ItemService_UnarchiveItem_FullMethodName: schemas.RoleAdmin,
+
+	pb.TransactionService_ListTransactions_FullMethodName:   schemas.RoleUser,
+	pb.TransactionService_GetTransaction_FullMethodName:     schemas.RoleUser,
+	pb.TransactionService_StreamTransactions_FullMethodName: schemas.RoleUser,
+	pb.TransactionService_CreateTransaction_FullMethodName:  schemas.RoleStocker,
+	pb.TransactionService_ReverseTransaction_FullMethodName: schemas.RoleAdmin,
+}
+
+// PublicMethods don't need a token.
+var PublicMethods = map[string]bool{
+	pb.AuthService_Register_FullMethodName: true,
+	pb.AuthService_Login_FullMethodName:    true,
+	pb.AuthService_Refresh_FullMethodName:  true,
+}
+
+type userKey struct{}
+
+type user struct {
+	UUID pgtype.UUID
+	Role schemas.Role
+}
+
+func (s *Server) rateLimitUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.rateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) rateLimitStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.rateLimit(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// rateLimit takes a token from the bucket of the client, the buckets are
+// the ones of the REST API so both count against the same limit. The
+// PublicMethods have the stricter ones of /auth.
+func (s *Server) rateLimit(ctx context.Context, method string) error {
+	rl := s.rl
+	if PublicMethods[method] {
+		rl = s.authRL
+	}
+	if !rl.Allow(peerIP(ctx)) {
+		return echo.ErrTooManyRequests
+	}
+	return nil
+}
+
+// peerIP is the host of the client, its whole address if it has no port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func (s *Server) authUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, contextStream{ss, ctx})
+}
+
+// authenticate checks the "authorization: Bearer <token>" metadata, like
+// JWTMiddleware, and the role the method needs.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if PublicMethods[method] || strings.HasPrefix(method, "/grpc.reflection.") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	auth := md.Get("authorization")
+	if len(auth) != 1 {
+		return nil, echo.ErrUnauthorized
+	}
+	token, ok := strings.CutPrefix(auth[0], "Bearer ")
+	if !ok {
+		return nil, echo.ErrUnauthorized
+	}
+	userID, role, err := s.app.ParseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	userUUID, err := handlers.UUIDFromString(userID)
+	if err != nil {
+		return nil, echo.ErrUnauthorized
+	}
+
+	expected, ok := MethodRoles[method]
+	if !ok || !handlers.IsAppropriateRole(role, expected) {
+		return nil, echo.ErrForbidden
+	}
+	return context.WithValue(ctx, userKey{}, user{UUID: userUUID, Role: role}), nil
+}
+
+// userFrom is the caller of an authenticated method.
+func userFrom(ctx context.Context) user {
+	u, _ := ctx.Value(userKey{}).(user)
+	return u
+}
+
+// contextStream passes the authenticated context on to stream handlers.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+type authService struct {
+	pb.UnimplementedAuthServiceServer
+	*Server
+}
+
+func (s authService) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	r := schemas.RegisterRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		Role:     roleFromPB(req.GetRole()),
+	}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.app.Register(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RegisterResponse{
+		Uuid:     res.UUID,
+		Username: res.Username,
+		Role:     roleToPB(res.Role),
+	}, nil
+}
+
+func (s authService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	r := schemas.LoginRequest{Username: req.GetUsername(), Password: req.GetPassword()}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, refresh, err := s.app.Login(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LoginResponse{
+		AccessToken:  res.AccessToken,
+		Expires:      res.Expires,
+		RefreshToken: refresh,
+	}, nil
+}
+
+func (s authService) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.LoginResponse, error) {
+	res, err := s.app.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LoginResponse{AccessToken: res.AccessToken, Expires: res.Expires}, nil
+}
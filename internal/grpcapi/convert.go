@@ -0,0 +1,145 @@
+package grpcapi
+
+import (
+	"github.com/bigelle/warehouse/pkg/pb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+)
+
+func roleToPB(r schemas.Role) pb.Role {
+	switch r {
+	case schemas.RoleUser:
+		return pb.Role_ROLE_USER
+	case schemas.RoleStocker:
+		return pb.Role_ROLE_STOCKER
+	case schemas.RoleAdmin:
+		return pb.Role_ROLE_ADMIN
+	}
+	return pb.Role_ROLE_UNSPECIFIED
+}
+
+// roleFromPB takes ROLE_UNSPECIFIED for a user like a missing role in JSON,
+// unknown roles are left for the validation to refuse.
+func roleFromPB(r pb.Role) schemas.Role {
+	switch r {
+	case pb.Role_ROLE_UNSPECIFIED, pb.Role_ROLE_USER:
+		return schemas.RoleUser
+	case pb.Role_ROLE_STOCKER:
+		return schemas.RoleStocker
+	case pb.Role_ROLE_ADMIN:
+		return schemas.RoleAdmin
+	}
+	return schemas.RoleUndefined
+}
+
+func itemToPB(item schemas.Item) *pb.Item {
+	return &pb.Item{
+		Uuid:        item.UUID,
+		Name:        item.Name,
+		Sku:         item.SKU,
+		Quantity:    int64(item.Quantity),
+		ProductUuid: item.ProductUUID,
+		Options:     item.Options,
+		ArchivedAt:  item.ArchivedAt,
+		Version:     int64(item.Version),
+	}
+}
+
+func itemsRequestFromPB(req *pb.ListItemsRequest) schemas.GetItemsRequest {
+	return schemas.GetItemsRequest{
+		Limit:         int(req.GetLimit()),
+		Offset:        int(req.GetOffset()),
+		Cursor:        req.GetCursor(),
+		Archived:      req.GetArchived(),
+		Name:          req.GetName(),
+		SKU:           req.GetSku(),
+		Query:         req.GetQuery(),
+		MinQuantity:   intPtr(req.MinQuantity),
+		MaxQuantity:   intPtr(req.MaxQuantity),
+		CreatedAfter:  req.GetCreatedAfter(),
+		CreatedBefore: req.GetCreatedBefore(),
+		UpdatedAfter:  req.GetUpdatedAfter(),
+		UpdatedBefore: req.GetUpdatedBefore(),
+		Sort:          req.GetSort(),
+	}
+}
+
+func transactionTypeToPB(t schemas.TransactionType) pb.TransactionType {
+	switch t {
+	case schemas.TransactionTypeRestock:
+		return pb.TransactionType_TRANSACTION_TYPE_RESTOCK
+	case schemas.TransactionTypeWithdraw:
+		return pb.TransactionType_TRANSACTION_TYPE_WITHDRAW
+	}
+	return pb.TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+}
+
+func transactionTypeFromPB(t pb.TransactionType) schemas.TransactionType {
+	switch t {
+	case pb.TransactionType_TRANSACTION_TYPE_RESTOCK:
+		return schemas.TransactionTypeRestock
+	case pb.TransactionType_TRANSACTION_TYPE_WITHDRAW:
+		return schemas.TransactionTypeWithdraw
+	}
+	return ""
+}
+
+func transactionStatusToPB(s schemas.TransactionStatus) pb.TransactionStatus {
+	switch s {
+	case schemas.TransactionStatusSucceeded:
+		return pb.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED
+	case schemas.TransactionStatusFailed:
+		return pb.TransactionStatus_TRANSACTION_STATUS_FAILED
+	case schemas.TransactionStatusReversed:
+		return pb.TransactionStatus_TRANSACTION_STATUS_REVERSED
+	}
+	return pb.TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func transactionStatusFromPB(s pb.TransactionStatus) schemas.TransactionStatus {
+	switch s {
+	case pb.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED:
+		return schemas.TransactionStatusSucceeded
+	case pb.TransactionStatus_TRANSACTION_STATUS_FAILED:
+		return schemas.TransactionStatusFailed
+	case pb.TransactionStatus_TRANSACTION_STATUS_REVERSED:
+		return schemas.TransactionStatusReversed
+	}
+	return ""
+}
+
+func transactionToPB(tr schemas.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		Uuid:       tr.UUID,
+		Type:       transactionTypeToPB(tr.Type),
+		OwnerUuid:  tr.OwnerUUID,
+		ItemUuid:   tr.ItemUUID,
+		Amount:     int64(tr.Amount),
+		Status:     transactionStatusToPB(tr.Status),
+		Reason:     tr.Reason,
+		CreatedAt:  tr.CreatedAt,
+		ReversalOf: tr.ReversalOf,
+	}
+}
+
+// transactionsRequestFromPB fills the filters, the pagination is up to the
+// caller.
+func transactionsRequestFromPB(f *pb.TransactionFilter) schemas.GetAllTransactionsRequest {
+	return schemas.GetAllTransactionsRequest{
+		Type:          transactionTypeFromPB(f.GetType()),
+		Status:        transactionStatusFromPB(f.GetStatus()),
+		UserUUID:      f.GetUserUuid(),
+		ItemUUID:      f.GetItemUuid(),
+		CreatedAfter:  f.GetCreatedAfter(),
+		CreatedBefore: f.GetCreatedBefore(),
+		MinAmount:     intPtr(f.MinAmount),
+		MaxAmount:     intPtr(f.MaxAmount),
+	}
+}
+
+func intPtr(v *int64) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
@@ -0,0 +1,108 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) errorsUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	res, err := handler(ctx, req)
+	if err != nil {
+		return nil, s.status(info.FullMethod, err)
+	}
+	return res, nil
+}
+
+func (s *Server) errorsStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		return s.status(info.FullMethod, err)
+	}
+	return nil
+}
+
+// status turns the errors of the App methods into statuses the way
+// HTTPErrorHandler turns them into problems, with the same details.
+func (s *Server) status(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		// the client is gone
+		return status.FromContextError(err).Err()
+	}
+	if errors.Is(err, handlers.ErrEventsTooSlow) {
+		// a follow stream that fell behind, it may start over from the
+		// last transaction it got
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	p := handlers.ProblemFromError(err)
+	if p.Status >= http.StatusInternalServerError && p.Type != schemas.ProblemTypeTimeout {
+		s.app.Logger.Error("grpc", zap.String("method", method), zap.Error(err))
+	}
+
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	st := status.New(codeFromProblem(p), msg)
+	if len(p.Errors) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, fe := range p.Errors {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+				Reason:      fe.Rule,
+			})
+		}
+		if withDetails, err := st.WithDetails(br); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+func codeFromProblem(p schemas.Problem) codes.Code {
+	switch p.Status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		if p.Type == schemas.ProblemTypeConflict {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case http.StatusPreconditionFailed:
+		// the item was changed by someone else, fetch and try again
+		return codes.Aborted
+	case http.StatusPreconditionRequired, http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		if p.Type == schemas.ProblemTypeTimeout {
+			return codes.DeadlineExceeded
+		}
+		return codes.Unavailable
+	}
+	if p.Status >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,162 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/pb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+type itemService struct {
+	pb.UnimplementedItemServiceServer
+	*Server
+}
+
+func (s itemService) ListItems(ctx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	r := itemsRequestFromPB(req)
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.app.GetItems(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	out := &pb.ListItemsResponse{
+		Items:      make([]*pb.Item, len(res.Items)),
+		NextCursor: res.NextCursor,
+		PrevCursor: res.PrevCursor,
+	}
+	for i, item := range res.Items {
+		out.Items[i] = itemToPB(item)
+	}
+	for _, p := range res.Products {
+		out.Products = append(out.Products, &pb.ProductStock{
+			Uuid:      p.UUID,
+			Name:      p.Name,
+			NVariants: int64(p.NVariants),
+			Quantity:  int64(p.Quantity),
+		})
+	}
+	return out, nil
+}
+
+func (s itemService) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.Item, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	item, err := s.app.GetItem(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return itemToPB(item), nil
+}
+
+func (s itemService) CreateItem(ctx context.Context, req *pb.CreateItemRequest) (*pb.CreateItemResponse, error) {
+	r := schemas.CreateItemRequest{Name: req.GetName(), SKU: req.GetSku()}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.app.CreateItem(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateItemResponse{
+		Uuid:      res.UUID,
+		Name:      res.Name,
+		Sku:       res.SKU,
+		CreatedAt: res.CreatedAt,
+	}, nil
+}
+
+func (s itemService) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Item, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+	versions, err := itemVersions(req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		return nil, echo.NewHTTPError(http.StatusPreconditionRequired, "version is required")
+	}
+
+	r := schemas.PatchRequest{Name: req.Name, SKU: req.Sku, Quantity: req.Quantity}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	item, err := s.app.PatchItem(ctx, userFrom(ctx).UUID, uuid, versions, r)
+	if err != nil {
+		return nil, err
+	}
+	return itemToPB(item), nil
+}
+
+func (s itemService) ArchiveItem(ctx context.Context, req *pb.ArchiveItemRequest) (*pb.Item, error) {
+	return s.setArchived(ctx, req, s.app.ArchiveItem)
+}
+
+func (s itemService) UnarchiveItem(ctx context.Context, req *pb.ArchiveItemRequest) (*pb.Item, error) {
+	return s.setArchived(ctx, req, s.app.UnarchiveItem)
+}
+
+func (s itemService) setArchived(ctx context.Context, req *pb.ArchiveItemRequest, set func(context.Context, pgtype.UUID, []int32) (schemas.Item, error)) (*pb.Item, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+	versions, err := itemVersions(req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := set(ctx, uuid, versions)
+	if err != nil {
+		return nil, err
+	}
+	return itemToPB(item), nil
+}
+
+func (s itemService) GetItemStock(ctx context.Context, req *pb.GetItemStockRequest) (*pb.ItemStock, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+	r := schemas.GetItemStockRequest{AsOf: req.GetAsOf()}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	stock, err := s.app.GetItemStock(ctx, uuid, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ItemStock{
+		ItemUuid:       stock.ItemUUID,
+		AsOf:           stock.AsOf,
+		Quantity:       int64(stock.Quantity),
+		NMovements:     int64(stock.NMovements),
+		LastMovementAt: stock.LastMovementAt,
+	}, nil
+}
+
+// itemVersions is the If-Match of the REST API, nil for any version.
+func itemVersions(version int64) ([]int32, error) {
+	switch {
+	case version == 0:
+		return nil, nil
+	case version < 0 || version > math.MaxInt32:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "version is out of range")
+	}
+	return []int32{int32(version)}, nil
+}
@@ -0,0 +1,34 @@
+// Package grpcapi serves the protos of proto/warehouse/v1 next to the REST
+// API. The services only convert messages, the work is done by the same
+// handlers.App methods the echo handlers call.
+package grpcapi
+
+import (
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type Server struct {
+	app        handlers.App
+	validator  *handlers.Validator
+	authRL, rl handlers.RateLimiter
+}
+
+// New registers the services, with reflection for tools like grpcurl. The
+// rate limiters are the ones of router.New.
+func New(app handlers.App, authRL, RL handlers.RateLimiter, opts ...grpc.ServerOption) *grpc.Server {
+	s := &Server{app: app, validator: handlers.NewValidator(), authRL: authRL, rl: RL}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.errorsUnary, s.rateLimitUnary, s.authUnary),
+		grpc.ChainStreamInterceptor(s.errorsStream, s.rateLimitStream, s.authStream),
+	)
+	srv := grpc.NewServer(opts...)
+	pb.RegisterAuthServiceServer(srv, authService{Server: s})
+	pb.RegisterItemServiceServer(srv, itemService{Server: s})
+	pb.RegisterTransactionServiceServer(srv, transactionService{Server: s})
+	reflection.Register(srv)
+	return srv
+}
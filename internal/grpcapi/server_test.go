@@ -0,0 +1,222 @@
+package grpcapi_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigelle/ratebucket"
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/grpcapi"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/testdb"
+	"github.com/bigelle/warehouse/pkg/pb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var testSecret = []byte("access")
+
+// dial serves app over an in-memory listener.
+func dial(t *testing.T, app handlers.App) *grpc.ClientConn {
+	t.Helper()
+	return dialLimited(t, app, handlers.RateLimiter{}, handlers.RateLimiter{})
+}
+
+// dialLimited is dial with the rate limiters of grpcapi.New.
+func dialLimited(t *testing.T, app handlers.App, authRL, RL handlers.RateLimiter) *grpc.ClientConn {
+	t.Helper()
+
+	app.Config.JWTAccessSecret = testSecret
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcapi.New(app, authRL, RL)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(t *testing.T, userID, role string) context.Context {
+	t.Helper()
+	token, err := handlers.GenerateAccessJWT(userID, role, testSecret, time.Minute)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestMethodsHaveRoles(t *testing.T) {
+	srv := grpcapi.New(handlers.App{Logger: zap.NewNop()}, handlers.RateLimiter{}, handlers.RateLimiter{})
+	for name, info := range srv.GetServiceInfo() {
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+		for _, m := range info.Methods {
+			method := "/" + name + "/" + m.Name
+			_, ok := grpcapi.MethodRoles[method]
+			require.True(t, ok || grpcapi.PublicMethods[method], "%s has no role", method)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	conn := dial(t, handlers.App{Logger: zap.NewNop()})
+	items := pb.NewItemServiceClient(conn)
+	userID := "0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b"
+
+	_, err := items.ListItems(context.Background(), &pb.ListItemsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope")
+	_, err = items.ListItems(bad, &pb.ListItemsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = items.CreateItem(withToken(t, userID, "user"), &pb.CreateItemRequest{Name: "drill"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// validated before the database is touched
+	_, err = items.CreateItem(withToken(t, userID, "admin"), &pb.CreateItemRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	var fields []string
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	require.Equal(t, []string{"name"}, fields)
+
+	_, err = pb.NewAuthServiceClient(conn).Login(context.Background(), &pb.LoginRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRateLimit(t *testing.T) {
+	authRL := handlers.RateLimiter{Pool: ratebucket.NewPoolConfig(ratebucket.PoolConfig{
+		InitialTokens: 2,
+		Capacity:      2,
+		RefillRate:    1.0 / 3600,
+	})}
+	RL := handlers.RateLimiter{Pool: ratebucket.NewPoolConfig(ratebucket.PoolConfig{
+		InitialTokens: 100,
+		Capacity:      100,
+		RefillRate:    1,
+	})}
+	conn := dialLimited(t, handlers.App{Logger: zap.NewNop()}, authRL, RL)
+	auth := pb.NewAuthServiceClient(conn)
+
+	for range 2 {
+		_, err := auth.Login(context.Background(), &pb.LoginRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	_, err := auth.Login(context.Background(), &pb.LoginRequest{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = auth.Register(context.Background(), &pb.RegisterRequest{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the other methods have buckets of their own
+	_, err = pb.NewItemServiceClient(conn).ListItems(context.Background(), &pb.ListItemsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestReflection(t *testing.T) {
+	conn := dial(t, handlers.App{Logger: zap.NewNop()})
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+
+	var names []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		names = append(names, s.GetName())
+	}
+	require.Contains(t, names, "warehouse.v1.ItemService")
+	require.Contains(t, names, "warehouse.v1.TransactionService")
+	require.Contains(t, names, "warehouse.v1.AuthService")
+}
+
+func TestTransactions(t *testing.T) {
+	pool := testdb.Pool(t)
+	app := handlers.App{
+		DB:     handlers.Database{Pool: pool, Queries: database.New(pool)},
+		Logger: zap.NewNop(),
+		Events: handlers.NewEventHub(pool, zap.NewNop()),
+	}
+	ctx := context.Background()
+	listenCtx, stopListening := context.WithCancel(ctx)
+	t.Cleanup(stopListening)
+	go app.Events.Listen(listenCtx)
+	// follow gets the new transactions from the hub
+	require.Eventually(t, func() bool {
+		var n int
+		err := pool.QueryRow(ctx, "SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN events'").Scan(&n)
+		return err == nil && n > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	usr, err := app.DB.Queries.CreateUser(ctx, database.CreateUserParams{
+		Username:     "grpc-" + suffix,
+		PasswordHash: "-",
+		Role:         "stocker",
+	})
+	require.NoError(t, err)
+	item, err := app.DB.Queries.CreateItem(ctx, database.CreateItemParams{Name: "grpc-" + suffix})
+	require.NoError(t, err)
+
+	conn := dial(t, app)
+	transactions := pb.NewTransactionServiceClient(conn)
+	authCtx := withToken(t, usr.ID.String(), "stocker")
+
+	_, err = transactions.CreateTransaction(authCtx, &pb.CreateTransactionRequest{
+		Type:     pb.TransactionType_TRANSACTION_TYPE_WITHDRAW,
+		ItemUuid: item.Uuid.String(),
+		Amount:   1,
+	})
+	st := status.Convert(err)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 1)
+	failed := st.Details()[0].(*pb.Transaction)
+	require.Equal(t, pb.TransactionStatus_TRANSACTION_STATUS_FAILED, failed.GetStatus())
+
+	streamCtx, cancel := context.WithTimeout(authCtx, 10*time.Second)
+	defer cancel()
+	stream, err := transactions.StreamTransactions(streamCtx, &pb.StreamTransactionsRequest{
+		Filter: &pb.TransactionFilter{ItemUuid: item.Uuid.String()},
+		Follow: true,
+	})
+	require.NoError(t, err)
+	tr, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, failed.GetUuid(), tr.GetUuid())
+
+	restock, err := transactions.CreateTransaction(authCtx, &pb.CreateTransactionRequest{
+		Type:     pb.TransactionType_TRANSACTION_TYPE_RESTOCK,
+		ItemUuid: item.Uuid.String(),
+		Amount:   3,
+	})
+	require.NoError(t, err)
+	tr, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, restock.GetUuid(), tr.GetUuid())
+	require.EqualValues(t, 3, tr.GetAmount())
+}
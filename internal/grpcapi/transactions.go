@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/pb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type transactionService struct {
+	pb.UnimplementedTransactionServiceServer
+	*Server
+}
+
+func (s transactionService) ListTransactions(ctx context.Context, req *pb.ListTransactionsRequest) (*pb.ListTransactionsResponse, error) {
+	r := transactionsRequestFromPB(req.GetFilter())
+	r.Limit, r.Offset, r.Cursor = int(req.GetLimit()), int(req.GetOffset()), req.GetCursor()
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.app.GetTransactions(ctx, r, pgtype.UUID{}, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+	out := &pb.ListTransactionsResponse{
+		Transactions: make([]*pb.Transaction, len(res.Transactions)),
+		NextCursor:   res.NextCursor,
+		PrevCursor:   res.PrevCursor,
+	}
+	for i, tr := range res.Transactions {
+		out.Transactions[i] = transactionToPB(tr)
+	}
+	return out, nil
+}
+
+func (s transactionService) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	tr, err := s.app.GetTransaction(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return transactionToPB(tr), nil
+}
+
+func (s transactionService) CreateTransaction(ctx context.Context, req *pb.CreateTransactionRequest) (*pb.Transaction, error) {
+	if req.GetAmount() > math.MaxInt32 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "amount is out of range")
+	}
+	r := schemas.CreateTransactionRequest{
+		Type:     transactionTypeFromPB(req.GetType()),
+		ItemUUID: req.GetItemUuid(),
+		Barcode:  req.GetBarcode(),
+		Amount:   int(req.GetAmount()),
+	}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	tr, err := s.app.CreateTransaction(ctx, userFrom(ctx).UUID, r)
+	if errors.Is(err, handlers.ErrNotEnoughItems) {
+		// the REST API answers with the recorded attempt as well
+		st, dErr := status.New(codes.FailedPrecondition, handlers.NotEnoughItemsMessage).WithDetails(transactionToPB(tr))
+		if dErr != nil {
+			return nil, dErr
+		}
+		return nil, st.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transactionToPB(tr), nil
+}
+
+func (s transactionService) ReverseTransaction(ctx context.Context, req *pb.ReverseTransactionRequest) (*pb.ReverseTransactionResponse, error) {
+	uuid, err := handlers.UUIDFromString(req.GetUuid())
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+	r := schemas.ReverseTransactionRequest{Reason: req.GetReason()}
+	if err := s.validator.Validate(&r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.app.ReverseTransaction(ctx, userFrom(ctx).UUID, uuid, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ReverseTransactionResponse{
+		Original: transactionToPB(res.Original),
+		Reversal: transactionToPB(res.Reversal),
+	}, nil
+}
+
+func (s transactionService) StreamTransactions(req *pb.StreamTransactionsRequest, stream grpc.ServerStreamingServer[pb.Transaction]) error {
+	r := transactionsRequestFromPB(req.GetFilter())
+	if err := s.validator.Validate(&r); err != nil {
+		return err
+	}
+
+	return s.app.StreamTransactions(stream.Context(), r, req.GetFollow(), func(tr schemas.Transaction) error {
+		return stream.Send(transactionToPB(tr))
+	})
+}
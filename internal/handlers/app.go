@@ -8,25 +8,38 @@ import (
 
 	"github.com/bigelle/ratebucket"
 	"github.com/bigelle/warehouse/internal/database"
-	"github.com/jackc/pgx/v5"
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
 type Database struct {
 	Queries *database.Queries
-	Conn    *pgx.Conn
+	Pool    *pgxpool.Pool
 }
 
 type Config struct {
 	JWTAccessSecret  []byte
 	JWTRefreshSecret []byte
+	// in bytes, DefaultAttachmentMaxSize if zero
+	AttachmentMaxSize int64
+	// how long responses are kept for replays, DefaultIdempotencyKeyTTL if zero
+	IdempotencyKeyTTL time.Duration
+	// how long events are kept for resuming, DefaultEventsRetention if zero
+	EventsRetention time.Duration
+	// lets the webhooks reach the loopback and private addresses, for
+	// receivers on the same network
+	WebhookAllowPrivate bool
 }
 
 type App struct {
-	DB     Database
-	Logger *zap.Logger
-	Config Config
+	DB      Database
+	Logger  *zap.Logger
+	Config  Config
+	Storage storage.Storage
+	// nil if the event streams are off
+	Events *EventHub
 }
 
 func (app App) LoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
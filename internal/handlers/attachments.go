@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	DefaultAttachmentMaxSize = 10 << 20
+	// images bigger than that are stored, but not thumbnailed, decoding takes
+	// 4 bytes per pixel
+	ThumbnailMaxPixels = 16_000_000
+	TimeoutStorage     = 5 * time.Second
+)
+
+var ErrThumbnailTooBig = errors.New("image is too big to thumbnail")
+
+// content types as reported by http.DetectContentType
+var allowedAttachmentTypes = map[string]bool{
+	"image/jpeg":                true,
+	"image/png":                 true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+func (app App) HandleUploadItemAttachment(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleStocker) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return app.uploadAttachment(c, itemUUID, pgtype.UUID{})
+}
+
+func (app App) HandleUploadTransactionAttachment(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleStocker) {
+		return echo.ErrForbidden
+	}
+
+	trUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return app.uploadAttachment(c, pgtype.UUID{}, trUUID)
+}
+
+func (app App) HandleGetItemAttachments(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.GetItemAttachments(ctx, itemUUID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, attachmentsResponse(found))
+}
+
+func (app App) HandleGetTransactionAttachments(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	trUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.GetTransactionAttachments(ctx, trUUID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, attachmentsResponse(found))
+}
+
+func (app App) HandleDownloadAttachment(c echo.Context) error {
+	return app.downloadAttachment(c, false)
+}
+
+func (app App) HandleDownloadAttachmentThumbnail(c echo.Context) error {
+	return app.downloadAttachment(c, true)
+}
+
+func (app App) HandleDeleteAttachment(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	deleted, err := app.DB.Queries.DeleteAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	// the row is gone already, leftovers are only a matter of disk space
+	app.removeStored(c.Request().Context(), deleted.StorageKey, StringFromPtr(deleted.ThumbnailKey))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (app App) uploadAttachment(c echo.Context, itemUUID, trUUID pgtype.UUID) error {
+	uuidStr, ok := c.Get("userID").(string)
+	if uuidStr == "" || !ok {
+		return echo.ErrForbidden
+	}
+	userUUID, err := UUIDFromString(uuidStr)
+	if err != nil {
+		return echo.ErrForbidden
+	}
+
+	maxSize := app.Config.AttachmentMaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultAttachmentMaxSize
+	}
+	// leaving some room for the multipart boundaries and headers
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxSize+64<<10)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			return echo.ErrStatusRequestEntityTooLarge
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "expected a multipart form with a file field")
+	}
+	if fh.Size > maxSize {
+		return echo.ErrStatusRequestEntityTooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		return echo.ErrStatusRequestEntityTooLarge
+	}
+	if len(data) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "empty file")
+	}
+
+	// not trusting the client's content type
+	contentType := http.DetectContentType(data)
+	if !allowedAttachmentTypes[contentType] {
+		return echo.ErrUnsupportedMediaType
+	}
+
+	storeCtx, cancelStore := context.WithTimeout(c.Request().Context(), TimeoutStorage)
+	defer cancelStore()
+
+	key := "attachments/" + uuid.NewString()
+	if err := app.Storage.Put(storeCtx, key, bytes.NewReader(data)); err != nil {
+		app.Logger.Error("storing attachment", zap.Error(err))
+		return err
+	}
+
+	var thumbKey *string
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, err := makeThumbnail(data, schemas.AttachmentThumbnailSize)
+		if err != nil {
+			// the original is still useful without a preview
+			app.Logger.Warn("generating thumbnail", zap.Error(err))
+		} else {
+			k := key + ".thumb.png"
+			if err := app.Storage.Put(storeCtx, k, bytes.NewReader(thumb)); err != nil {
+				app.Logger.Error("storing thumbnail", zap.Error(err))
+			} else {
+				thumbKey = &k
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	att, err := app.DB.Queries.CreateAttachment(ctx, database.CreateAttachmentParams{
+		ItemID:        itemUUID,
+		TransactionID: trUUID,
+		UserID:        userUUID,
+		Filename:      attachmentFilename(fh.Filename),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		StorageKey:    key,
+		ThumbnailKey:  thumbKey,
+	})
+	if err != nil {
+		app.removeStored(c.Request().Context(), key, StringFromPtr(thumbKey))
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, attachmentFromRow(att))
+}
+
+func (app App) downloadAttachment(c echo.Context, thumbnail bool) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	att, err := app.DB.Queries.GetAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	key, contentType, filename := att.StorageKey, att.ContentType, att.Filename
+	if thumbnail {
+		if att.ThumbnailKey == nil {
+			return echo.ErrNotFound
+		}
+		key, contentType = *att.ThumbnailKey, "image/png"
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".thumb.png"
+	}
+
+	r, err := app.Storage.Get(c.Request().Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			app.Logger.Error("attachment is missing from storage", zap.String("key", key))
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	defer r.Close()
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentDisposition, mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	h.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, contentType, r)
+}
+
+func (app App) removeStored(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		if k == "" {
+			continue
+		}
+		if err := app.Storage.Delete(ctx, k); err != nil {
+			app.Logger.Error("removing stored file", zap.String("key", k), zap.Error(err))
+		}
+	}
+}
+
+// makeThumbnail scales the image down so that its longest side is size,
+// smaller images are only re-encoded.
+func makeThumbnail(data []byte, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	// checked before decoding, the header alone can claim any size
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > ThumbnailMaxPixels {
+		return nil, ErrThumbnailTooBig
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	return name
+}
+
+func attachmentFromRow(a database.Attachment) schemas.Attachment {
+	return schemas.Attachment{
+		UUID:            a.ID.String(),
+		ItemUUID:        a.ItemID.String(),
+		TransactionUUID: a.TransactionID.String(),
+		OwnerUUID:       a.UserID.String(),
+		Filename:        a.Filename,
+		ContentType:     a.ContentType,
+		Size:            a.Size,
+		HasThumbnail:    a.ThumbnailKey != nil,
+		CreatedAt:       a.CreatedAt.Time.Unix(),
+	}
+}
+
+func attachmentsResponse(found []database.Attachment) schemas.GetAttachmentsResponse {
+	atts := make([]schemas.Attachment, len(found))
+	for i := range found {
+		atts[i] = attachmentFromRow(found[i])
+	}
+	return schemas.GetAttachmentsResponse{
+		NResults:    len(atts),
+		Attachments: atts,
+	}
+}
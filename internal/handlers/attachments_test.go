@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := range w {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// claimSize rewrites the dimensions in the header of a PNG, the pixels are
+// left as they are.
+func claimSize(data []byte, w, h uint32) []byte {
+	data = bytes.Clone(data)
+	// signature, chunk length and type, then the IHDR data
+	ihdr := data[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:4], w)
+	binary.BigEndian.PutUint32(ihdr[4:8], h)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestAttachments(t *testing.T) {
+	app := testApp(t)
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	app.Storage = store
+	app.Config.AttachmentMaxSize = 64 << 10
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	item := newTestItem(t, app, "photographed")
+	e := newTestEcho(app, usr)
+	e.POST("/items/:uuid/attachments", app.HandleUploadItemAttachment)
+	e.GET("/attachments/:uuid", app.HandleDownloadAttachment)
+	e.GET("/attachments/:uuid/thumbnail", app.HandleDownloadAttachmentThumbnail)
+	upload := func(filename string, data []byte) (*httptest.ResponseRecorder, schemas.Attachment) {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		fw.Write(data)
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/items/"+item.Uuid.String()+"/attachments", &body)
+		req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var att schemas.Attachment
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &att))
+		}
+		return rec, att
+	}
+
+	photo := encodePNG(t, 1024, 512)
+	rec, att := upload(`..\..\shelf.png`, photo)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "shelf.png", att.Filename)
+	require.Equal(t, "image/png", att.ContentType)
+	require.EqualValues(t, len(photo), att.Size)
+	require.True(t, att.HasThumbnail)
+
+	rec = serve(e, http.MethodGet, "/attachments/"+att.UUID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, photo, rec.Body.Bytes())
+	rec = serve(e, http.MethodGet, "/attachments/"+att.UUID+"/thumbnail", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	cfg, err := png.DecodeConfig(rec.Body)
+	require.NoError(t, err)
+	require.Equal(t, schemas.AttachmentThumbnailSize, cfg.Width)
+	require.Equal(t, schemas.AttachmentThumbnailSize/2, cfg.Height)
+
+	// sniffed, whatever the name says
+	rec, att = upload("invoice.png", []byte("delivered 40 boxes"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/plain; charset=utf-8", att.ContentType)
+	require.False(t, att.HasThumbnail)
+	rec, _ = upload("setup.png", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00"))
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	// a header claiming a huge image is stored without a thumbnail
+	rec, att = upload("bomb.png", claimSize(encodePNG(t, 1, 1), 1<<16, 1<<16))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.False(t, att.HasThumbnail)
+
+	rec, _ = upload("huge.png", bytes.Repeat([]byte("a"), int(app.Config.AttachmentMaxSize)+1))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
@@ -8,16 +8,18 @@ import (
 	"time"
 
 	"github.com/bigelle/warehouse/internal/database"
-	"github.com/bigelle/warehouse/internal/schemas"
+	"github.com/bigelle/warehouse/pkg/schemas"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
 const (
 	TimeoutDatabase = 500 * time.Millisecond
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
 )
 
 func (app App) HandleRegister(c echo.Context) error {
@@ -25,16 +27,26 @@ func (app App) HandleRegister(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
-	// TODO: validating things
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	res, err := app.Register(c.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(200, res)
+}
 
+func (app App) Register(ctx context.Context, req schemas.RegisterRequest) (schemas.RegisterResponse, error) {
 	// hashing
 	hash, err := HashPassword(req.Password)
 	if err != nil {
-		return err
+		return schemas.RegisterResponse{}, err
 	}
 
 	// creating user
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
 	usr, err := app.DB.Queries.CreateUser(ctx,
 		database.CreateUserParams{
@@ -44,27 +56,15 @@ func (app App) HandleRegister(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return echo.ErrConflict
-			case "23514":
-				return echo.NewHTTPError(http.StatusBadRequest, "invalid role type")
-			case "23502":
-				return echo.NewHTTPError(http.StatusBadRequest, "null value")
-			default:
-				return err // 500 for now, maybe I'll leave it like that
-			}
-		}
-		return err
+		// a taken username is answered as a conflict by the error handler
+		return schemas.RegisterResponse{}, err
 	}
 
-	return c.JSON(200, schemas.RegisterResponse{
+	return schemas.RegisterResponse{
 		Username: usr.Username,
 		UUID:     usr.ID.String(),
 		Role:     schemas.RoleFromString(usr.Role),
-	})
+	}, nil
 }
 
 func (app App) HandleLogin(c echo.Context) error {
@@ -72,37 +72,59 @@ func (app App) HandleLogin(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "bad request")
 	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
 
-	// TODO: validating things
+	res, refresh, err := app.Login(c.Request().Context(), req)
+	if err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	c.SetCookie(&http.Cookie{
+		Name:     "refresh",
+		Value:    refresh,
+		Path:     "/auth/refresh",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(RefreshTokenTTL),
+	})
+
+	return c.JSON(200, res)
+}
+
+// Login checks the password and returns the access token along with the
+// refresh one, which the REST API keeps in a cookie.
+func (app App) Login(ctx context.Context, req schemas.LoginRequest) (schemas.LoginResponse, string, error) {
+	dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
-	usr, err := app.DB.Queries.GetUserByUsername(ctx, req.Username)
+	usr, err := app.DB.Queries.GetUserByUsername(dbCtx, req.Username)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.NewHTTPError(http.StatusUnauthorized, "wrong username or password")
+			return schemas.LoginResponse{}, "", echo.NewHTTPError(http.StatusUnauthorized, "wrong username or password")
 		}
-		return err
+		return schemas.LoginResponse{}, "", err
 	}
 
 	if !IsCorrectPassword(req.Password, usr.PasswordHash) {
-		return echo.NewHTTPError(http.StatusUnauthorized, "wrong username or password")
+		return schemas.LoginResponse{}, "", echo.NewHTTPError(http.StatusUnauthorized, "wrong username or password")
 	}
 
-	access, err := GenerateAccessJWT(usr.ID.String(), usr.Role, app.Config.JWTAccessSecret, 15*time.Minute)
+	access, err := GenerateAccessJWT(usr.ID.String(), usr.Role, app.Config.JWTAccessSecret, AccessTokenTTL)
 	if err != nil {
-		return err // nothing i can do
+		return schemas.LoginResponse{}, "", err // nothing i can do
 	}
-	refresh, err := GenerateRefreshJWT(usr.ID.String(), app.Config.JWTRefreshSecret, 7*24*time.Hour)
+	refresh, err := GenerateRefreshJWT(usr.ID.String(), app.Config.JWTRefreshSecret, RefreshTokenTTL)
 	if err != nil {
-		return err
+		return schemas.LoginResponse{}, "", err
 	}
 
 	// NOTE: maybe i can do it in parallel
 	cancel()
-	ctx, cancel = context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	dbCtx, cancel = context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
-	_, err = app.DB.Queries.SetRefreshToken(ctx, database.SetRefreshTokenParams{
+	_, err = app.DB.Queries.SetRefreshToken(dbCtx, database.SetRefreshTokenParams{
 		RefreshToken: refresh,
 		ID:           usr.ID,
 	})
@@ -116,19 +138,10 @@ func (app App) HandleLogin(c echo.Context) error {
 		}
 	}
 
-	c.SetCookie(&http.Cookie{
-		Name:     "refresh",
-		Value:    refresh,
-		Path:     "/refresh",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
-
-	return c.JSON(200, schemas.LoginResponse{
+	return schemas.LoginResponse{
 		AccessToken: access,
-	})
+		Expires:     time.Now().Add(AccessTokenTTL).Unix(),
+	}, refresh, nil
 }
 
 func (app App) HandleRefresh(c echo.Context) error {
@@ -137,46 +150,77 @@ func (app App) HandleRefresh(c echo.Context) error {
 		return echo.ErrUnauthorized
 	}
 
+	res, err := app.Refresh(c.Request().Context(), refresh.Value)
+	if err != nil {
+		return err
+	}
+	return c.JSON(200, res)
+}
+
+// GetUsers looks users up by UUID, the ones that don't exist are left out.
+func (app App) GetUsers(ctx context.Context, uuids []pgtype.UUID) ([]schemas.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	rows, err := app.DB.Queries.GetUsers(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]schemas.User, len(rows))
+	for i, row := range rows {
+		users[i] = schemas.User{
+			UUID:      row.ID.String(),
+			Username:  row.Username,
+			Role:      schemas.RoleFromString(row.Role),
+			CreatedAt: row.CreatedAt.Time.Unix(),
+		}
+	}
+	return users, nil
+}
+
+// Refresh issues a new access token for a refresh token of Login.
+func (app App) Refresh(ctx context.Context, refresh string) (schemas.LoginResponse, error) {
 	// Validating refresher:
-	token, err := jwt.Parse(refresh.Value, func(t *jwt.Token) (any, error) {
+	token, err := jwt.Parse(refresh, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, echo.ErrUnauthorized
 		}
 		return app.Config.JWTRefreshSecret, nil
 	})
 	if err != nil || !token.Valid {
-		return echo.ErrUnauthorized
+		return schemas.LoginResponse{}, echo.ErrUnauthorized
 	}
 	refreshClaims := token.Claims.(jwt.MapClaims)
 
 	// Getting uuid:
 	subj, err := refreshClaims.GetSubject()
 	if err != nil {
-		return err
+		return schemas.LoginResponse{}, err
 	}
 	uuid, err := UUIDFromString(subj)
 	if err != nil {
-		return err
+		return schemas.LoginResponse{}, err
 	}
 
 	// Generating access:
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
 	usrRole, err := app.DB.Queries.GetUserRole(ctx, uuid)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrUnauthorized
+			return schemas.LoginResponse{}, echo.ErrUnauthorized
 		}
-		return err
+		return schemas.LoginResponse{}, err
 	}
-	access, err := GenerateAccessJWT(usrRole.ID.String(), usrRole.Role, app.Config.JWTAccessSecret, 15*time.Minute)
+	access, err := GenerateAccessJWT(usrRole.ID.String(), usrRole.Role, app.Config.JWTAccessSecret, AccessTokenTTL)
 	if err != nil {
-		return err
+		return schemas.LoginResponse{}, err
 	}
 
-	return c.JSON(200, schemas.LoginResponse{
+	return schemas.LoginResponse{
 		AccessToken: access,
-	})
+		Expires:     time.Now().Add(AccessTokenTTL).Unix(),
+	}, nil
 }
 
 func (app App) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -192,25 +236,61 @@ func (app App) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return echo.ErrUnauthorized
 		}
 
-		// parsing
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (any, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, echo.ErrUnauthorized
-			}
-			return app.Config.JWTAccessSecret, nil
-		})
-		if err != nil || !token.Valid {
-			return echo.ErrUnauthorized
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return echo.ErrUnauthorized
+		userID, role, err := app.ParseAccessToken(parts[1])
+		if err != nil {
+			return err
 		}
 
 		// setting
-		c.Set("userID", claims["sub"])
-		c.Set("userRole", schemas.RoleFromString(claims["role"].(string)))
+		c.Set("userID", userID)
+		c.Set("userRole", role)
 
 		return next(c)
 	}
 }
+
+// ParseAccessToken returns the user and role of a valid access token,
+// echo.ErrUnauthorized otherwise.
+func (app App) ParseAccessToken(access string) (string, schemas.Role, error) {
+	token, err := jwt.Parse(access, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, echo.ErrUnauthorized
+		}
+		return app.Config.JWTAccessSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return "", schemas.RoleUndefined, echo.ErrUnauthorized
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", schemas.RoleUndefined, echo.ErrUnauthorized
+	}
+	// tickets of the event streams aren't access tokens
+	if aud, err := claims.GetAudience(); err != nil || len(aud) > 0 {
+		return "", schemas.RoleUndefined, echo.ErrUnauthorized
+	}
+	userID, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	return userID, schemas.RoleFromString(role), nil
+}
+
+// ParseEventsTicket is ParseAccessToken for the tickets of
+// HandleCreateEventsTicket.
+func (app App) ParseEventsTicket(ticket string) (string, schemas.Role, error) {
+	token, err := jwt.Parse(ticket, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, echo.ErrUnauthorized
+		}
+		return app.Config.JWTAccessSecret, nil
+	}, jwt.WithAudience(eventsTicketAudience), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return "", schemas.RoleUndefined, echo.ErrUnauthorized
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", schemas.RoleUndefined, echo.ErrUnauthorized
+	}
+	userID, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	return userID, schemas.RoleFromString(role), nil
+}
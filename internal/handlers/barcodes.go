@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func (app App) HandleAddItemBarcode(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.AddBarcodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Format == "" {
+		req.Format = DetectBarcodeFormat(req.Code)
+	}
+	if err := ValidateBarcode(req.Code, req.Format); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	req.Code = NormalizeBarcode(req.Code, req.Format)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	bc, err := app.DB.Queries.CreateItemBarcode(ctx, database.CreateItemBarcodeParams{
+		ItemID: itemUUID,
+		Code:   req.Code,
+		Format: string(req.Format),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return echo.NewHTTPError(http.StatusConflict, "barcode is already assigned to an item")
+			case "23503":
+				return echo.ErrNotFound
+			}
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, schemas.Barcode{
+		ItemUUID:  bc.ItemID.String(),
+		Code:      bc.Code,
+		Format:    schemas.BarcodeFormat(bc.Format),
+		CreatedAt: bc.CreatedAt.Time.Unix(),
+	})
+}
+
+func (app App) HandleGetItemBarcodes(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.GetItemBarcodes(ctx, itemUUID)
+	if err != nil {
+		return err
+	}
+
+	nFound := len(found)
+	barcodes := make([]schemas.Barcode, nFound)
+	for i := range nFound {
+		barcodes[i] = schemas.Barcode{
+			ItemUUID:  found[i].ItemID.String(),
+			Code:      found[i].Code,
+			Format:    schemas.BarcodeFormat(found[i].Format),
+			CreatedAt: found[i].CreatedAt.Time.Unix(),
+		}
+	}
+
+	return c.JSON(http.StatusOK, schemas.GetItemBarcodesResponse{
+		NResults: nFound,
+		Barcodes: barcodes,
+	})
+}
+
+func (app App) HandleDeleteItemBarcode(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	code := c.Param("code")
+	if code == "" {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	n, err := app.DB.Queries.DeleteItemBarcode(ctx, database.DeleteItemBarcodeParams{
+		ItemID: itemUUID,
+		Codes:  BarcodeLookupCodes(code),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return echo.ErrNotFound
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (app App) HandleGetItemByBarcode(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	item, err := app.DB.Queries.GetItemByBarcode(ctx, BarcodeLookupCodes(code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, itemFromRow(database.GetItemRow(item)))
+}
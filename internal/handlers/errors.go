@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const MIMEProblemJSON = "application/problem+json"
+
+// HTTPErrorHandler answers every failed request with a schemas.Problem, plug
+// it into echo.Echo.HTTPErrorHandler.
+func (app App) HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	p := ProblemFromError(err)
+	p.Instance = c.Request().URL.Path
+	p.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	if p.RequestID == "" {
+		p.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	if p.Status >= http.StatusInternalServerError {
+		app.Logger.Error("internal error",
+			zap.Error(err),
+			zap.String("path", p.Instance),
+			zap.String("request_id", p.RequestID),
+		)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.Status)
+	} else {
+		var b []byte
+		b, err = json.Marshal(p)
+		if err == nil {
+			err = c.Blob(p.Status, MIMEProblemJSON, b)
+		}
+	}
+	if err != nil {
+		app.Logger.Error("writing error response", zap.Error(err))
+	}
+}
+
+// domainProblems are the errors clients may want to tell apart without
+// parsing the detail, the message of the error becomes the detail.
+var domainProblems = []struct {
+	err     error
+	problem schemas.Problem
+}{
+	{ErrItemArchived, schemas.Problem{Type: schemas.ProblemTypeItemArchived, Title: "Item archived", Status: http.StatusConflict}},
+	{ErrNotEnoughItems, schemas.Problem{Type: schemas.ProblemTypeNotEnoughStock, Title: "Not enough stock", Status: http.StatusUnprocessableEntity}},
+	{ErrItemModified, schemas.Problem{Type: schemas.ProblemTypeItemModified, Title: "Item modified", Status: http.StatusPreconditionFailed}},
+	{ErrIdempotencyKeyMismatch, schemas.Problem{Type: schemas.ProblemTypeIdempotencyKeyMismatch, Title: "Idempotency-Key reused", Status: http.StatusUnprocessableEntity}},
+	{ErrIdempotencyKeyInProgress, schemas.Problem{Type: schemas.ProblemTypeIdempotencyKeyInProgress, Title: "Request in progress", Status: http.StatusConflict}},
+	{ErrNotReversible, schemas.Problem{Type: schemas.ProblemTypeNotReversible, Title: "Not reversible", Status: http.StatusConflict}},
+}
+
+// ProblemFromError maps errors returned by the handlers to responses. Only
+// echo.HTTPError and domain error messages make it into the detail, anything
+// unknown is a 500 without one.
+func ProblemFromError(err error) schemas.Problem {
+	for _, d := range domainProblems {
+		if errors.Is(err, d.err) {
+			p := d.problem
+			p.Detail = err.Error()
+			return p
+		}
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		p := blankProblem(he.Code)
+		switch msg := he.Message.(type) {
+		case schemas.ValidationErrorResponse:
+			p.Type = schemas.ProblemTypeValidation
+			p.Title = "Invalid request"
+			p.Detail = msg.Message
+			p.Errors = msg.Errors
+		case string:
+			// echo's own errors only repeat the status text
+			if !strings.EqualFold(msg, http.StatusText(he.Code)) {
+				p.Detail = msg
+			}
+		}
+		return p
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return schemas.Problem{
+				Type:   schemas.ProblemTypeConflict,
+				Title:  "Already exists",
+				Status: http.StatusConflict,
+				Detail: "a record with the same " + constraintSubject(pgErr) + " already exists",
+			}
+		case "23503":
+			// inserting a reference to nothing vs deleting something referenced
+			if strings.Contains(pgErr.Detail, "is still referenced") {
+				return schemas.Problem{
+					Type:   schemas.ProblemTypeStillReferenced,
+					Title:  "Still referenced",
+					Status: http.StatusConflict,
+					Detail: "the record is still referenced by " + pgErr.TableName,
+				}
+			}
+			return schemas.Problem{
+				Type:   schemas.ProblemTypeMissingReference,
+				Title:  "Referenced record not found",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "a referenced record does not exist",
+			}
+		case "23514":
+			return schemas.Problem{
+				Type:   schemas.ProblemTypeConstraint,
+				Title:  "Constraint violated",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "the value violates " + pgErr.ConstraintName,
+			}
+		case "23502":
+			return schemas.Problem{
+				Type:   schemas.ProblemTypeConstraint,
+				Title:  "Constraint violated",
+				Status: http.StatusUnprocessableEntity,
+				Detail: pgErr.ColumnName + " can't be null",
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, storage.ErrNotExist):
+		return blankProblem(http.StatusNotFound)
+	case errors.Is(err, ErrInvalidCursor):
+		p := blankProblem(http.StatusBadRequest)
+		p.Detail = err.Error()
+		return p
+	case errors.Is(err, context.DeadlineExceeded):
+		return schemas.Problem{
+			Type:   schemas.ProblemTypeTimeout,
+			Title:  "Timed out",
+			Status: http.StatusServiceUnavailable,
+			Detail: "the request took too long, try again later",
+		}
+	}
+
+	return blankProblem(http.StatusInternalServerError)
+}
+
+func blankProblem(status int) schemas.Problem {
+	return schemas.Problem{
+		Type:   schemas.ProblemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+}
+
+// constraintSubject guesses the column from names like "items_sku_key".
+func constraintSubject(pgErr *pgconn.PgError) string {
+	name := strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_")
+	name = strings.TrimSuffix(name, "_key")
+	if name == "" || name == "pkey" || name == pgErr.ConstraintName {
+		return "value"
+	}
+	return strings.ReplaceAll(name, "_", " ")
+}
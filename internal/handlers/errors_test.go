@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	app := handlers.App{Logger: zap.NewNop()}
+	e := echo.New()
+	e.Validator = handlers.NewValidator()
+	e.HTTPErrorHandler = app.HTTPErrorHandler
+	e.Use(middleware.RequestID())
+
+	e.POST("/register", func(c echo.Context) error {
+		var req schemas.RegisterRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.ErrBadRequest
+		}
+		return c.Validate(&req)
+	})
+	e.GET("/taken", func(c echo.Context) error {
+		return fmt.Errorf("creating user: %w", &pgconn.PgError{
+			Code:           "23505",
+			TableName:      "users",
+			ConstraintName: "users_username_key",
+		})
+	})
+	e.GET("/message", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusConflict, "item has stock history, archive it instead")
+	})
+	e.GET("/archived", func(c echo.Context) error {
+		return handlers.ErrItemArchived
+	})
+	e.GET("/reversal", func(c echo.Context) error {
+		return fmt.Errorf("%w: it is a reversal itself", handlers.ErrNotReversible)
+	})
+	e.GET("/secret", func(c echo.Context) error {
+		return fmt.Errorf("connecting to 10.0.0.3: refused")
+	})
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, schemas.Problem) {
+		req := httptest.NewRequest(method, path, nil)
+		if body != "" {
+			req = httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		require.Equal(t, handlers.MIMEProblemJSON, rec.Header().Get(echo.HeaderContentType))
+		var p schemas.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		require.Equal(t, rec.Code, p.Status)
+		require.Equal(t, rec.Header().Get(echo.HeaderXRequestID), p.RequestID)
+		require.NotEmpty(t, p.RequestID)
+		return rec, p
+	}
+
+	_, p := do(http.MethodPost, "/register", `{"role": "root"}`)
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Equal(t, schemas.ProblemTypeValidation, p.Type)
+	require.Equal(t, "/register", p.Instance)
+	require.Len(t, p.Errors, 3)
+
+	_, p = do(http.MethodGet, "/taken", "")
+	require.Equal(t, http.StatusConflict, p.Status)
+	require.Equal(t, schemas.ProblemTypeConflict, p.Type)
+	require.Equal(t, "a record with the same username already exists", p.Detail)
+
+	_, p = do(http.MethodGet, "/message", "")
+	require.Equal(t, schemas.ProblemTypeBlank, p.Type)
+	require.Equal(t, "item has stock history, archive it instead", p.Detail)
+
+	_, p = do(http.MethodGet, "/archived", "")
+	require.Equal(t, http.StatusConflict, p.Status)
+	require.Equal(t, schemas.ProblemTypeItemArchived, p.Type)
+	require.Equal(t, "item is archived", p.Detail)
+
+	_, p = do(http.MethodGet, "/reversal", "")
+	require.Equal(t, http.StatusConflict, p.Status)
+	require.Equal(t, schemas.ProblemTypeNotReversible, p.Type)
+	require.Equal(t, "transaction can't be reversed: it is a reversal itself", p.Detail)
+
+	_, p = do(http.MethodGet, "/secret", "")
+	require.Equal(t, http.StatusInternalServerError, p.Status)
+	require.Empty(t, p.Detail)
+
+	_, p = do(http.MethodGet, "/nowhere", "")
+	require.Equal(t, http.StatusNotFound, p.Status)
+	require.Equal(t, "Not Found", p.Title)
+	require.Empty(t, p.Detail)
+}
+
+func TestDomainProblems(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+		typ    string
+	}{
+		{handlers.ErrItemArchived, http.StatusConflict, schemas.ProblemTypeItemArchived},
+		{handlers.ErrNotEnoughItems, http.StatusUnprocessableEntity, schemas.ProblemTypeNotEnoughStock},
+		{handlers.ErrItemModified, http.StatusPreconditionFailed, schemas.ProblemTypeItemModified},
+		{handlers.ErrIdempotencyKeyMismatch, http.StatusUnprocessableEntity, schemas.ProblemTypeIdempotencyKeyMismatch},
+		{handlers.ErrIdempotencyKeyInProgress, http.StatusConflict, schemas.ProblemTypeIdempotencyKeyInProgress},
+		{handlers.ErrNotReversible, http.StatusConflict, schemas.ProblemTypeNotReversible},
+	} {
+		p := handlers.ProblemFromError(fmt.Errorf("handling: %w", tt.err))
+		require.Equal(t, tt.status, p.Status, tt.typ)
+		require.Equal(t, tt.typ, p.Type)
+		require.NotEmpty(t, p.Title)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// ItemETag is a strong validator made of the version of the item.
+func ItemETag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+// ParseIfMatch returns the item versions listed in an If-Match header, nil
+// for "*". Weak and unknown tags never match, so they are left out.
+func ParseIfMatch(header string) []int32 {
+	if strings.TrimSpace(header) == "*" {
+		return nil
+	}
+
+	versions := []int32{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 32)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, int32(v))
+	}
+	return versions
+}
+
+// MatchesIfNoneMatch compares the ETag with an If-None-Match header, weakly as
+// RFC 9110 wants it for GET and HEAD.
+func MatchesIfNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// requireIfMatch makes writes conditional: a client has to prove it saw the
+// latest version of the item, or explicitly ask to overwrite any with "*".
+func requireIfMatch(c echo.Context) ([]int32, error) {
+	header := c.Request().Header.Get(HeaderIfMatch)
+	if header == "" {
+		return nil, echo.NewHTTPError(http.StatusPreconditionRequired, "If-Match header is required")
+	}
+	return ParseIfMatch(header), nil
+}
+
+// optionalIfMatch is requireIfMatch for endpoints where the condition is
+// not mandatory, nil matches any version.
+func optionalIfMatch(c echo.Context) []int32 {
+	header := c.Request().Header.Get(HeaderIfMatch)
+	if header == "" {
+		return nil
+	}
+	return ParseIfMatch(header)
+}
@@ -0,0 +1,40 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemETag(t *testing.T) {
+	require.Equal(t, `"7"`, handlers.ItemETag(7))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	// "*" matches any version
+	require.Nil(t, handlers.ParseIfMatch("*"))
+	require.Nil(t, handlers.ParseIfMatch(" * "))
+
+	require.Equal(t, []int32{3}, handlers.ParseIfMatch(`"3"`))
+	require.Equal(t, []int32{3, 4}, handlers.ParseIfMatch(`"3", "4"`))
+
+	// weak and foreign tags never match, but aren't "*" either
+	require.Equal(t, []int32{}, handlers.ParseIfMatch(`W/"3"`))
+	require.Equal(t, []int32{}, handlers.ParseIfMatch(`"abc"`))
+	require.Equal(t, []int32{5}, handlers.ParseIfMatch(`W/"3", "5"`))
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	etag := handlers.ItemETag(2)
+
+	// expect true:
+	require.True(t, handlers.MatchesIfNoneMatch(`"2"`, etag))
+	require.True(t, handlers.MatchesIfNoneMatch(`W/"2"`, etag))
+	require.True(t, handlers.MatchesIfNoneMatch(`"1", "2"`, etag))
+	require.True(t, handlers.MatchesIfNoneMatch("*", etag))
+
+	// expect false:
+	require.False(t, handlers.MatchesIfNoneMatch("", etag))
+	require.False(t, handlers.MatchesIfNoneMatch(`"1"`, etag))
+}
@@ -0,0 +1,576 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderLastEventID      = "Last-Event-ID"
+	MIMEEventStream        = "text/event-stream"
+	DefaultEventsRetention = 7 * 24 * time.Hour
+	// long enough to open a stream, a stream outlives its ticket
+	EventsTicketTTL = 30 * time.Second
+	// tickets are access tokens with an audience
+	eventsTicketAudience = "events"
+	// the channel LISTEN/NOTIFY uses, the payload is the id of the event
+	eventsChannel = "events"
+	// events a subscriber may lag behind before it's dropped
+	eventsBuffer   = 256
+	eventsPageSize = 100
+	eventsPing     = 15 * time.Second
+	// how often the events held back are looked at again
+	eventsPoll       = time.Second
+	eventsMaxBackoff = time.Minute
+)
+
+// ErrEventsTooSlow ends the streams of clients that can't keep up, they
+// resume from their last event.
+var ErrEventsTooSlow = errors.New("the client is too slow for the events")
+
+// EventHub passes the events of the database to the streams of this
+// instance. The rows are written by triggers, so every instance sees every
+// change whoever made it.
+type EventHub struct {
+	pool    *pgxpool.Pool
+	queries *database.Queries
+	logger  *zap.Logger
+
+	mu   sync.Mutex
+	subs map[chan HubEvent]struct{}
+	// of the last event broadcast
+	last EventPos
+}
+
+// EventPos is the place of an event in the streams. The ids are taken
+// before the transactions commit, so the events go by the transaction that
+// wrote them first, see database.GetEvents.
+type EventPos struct {
+	TxID int64
+	ID   int64
+}
+
+func (p EventPos) Before(o EventPos) bool {
+	return p.TxID < o.TxID || p.TxID == o.TxID && p.ID < o.ID
+}
+
+func eventPosOf(row database.Event) EventPos {
+	return EventPos{TxID: row.TxID, ID: row.ID}
+}
+
+// HubEvent is an event as the hub broadcasts it.
+type HubEvent struct {
+	schemas.Event
+	Pos EventPos
+}
+
+func NewEventHub(pool *pgxpool.Pool, logger *zap.Logger) *EventHub {
+	return &EventHub{
+		pool:    pool,
+		queries: database.New(pool),
+		logger:  logger,
+		subs:    map[chan HubEvent]struct{}{},
+	}
+}
+
+// Listen waits for notifications and broadcasts their events until ctx is
+// done, reconnecting when the connection is lost.
+func (h *EventHub) Listen(ctx context.Context) {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > eventsMaxBackoff {
+			backoff = time.Second
+		}
+		h.logger.Error("error listening for events", zap.Error(err), zap.Duration("retry_in", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, eventsMaxBackoff)
+	}
+}
+
+func (h *EventHub) listen(ctx context.Context) error {
+	pooled, err := h.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// a listening connection shouldn't go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+		return err
+	}
+
+	for {
+		// what happened while nobody was listening, then what woke us up
+		pending, err := h.catchUp(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the events held back wait for transactions that may never
+		// notify, they're looked at again in a while
+		waitCtx, cancel := ctx, context.CancelFunc(func() {})
+		if pending {
+			waitCtx, cancel = context.WithTimeout(ctx, eventsPoll)
+		}
+		_, err = conn.WaitForNotification(waitCtx)
+		cancel()
+		if err != nil && (!pending || ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+			return err
+		}
+	}
+}
+
+// catchUp broadcasts the events after the last one, and tells if some are
+// held back for the transactions still running. The first time there's no
+// one to send them to, it only remembers where the events are at.
+func (h *EventHub) catchUp(ctx context.Context) (bool, error) {
+	h.mu.Lock()
+	last := h.last
+	h.mu.Unlock()
+
+	dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase*4)
+	defer cancel()
+
+	if last == (EventPos{}) {
+		txID, err := h.queries.GetFinishedTxID(dbCtx)
+		if err != nil {
+			return false, err
+		}
+		h.mu.Lock()
+		h.last = EventPos{TxID: txID}
+		h.mu.Unlock()
+		return false, nil
+	}
+
+	for {
+		rows, err := h.queries.GetEvents(dbCtx, database.GetEventsParams{
+			Limit:     eventsPageSize,
+			AfterTxID: last.TxID,
+			AfterID:   last.ID,
+		})
+		if err != nil {
+			return false, err
+		}
+		h.broadcast(rows)
+		if len(rows) > 0 {
+			last = eventPosOf(rows[len(rows)-1])
+		}
+		if len(rows) < eventsPageSize {
+			break
+		}
+	}
+	return h.queries.HasEventsAfter(dbCtx, database.HasEventsAfterParams{AfterTxID: last.TxID, AfterID: last.ID})
+}
+
+func (h *EventHub) broadcast(rows []database.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, row := range rows {
+		h.last = eventPosOf(row)
+		event, err := EventFromDB(row)
+		if err != nil {
+			h.logger.Error("invalid event", zap.Int64("id", row.ID), zap.Error(err))
+			continue
+		}
+		for ch := range h.subs {
+			select {
+			case ch <- HubEvent{Event: event, Pos: h.last}:
+			default:
+				// closing tells the stream it missed something
+				delete(h.subs, ch)
+				close(ch)
+			}
+		}
+	}
+}
+
+// Subscribe returns a channel of all the new events, closed if the
+// subscriber falls too far behind. The caller has to unsubscribe.
+func (h *EventHub) Subscribe() chan HubEvent {
+	ch := make(chan HubEvent, eventsBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *EventHub) Unsubscribe(ch chan HubEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// EventFromDB decodes the item or transaction the trigger recorded.
+func EventFromDB(row database.Event) (schemas.Event, error) {
+	event := schemas.Event{
+		ID:        row.ID,
+		Type:      schemas.EventType(row.Type),
+		ItemUUID:  row.ItemID.String(),
+		CreatedAt: row.CreatedAt.Time.Unix(),
+	}
+	var err error
+	if strings.HasPrefix(row.Type, "transaction.") {
+		event.Transaction = &schemas.Transaction{}
+		err = json.Unmarshal(row.Data, event.Transaction)
+	} else {
+		event.Item = &schemas.Item{}
+		err = json.Unmarshal(row.Data, event.Item)
+	}
+	return event, err
+}
+
+// eventsStream is what the SSE and WebSocket handlers share.
+type eventsStream struct {
+	itemID pgtype.UUID
+	types  []string
+	// nil for only the new events
+	lastID *int64
+}
+
+// newEventsStream binds and validates the filters, Last-Event-ID wins over
+// the last_event_id parameter.
+func newEventsStream(c echo.Context) (eventsStream, error) {
+	var req schemas.EventsRequest
+	if err := c.Bind(&req); err != nil {
+		return eventsStream{}, echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return eventsStream{}, err
+	}
+
+	s := eventsStream{lastID: req.LastEventID}
+	if v := c.Request().Header.Get(HeaderLastEventID); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			return eventsStream{}, echo.NewHTTPError(http.StatusBadRequest, "Last-Event-ID has to be an event id")
+		}
+		s.lastID = &id
+	}
+	if req.ItemUUID != "" {
+		id, err := UUIDFromString(req.ItemUUID)
+		if err != nil {
+			return eventsStream{}, echo.ErrBadRequest
+		}
+		s.itemID = id
+	}
+	for _, t := range req.Types {
+		s.types = append(s.types, string(t))
+	}
+	return s, nil
+}
+
+func (s eventsStream) match(e schemas.Event) bool {
+	if s.itemID.Valid && e.ItemUUID != s.itemID.String() {
+		return false
+	}
+	if len(s.types) == 0 {
+		return true
+	}
+	for _, t := range s.types {
+		if string(e.Type) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// run sends the events after lastID, then the new ones as they come, and
+// pings when there's nothing to send, until ctx is done or sending fails.
+func (s eventsStream) run(ctx context.Context, app App, send func(schemas.Event) error, ping func() error) error {
+	// subscribed first, so nothing is missed between the replay and the
+	// new events
+	ch := app.Events.Subscribe()
+	defer app.Events.Unsubscribe(ch)
+
+	var replayed EventPos
+	if s.lastID != nil {
+		var err error
+		replayed, err = app.eventPos(ctx, *s.lastID)
+		if err != nil {
+			return err
+		}
+		for {
+			dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+			rows, err := app.DB.Queries.GetEvents(dbCtx, database.GetEventsParams{
+				Limit:     eventsPageSize,
+				AfterTxID: replayed.TxID,
+				AfterID:   replayed.ID,
+				ItemID:    s.itemID,
+				Types:     s.types,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				event, err := EventFromDB(row)
+				if err != nil {
+					return err
+				}
+				if err := send(event); err != nil {
+					return err
+				}
+				replayed = eventPosOf(row)
+			}
+			if len(rows) < eventsPageSize {
+				break
+			}
+		}
+	}
+
+	t := time.NewTicker(eventsPing)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			if err := ping(); err != nil {
+				return err
+			}
+		case event, ok := <-ch:
+			if !ok {
+				return ErrEventsTooSlow
+			}
+			if !replayed.Before(event.Pos) || !s.match(event.Event) {
+				continue
+			}
+			if err := send(event.Event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventPos finds where the client left off. Nothing is kept of pruned
+// events, everything there is came after them.
+func (app App) eventPos(ctx context.Context, id int64) (EventPos, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	txID, err := app.DB.Queries.GetEventTxID(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return EventPos{}, err
+	}
+	return EventPos{TxID: txID, ID: id}, nil
+}
+
+// HandleEvents streams the events as Server-Sent Events. Browsers resume
+// with Last-Event-ID on their own when the connection drops, once their
+// ticket has expired they have to get another one and resume with
+// last_event_id.
+func (app App) HandleEvents(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+	if app.Events == nil {
+		return echo.ErrServiceUnavailable
+	}
+	stream, err := newEventsStream(c)
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, MIMEEventStream)
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// or proxies like nginx hold the events back
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	fmt.Fprintf(res, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+	res.Flush()
+
+	send := func(e schemas.Event) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+
+	// the headers are out, errors can only end the stream
+	err = stream.run(c.Request().Context(), app, send, ping)
+	if err != nil && !errors.Is(err, ErrEventsTooSlow) && c.Request().Context().Err() == nil {
+		app.Logger.Error("error streaming events", zap.Error(err))
+	}
+	return nil
+}
+
+var eventsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// HandleEventsWebSocket sends the same events as HandleEvents as JSON text
+// messages. Nothing is read from the client but the control frames.
+func (app App) HandleEventsWebSocket(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+	if app.Events == nil {
+		return echo.ErrServiceUnavailable
+	}
+	stream, err := newEventsStream(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := eventsUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader has answered already
+		return nil
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(eventsPing * 2))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventsPing * 2))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(e schemas.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(TimeoutDatabase))
+		return conn.WriteJSON(e)
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(TimeoutDatabase))
+	}
+
+	err = stream.run(ctx, app, send, ping)
+	switch {
+	case errors.Is(err, ErrEventsTooSlow):
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow, resume with last_event_id"),
+			time.Now().Add(time.Second))
+	case err != nil && ctx.Err() == nil:
+		app.Logger.Error("error streaming events", zap.Error(err))
+	default:
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+			time.Now().Add(time.Second))
+	}
+	return nil
+}
+
+// HandleCreateEventsTicket gives browsers a ticket to open the streams
+// with in the ticket parameter, they can't set headers for them and the
+// access token shouldn't end up in URLs and the logs of proxies.
+func (app App) HandleCreateEventsTicket(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+	userID, _ := c.Get("userID").(string)
+	role, _ := c.Get("userRole").(schemas.Role)
+	ticket, err := GenerateEventsTicket(userID, role.String(), app.Config.JWTAccessSecret, EventsTicketTTL)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, schemas.EventsTicketResponse{
+		Ticket:  ticket,
+		Expires: time.Now().Add(EventsTicketTTL).Unix(),
+	})
+}
+
+// EventsAuthMiddleware is JWTMiddleware that takes a ticket of
+// HandleCreateEventsTicket in the ticket parameter as well.
+func (app App) EventsAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	withToken := app.JWTMiddleware(next)
+	return func(c echo.Context) error {
+		ticket := c.QueryParam("ticket")
+		if ticket == "" || c.Request().Header.Get("Authorization") != "" {
+			return withToken(c)
+		}
+		userID, role, err := app.ParseEventsTicket(ticket)
+		if err != nil {
+			return err
+		}
+		c.Set("userID", userID)
+		c.Set("userRole", role)
+		return next(c)
+	}
+}
+
+// PruneEvents deletes the events older than Config.EventsRetention, and the
+// finished webhook deliveries as old, every so often until ctx is done.
+func (app App) PruneEvents(ctx context.Context, every time.Duration) {
+	retention := app.Config.EventsRetention
+	if retention == 0 {
+		retention = DefaultEventsRetention
+	}
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			before := pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true}
+			dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase*4)
+			n, err := app.DB.Queries.DeleteEventsBefore(dbCtx, before)
+			if err != nil {
+				app.Logger.Error("error deleting old events", zap.Error(err))
+			} else if n > 0 {
+				app.Logger.Info("deleted old events", zap.Int64("n", n))
+			}
+			n, err = app.DB.Queries.DeleteWebhookDeliveriesBefore(dbCtx, before)
+			cancel()
+			if err != nil {
+				app.Logger.Error("error deleting old webhook deliveries", zap.Error(err))
+			} else if n > 0 {
+				app.Logger.Info("deleted old webhook deliveries", zap.Int64("n", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEventFromDB(t *testing.T) {
+	itemID, err := handlers.UUIDFromString("0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b")
+	require.NoError(t, err)
+	createdAt := pgtype.Timestamptz{Time: time.Unix(1760000000, 0), Valid: true}
+
+	event, err := handlers.EventFromDB(database.Event{
+		ID:        7,
+		Type:      "item.updated",
+		ItemID:    itemID,
+		Data:      []byte(`{"uuid": "0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b", "name": "nails", "quantity": 3, "version": 2}`),
+		CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, schemas.Event{
+		ID:        7,
+		Type:      schemas.EventItemUpdated,
+		ItemUUID:  itemID.String(),
+		CreatedAt: 1760000000,
+		Item:      &schemas.Item{UUID: itemID.String(), Name: "nails", Quantity: 3, Version: 2},
+	}, event)
+
+	event, err = handlers.EventFromDB(database.Event{
+		ID:        8,
+		Type:      "transaction.created",
+		ItemID:    itemID,
+		Data:      []byte(`{"type": "restock", "item_uuid": "0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b", "amount": 3, "status": "succeeded"}`),
+		CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	require.Nil(t, event.Item)
+	require.Equal(t, schemas.TransactionTypeRestock, event.Transaction.Type)
+	require.Equal(t, 3, event.Transaction.Amount)
+}
+
+func TestEventsOrder(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	// takes its id first, but commits after the next one
+	tx, err := app.DB.Pool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+	slow, err := app.DB.Queries.WithTx(tx).CreateItem(ctx, database.CreateItemParams{Name: "slow"})
+	require.NoError(t, err)
+	fast := newTestItem(t, app, "fast")
+
+	events := func() []database.Event {
+		rows, err := app.DB.Queries.GetEvents(ctx, database.GetEventsParams{Limit: 10})
+		require.NoError(t, err)
+		return rows
+	}
+	// a client that got it would skip the slow one when resuming
+	require.Empty(t, events())
+	pending, err := app.DB.Queries.HasEventsAfter(ctx, database.HasEventsAfterParams{})
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	require.NoError(t, tx.Commit(ctx))
+	var rows []database.Event
+	// other transactions of the cluster hold them back too
+	require.Eventually(t, func() bool {
+		rows = events()
+		return len(rows) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, slow.Uuid, rows[0].ItemID)
+	require.Equal(t, fast.Uuid, rows[1].ItemID)
+	require.True(t, handlers.EventPos{TxID: rows[0].TxID, ID: rows[0].ID}.Before(handlers.EventPos{TxID: rows[1].TxID, ID: rows[1].ID}))
+}
+
+func TestEventsAuth(t *testing.T) {
+	app := handlers.App{Logger: zap.NewNop(), Config: handlers.Config{JWTAccessSecret: []byte("access")}}
+	token, err := handlers.GenerateAccessJWT("0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b", "user", app.Config.JWTAccessSecret, time.Minute)
+	require.NoError(t, err)
+
+	do := func(app handlers.App, method, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k := range header {
+			req.Header.Set(k, header[k][0])
+		}
+		rec := httptest.NewRecorder()
+		router.New(app, handlers.RateLimiter{}, handlers.RateLimiter{}).ServeHTTP(rec, req)
+		return rec
+	}
+	get := func(app handlers.App, target string, header http.Header) int {
+		return do(app, http.MethodGet, target, header).Code
+	}
+
+	rec := do(app, http.MethodPost, "/events/ticket", http.Header{"Authorization": {"Bearer " + token}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ticket schemas.EventsTicketResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ticket))
+	require.NotEmpty(t, ticket.Ticket)
+	require.Greater(t, ticket.Expires, time.Now().Unix())
+
+	require.Equal(t, http.StatusUnauthorized, get(app, "/events", nil))
+	require.Equal(t, http.StatusUnauthorized, get(app, "/events/ws?ticket=nope", nil))
+	// neither takes the place of the other
+	require.Equal(t, http.StatusUnauthorized, get(app, "/events?ticket="+token, nil))
+	require.Equal(t, http.StatusUnauthorized, do(app, http.MethodPost, "/events/ticket", http.Header{"Authorization": {"Bearer " + ticket.Ticket}}).Code)
+	expired, err := handlers.GenerateEventsTicket("0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b", "user", app.Config.JWTAccessSecret, -time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, get(app, "/events?ticket="+expired, nil))
+	// authenticated, but the streams are off
+	require.Equal(t, http.StatusServiceUnavailable, get(app, "/events?ticket="+ticket.Ticket, nil))
+	require.Equal(t, http.StatusServiceUnavailable, get(app, "/events/ws", http.Header{"Authorization": {"Bearer " + token}}))
+
+	app.Events = handlers.NewEventHub(nil, zap.NewNop())
+	require.Equal(t, http.StatusBadRequest, get(app, "/events?ticket="+ticket.Ticket, http.Header{handlers.HeaderLastEventID: {"nope"}}))
+	require.Equal(t, http.StatusBadRequest, get(app, "/events?type=item.moved&ticket="+ticket.Ticket, nil))
+	require.Equal(t, http.StatusBadRequest, get(app, "/events/ws?item_uuid=nope&ticket="+ticket.Ticket, nil))
+}
+
+func TestEvents(t *testing.T) {
+	app := testApp(t)
+	app.Config.JWTAccessSecret = []byte("access")
+	app.Events = handlers.NewEventHub(app.DB.Pool, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go app.Events.Listen(ctx)
+
+	usr := newTestUser(t, app, schemas.RoleStocker)
+	item := newTestItem(t, app, "watched")
+
+	created, err := app.DB.Queries.GetEvents(ctx, database.GetEventsParams{Limit: 10, ItemID: item.Uuid})
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.Equal(t, string(schemas.EventItemCreated), created[0].Type)
+
+	// the new events are only sent once the hub listens
+	require.Eventually(t, func() bool {
+		var n int
+		err := app.DB.Pool.QueryRow(ctx, "SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN events'").Scan(&n)
+		return err == nil && n > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	srv := httptest.NewServer(router.New(app, handlers.RateLimiter{}, handlers.RateLimiter{}))
+	t.Cleanup(srv.Close)
+	token, err := handlers.GenerateAccessJWT(usr.ID.String(), "stocker", app.Config.JWTAccessSecret, time.Minute)
+	require.NoError(t, err)
+	ticket, err := handlers.GenerateEventsTicket(usr.ID.String(), "stocker", app.Config.JWTAccessSecret, time.Minute)
+	require.NoError(t, err)
+	query := url.Values{"item_uuid": {item.Uuid.String()}}.Encode()
+	resume := fmt.Sprint(created[0].ID - 1)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?"+query, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(handlers.HeaderLastEventID, resume)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, handlers.MIMEEventStream, res.Header.Get("Content-Type"))
+
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx,
+		"ws"+strings.TrimPrefix(srv.URL, "http")+"/events/ws?"+query+"&last_event_id="+resume+"&ticket="+ticket, nil)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	lines := bufio.NewScanner(res.Body)
+	nextSSE := func() schemas.Event {
+		t.Helper()
+		var id, name string
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				var e schemas.Event
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+				require.Equal(t, fmt.Sprint(e.ID), id)
+				require.Equal(t, string(e.Type), name)
+				return e
+			}
+		}
+		t.Fatalf("the stream ended: %v", lines.Err())
+		return schemas.Event{}
+	}
+	nextWS := func() schemas.Event {
+		t.Helper()
+		var e schemas.Event
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		require.NoError(t, ws.ReadJSON(&e))
+		return e
+	}
+
+	// replayed
+	for _, next := range []func() schemas.Event{nextSSE, nextWS} {
+		e := next()
+		require.Equal(t, created[0].ID, e.ID)
+		require.Equal(t, schemas.EventItemCreated, e.Type)
+		require.Equal(t, "watched", e.Item.Name)
+	}
+
+	// live
+	tr, err := app.CreateTransaction(ctx, usr.ID, schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: item.Uuid.String(),
+		Amount:   4,
+	})
+	require.NoError(t, err)
+	for _, next := range []func() schemas.Event{nextSSE, nextWS} {
+		got := map[schemas.EventType]schemas.Event{}
+		for range 2 {
+			e := next()
+			got[e.Type] = e
+		}
+		require.Equal(t, tr.UUID, got[schemas.EventTransactionCreated].Transaction.UUID)
+		require.Equal(t, 4, got[schemas.EventItemUpdated].Item.Quantity)
+	}
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	MIMETextCSV     = "text/csv"
+	MIMENDJSON      = "application/x-ndjson"
+	MIMEXLSX        = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	TimeoutExport   = 10 * time.Minute
+	exportFlushRows = 1000
+)
+
+var ErrExportFormat = echo.NewHTTPError(http.StatusNotAcceptable, "expected csv, ndjson or xlsx")
+
+var exportFormatTypes = map[string]schemas.ExportFormat{
+	MIMETextCSV:          schemas.ExportFormatCSV,
+	MIMENDJSON:           schemas.ExportFormatNDJSON,
+	"application/ndjson": schemas.ExportFormatNDJSON,
+	"application/jsonl":  schemas.ExportFormatNDJSON,
+	MIMEXLSX:             schemas.ExportFormatXLSX,
+	"text/*":             schemas.ExportFormatCSV,
+	"*/*":                schemas.ExportFormatCSV,
+}
+
+var itemsExportColumns = []string{
+	"uuid", "name", "sku", "quantity", "product_uuid", "options", "archived_at", "created_at", "updated_at", "version",
+}
+
+var transactionsExportColumns = []string{
+	"uuid", "type", "status", "item_uuid", "owner_uuid", "amount", "reason", "reversal_of", "created_at",
+}
+
+// NegotiateExportFormat picks the format from the format query parameter,
+// or else from the Accept header. CSV is the default.
+func NegotiateExportFormat(format, accept string) (schemas.ExportFormat, error) {
+	if format != "" {
+		switch f := schemas.ExportFormat(strings.ToLower(format)); f {
+		case schemas.ExportFormatCSV, schemas.ExportFormatNDJSON, schemas.ExportFormatXLSX:
+			return f, nil
+		case "jsonl":
+			return schemas.ExportFormatNDJSON, nil
+		}
+		return "", ErrExportFormat
+	}
+	if strings.TrimSpace(accept) == "" {
+		return schemas.ExportFormatCSV, nil
+	}
+
+	// the first acceptable type with the highest q wins
+	var best schemas.ExportFormat
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		f, ok := exportFormatTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	if best == "" {
+		return "", ErrExportFormat
+	}
+	return best, nil
+}
+
+// HandleExportItems streams the items matching the filters of GET /items.
+func (app App) HandleExportItems(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	format, err := NegotiateExportFormat(c.QueryParam("format"), c.Request().Header.Get(echo.HeaderAccept))
+	if err != nil {
+		return err
+	}
+
+	var req schemas.GetItemsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Cursor != "" || req.Limit != 0 || req.Offset != 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "exports aren't paginated")
+	}
+
+	archived, err := archivedFilter(req.Archived)
+	if err != nil {
+		return err
+	}
+	params, err := searchItemsParams(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	params.Archived = archived
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutExport)
+	defer cancel()
+
+	w, err := newExportWriter(c, format, "items", "Items", itemsExportColumns)
+	if err != nil {
+		return err
+	}
+	err = app.DB.Queries.ForEachItem(ctx, params, func(row database.SearchItemsRow) error {
+		item := itemFromRow(row.GetItemRow)
+		var options any
+		if len(item.Options) > 0 {
+			options = string(row.VariantOptions)
+		}
+		return w.Write(item, []any{
+			item.UUID,
+			item.Name,
+			item.SKU,
+			item.Quantity,
+			item.ProductUUID,
+			options,
+			exportTime(row.ArchivedAt),
+			exportTime(row.CreatedAt),
+			exportTime(row.UpdatedAt),
+			item.Version,
+		})
+	})
+	return app.finishExport(c, w, err)
+}
+
+// HandleExportTransactions streams the transactions matching the filters of
+// GET /transactions, oldest first.
+func (app App) HandleExportTransactions(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	format, err := NegotiateExportFormat(c.QueryParam("format"), c.Request().Header.Get(echo.HeaderAccept))
+	if err != nil {
+		return err
+	}
+
+	var req schemas.GetAllTransactionsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Cursor != "" || req.Limit != 0 || req.Offset != 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "exports aren't paginated")
+	}
+
+	filters, err := transactionFilters(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutExport)
+	defer cancel()
+
+	w, err := newExportWriter(c, format, "transactions", "Transactions", transactionsExportColumns)
+	if err != nil {
+		return err
+	}
+	err = app.DB.Queries.ForEachTransaction(ctx, filters, func(row database.Transaction) error {
+		tr := transactionFromRow(row)
+		return w.Write(tr, []any{
+			tr.UUID,
+			string(tr.Type),
+			string(tr.Status),
+			tr.ItemUUID,
+			tr.OwnerUUID,
+			tr.Amount,
+			tr.Reason,
+			tr.ReversalOf,
+			exportTime(row.CreatedAt),
+		})
+	})
+	return app.finishExport(c, w, err)
+}
+
+// finishExport ends the file. The status is sent with the first rows, so a
+// failure after that can only be told by cutting the response short.
+func (app App) finishExport(c echo.Context, w exportWriter, err error) error {
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return nil
+	}
+	if !c.Response().Committed {
+		return err
+	}
+	app.Logger.Error("export failed midway", zap.String("path", c.Request().URL.Path), zap.Error(err))
+	panic(http.ErrAbortHandler)
+}
+
+// exportTime is nil for NULL so the cell stays empty.
+func exportTime(ts pgtype.Timestamptz) any {
+	if !ts.Valid {
+		return nil
+	}
+	return ts.Time.UTC()
+}
+
+// exportWriter writes the rows in one of the formats, v is what NDJSON gets
+// and record is for the spreadsheets, in the order of the columns.
+type exportWriter interface {
+	Write(v any, record []any) error
+	Close() error
+}
+
+func newExportWriter(c echo.Context, format schemas.ExportFormat, name, sheet string, columns []string) (exportWriter, error) {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102-150405"), format)
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	switch format {
+	case schemas.ExportFormatNDJSON:
+		h.Set(echo.HeaderContentType, MIMENDJSON)
+		return &ndjsonExport{res: c.Response(), enc: json.NewEncoder(c.Response())}, nil
+	case schemas.ExportFormatXLSX:
+		h.Set(echo.HeaderContentType, MIMEXLSX)
+		return newXLSXExport(c.Response(), sheet, columns)
+	default:
+		h.Set(echo.HeaderContentType, MIMETextCSV+"; charset=utf-8")
+		w := &csvExport{res: c.Response(), w: csv.NewWriter(c.Response()), header: columns}
+		return w, nil
+	}
+}
+
+type csvExport struct {
+	res    *echo.Response
+	w      *csv.Writer
+	header []string
+	n      int
+}
+
+func (e *csvExport) Write(_ any, record []any) error {
+	if e.header != nil {
+		if err := e.w.Write(e.header); err != nil {
+			return err
+		}
+		e.header = nil
+	}
+	fields := make([]string, len(record))
+	for i, v := range record {
+		switch v := v.(type) {
+		case nil:
+		case time.Time:
+			fields[i] = v.Format(time.RFC3339)
+		case string:
+			fields[i] = escapeCSVFormula(v)
+		default:
+			fields[i] = fmt.Sprint(v)
+		}
+	}
+	if err := e.w.Write(fields); err != nil {
+		return err
+	}
+	e.n++
+	if e.n%exportFlushRows == 0 {
+		e.w.Flush()
+		e.res.Flush()
+	}
+	return e.w.Error()
+}
+
+func (e *csvExport) Close() error {
+	if e.header != nil {
+		// no rows, still a valid file
+		if err := e.w.Write(e.header); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// escapeCSVFormula keeps spreadsheets from running user input as a formula.
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type ndjsonExport struct {
+	res *echo.Response
+	enc *json.Encoder
+	n   int
+}
+
+func (e *ndjsonExport) Write(v any, _ []any) error {
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	e.n++
+	if e.n%exportFlushRows == 0 {
+		e.res.Flush()
+	}
+	return nil
+}
+
+func (e *ndjsonExport) Close() error {
+	if !e.res.Committed {
+		e.res.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
+
+// xlsxExport can't be sent before it's done since XLSX is a zip, the stream
+// writer keeps the rows in a temporary file meanwhile.
+type xlsxExport struct {
+	w     io.Writer
+	f     *excelize.File
+	sw    *excelize.StreamWriter
+	nRows int
+}
+
+func newXLSXExport(w io.Writer, sheet string, columns []string) (*xlsxExport, error) {
+	f := excelize.NewFile()
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		f.Close()
+		return nil, err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header := make([]any, len(columns))
+	for i, col := range columns {
+		header[i] = col
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxExport{w: w, f: f, sw: sw, nRows: 1}, nil
+}
+
+func (e *xlsxExport) Write(_ any, record []any) error {
+	e.nRows++
+	cell, err := excelize.CoordinatesToCellName(1, e.nRows)
+	if err != nil {
+		return err
+	}
+	return e.sw.SetRow(cell, record)
+}
+
+func (e *xlsxExport) Close() error {
+	defer e.f.Close()
+	if err := e.sw.Flush(); err != nil {
+		return err
+	}
+	return e.f.Write(e.w)
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNegotiateExportFormat(t *testing.T) {
+	cases := []struct {
+		format, accept string
+		want           schemas.ExportFormat
+	}{
+		{"", "", schemas.ExportFormatCSV},
+		{"", "*/*", schemas.ExportFormatCSV},
+		{"", "application/x-ndjson", schemas.ExportFormatNDJSON},
+		{"", "text/csv;q=0.5, " + handlers.MIMEXLSX, schemas.ExportFormatXLSX},
+		{"", "text/html, application/jsonl;q=0.9, */*;q=0.1", schemas.ExportFormatNDJSON},
+		{"XLSX", "text/csv", schemas.ExportFormatXLSX},
+		{"jsonl", "", schemas.ExportFormatNDJSON},
+	}
+	for _, tc := range cases {
+		got, err := handlers.NegotiateExportFormat(tc.format, tc.accept)
+		require.NoError(t, err, "%q %q", tc.format, tc.accept)
+		require.Equal(t, tc.want, got, "%q %q", tc.format, tc.accept)
+	}
+
+	_, err := handlers.NegotiateExportFormat("pdf", "")
+	require.ErrorIs(t, err, handlers.ErrExportFormat)
+	_, err = handlers.NegotiateExportFormat("", "text/html")
+	require.ErrorIs(t, err, handlers.ErrExportFormat)
+}
+
+func TestExportItems(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	var uuids []string
+	for _, name := range []string{"=cmd|calc", "audit-b", "audit-c"} {
+		item, err := app.DB.Queries.CreateItem(ctx, database.CreateItemParams{Name: name + "-" + suffix})
+		require.NoError(t, err)
+		uuids = append(uuids, item.Uuid.String())
+	}
+
+	e := newTestEcho(app, newTestUser(t, app, schemas.RoleUser))
+	e.GET("/items/export", app.HandleExportItems)
+	export := func(query, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items/export?sort=created_at&name="+suffix+query, nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := export("", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get(echo.HeaderContentType), handlers.MIMETextCSV)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, "uuid", records[0][0])
+	require.Equal(t, uuids[0], records[1][0])
+	require.Equal(t, "'=cmd|calc-"+suffix, records[1][1])
+
+	rec = export("", handlers.MIMENDJSON)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var items []schemas.Item
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var item schemas.Item
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &item))
+		items = append(items, item)
+	}
+	require.Len(t, items, 3)
+	require.Equal(t, "=cmd|calc-"+suffix, items[0].Name)
+
+	rec = export("&format=xlsx", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	f, err := excelize.OpenReader(rec.Body)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Items")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	require.Equal(t, uuids[2], rows[3][0])
+
+	require.Equal(t, http.StatusBadRequest, export("&limit=10", "").Code)
+	require.Equal(t, http.StatusNotAcceptable, export("", "image/png").Code)
+}
@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/testdb"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testApp connects to a database of the test's own, see testdb.
+func testApp(t *testing.T) handlers.App {
+	t.Helper()
+
+	pool := testdb.Pool(t)
+	return handlers.App{
+		DB: handlers.Database{
+			Pool:    pool,
+			Queries: database.New(pool),
+		},
+		Logger: zap.NewNop(),
+	}
+}
+
+// newTestUser creates a user named after its role.
+func newTestUser(t *testing.T, app handlers.App, role schemas.Role) database.CreateUserRow {
+	t.Helper()
+
+	usr, err := app.DB.Queries.CreateUser(context.Background(), database.CreateUserParams{
+		Username:     role.String(),
+		PasswordHash: "-",
+		Role:         role.String(),
+	})
+	require.NoError(t, err)
+	return usr
+}
+
+func newTestItem(t *testing.T, app handlers.App, name string) database.CreateItemRow {
+	t.Helper()
+
+	item, err := app.DB.Queries.CreateItem(context.Background(), database.CreateItemParams{Name: name})
+	require.NoError(t, err)
+	return item
+}
+
+// newTestEcho serves the routes added to it as usr, the way the router does
+// once JWTMiddleware let a request through.
+func newTestEcho(app handlers.App, usr database.CreateUserRow) *echo.Echo {
+	e := echo.New()
+	e.Validator = handlers.NewValidator()
+	e.HTTPErrorHandler = app.HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("userID", usr.ID.String())
+			c.Set("userRole", schemas.RoleFromString(usr.Role))
+			return next(c)
+		}
+	})
+	return e
+}
+
+// serve sends a JSON body to e, header is a list of names and values.
+func serve(e *echo.Echo, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	DefaultIdempotencyKeyTTL  = 24 * time.Hour
+	IdempotencyKeyMaxLength   = 255
+	idempotencyRequestMaxSize = 1 << 20
+	// a claimed key without a response is given up on after this, in case
+	// the server died while handling the request
+	idempotencyLockTimeout = time.Minute
+)
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("Idempotency-Key was already used for another request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+)
+
+// IdempotencyMiddleware makes retries of the route safe: the first request
+// with an Idempotency-Key runs, the following ones with the same key get its
+// response back. Goes after JWTMiddleware, keys are scoped by user.
+func (app App) IdempotencyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > IdempotencyKeyMaxLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+		}
+
+		userID, ok := c.Get("userID").(string)
+		if !ok {
+			return echo.ErrForbidden
+		}
+		userUUID, err := UUIDFromString(userID)
+		if err != nil {
+			return echo.ErrForbidden
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, idempotencyRequestMaxSize+1))
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		if len(body) > idempotencyRequestMaxSize {
+			return echo.ErrStatusRequestEntityTooLarge
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+		hash := RequestHash(c.Request().Method, c.Path(), body)
+
+		ttl := app.Config.IdempotencyKeyTTL
+		if ttl == 0 {
+			ttl = DefaultIdempotencyKeyTTL
+		}
+		now := time.Now()
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+		defer cancel()
+		claimed, err := app.DB.Queries.ClaimIdempotencyKey(ctx, database.ClaimIdempotencyKeyParams{
+			UserID:      userUUID,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   pgtype.Timestamptz{Time: now.Add(ttl), Valid: true},
+			StaleBefore: pgtype.Timestamptz{Time: now.Add(-idempotencyLockTimeout), Valid: true},
+		})
+		if err != nil {
+			app.Logger.Error("error claiming idempotency key", zap.Error(err))
+			return err
+		}
+		if claimed == 0 {
+			return app.replayIdempotent(ctx, c, userUUID, key, hash)
+		}
+
+		rec := &bodyRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = rec
+		committed := new(atomic.Bool)
+		c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), commitFlagKey{}, committed)))
+		err = next(c)
+
+		// the client may be gone already, the outcome has to be kept anyway
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(c.Request().Context()), TimeoutDatabase)
+		defer cancel()
+
+		// nothing happened, the key can be used again
+		if !committed.Load() {
+			relErr := app.DB.Queries.ReleaseIdempotencyKey(ctx, database.ReleaseIdempotencyKeyParams{
+				UserID: userUUID,
+				Key:    key,
+			})
+			if relErr != nil {
+				app.Logger.Error("error releasing idempotency key", zap.Error(relErr))
+			}
+			return err
+		}
+
+		// the changes are in whatever the response, a retry must not repeat
+		// them, so even an error is answered here to be kept
+		if err != nil {
+			c.Error(err)
+		}
+		saveErr := app.DB.Queries.SaveIdempotentResponse(ctx, database.SaveIdempotentResponseParams{
+			UserID:       userUUID,
+			Key:          key,
+			StatusCode:   int32(c.Response().Status),
+			ResponseBody: rec.body.Bytes(),
+			ContentType:  PtrFromString(c.Response().Header().Get(echo.HeaderContentType)),
+		})
+		if saveErr != nil {
+			app.Logger.Error("error saving idempotent response", zap.Error(saveErr))
+		}
+		return nil
+	}
+}
+
+// commitFlagKey marks the context of a request with an Idempotency-Key.
+type commitFlagKey struct{}
+
+// commitTx commits the changes of an idempotent route. Goes instead of
+// tx.Commit there, so that the key is kept once something has been changed.
+func commitTx(ctx context.Context, tx pgx.Tx) error {
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if committed, ok := ctx.Value(commitFlagKey{}).(*atomic.Bool); ok {
+		committed.Store(true)
+	}
+	return nil
+}
+
+func (app App) replayIdempotent(ctx context.Context, c echo.Context, userUUID pgtype.UUID, key string, hash []byte) error {
+	stored, err := app.DB.Queries.GetIdempotencyKey(ctx, database.GetIdempotencyKeyParams{
+		UserID: userUUID,
+		Key:    key,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// released in between, the first request failed and retrying is fine
+		return idempotencyInProgress(c)
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored.RequestHash, hash) {
+		return ErrIdempotencyKeyMismatch
+	}
+	if stored.StatusCode == nil {
+		return idempotencyInProgress(c)
+	}
+
+	contentType := echo.MIMEApplicationJSON
+	if stored.ContentType != nil {
+		contentType = *stored.ContentType
+	}
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(int(*stored.StatusCode), contentType, stored.ResponseBody)
+}
+
+func idempotencyInProgress(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+	return ErrIdempotencyKeyInProgress
+}
+
+// RequestHash tells apart requests sent with the same Idempotency-Key.
+func RequestHash(method, route string, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(route))
+	h.Write([]byte{0})
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// PruneIdempotencyKeys deletes expired keys every so often until ctx is done.
+func (app App) PruneIdempotencyKeys(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase*4)
+			n, err := app.DB.Queries.DeleteExpiredIdempotencyKeys(dbCtx)
+			cancel()
+			if err != nil {
+				app.Logger.Error("error deleting expired idempotency keys", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				app.Logger.Info("deleted expired idempotency keys", zap.Int64("n", n))
+			}
+		}
+	}
+}
+
+// bodyRecorder keeps a copy of the response to replay it later.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHash(t *testing.T) {
+	h := handlers.RequestHash(http.MethodPost, "/transactions", []byte(`{"amount":1}`))
+	require.Len(t, h, 32)
+	require.Equal(t, h, handlers.RequestHash(http.MethodPost, "/transactions", []byte(`{"amount":1}`)))
+	require.NotEqual(t, h, handlers.RequestHash(http.MethodPost, "/transactions", []byte(`{"amount":2}`)))
+	require.NotEqual(t, h, handlers.RequestHash(http.MethodPost, "/transactions/batch", []byte(`{"amount":1}`)))
+}
+
+func TestIdempotentTransactions(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleStocker)
+	item := newTestItem(t, app, "flaky-wifi")
+
+	e := newTestEcho(app, usr)
+	e.POST("/transactions", app.HandleCreateTransaction, app.IdempotencyMiddleware)
+	sendFor := func(key, typ, itemUUID string, amount int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"type": %q, "item_uuid": %q, "amount": %d}`, typ, itemUUID, amount)
+		return serve(e, http.MethodPost, "/transactions", body, handlers.HeaderIdempotencyKey, key)
+	}
+	send := func(key string, amount int) *httptest.ResponseRecorder {
+		return sendFor(key, "restock", item.Uuid.String(), amount)
+	}
+	nTransactions := func() int {
+		var n int
+		err := app.DB.Pool.QueryRow(ctx, "SELECT count(*) FROM transactions WHERE item_id = $1", item.Uuid).Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+
+	// a retry gets the same transaction back
+	first := send("scan-1", 5)
+	require.Equal(t, http.StatusAccepted, first.Code)
+	retry := send("scan-1", 5)
+	require.Equal(t, http.StatusAccepted, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(handlers.HeaderIdempotentReplayed))
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+	require.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+	require.Equal(t, 1, nTransactions())
+
+	// the same key for something else
+	require.Equal(t, http.StatusUnprocessableEntity, send("scan-1", 6).Code)
+
+	// concurrent duplicates run once
+	const workers = 10
+	recs := make(chan *httptest.ResponseRecorder, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs <- send("scan-2", 1)
+		}()
+	}
+	wg.Wait()
+	close(recs)
+
+	uuids := map[string]bool{}
+	for rec := range recs {
+		switch rec.Code {
+		case http.StatusAccepted:
+			var tr schemas.Transaction
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tr))
+			uuids[tr.UUID] = true
+		case http.StatusConflict:
+			require.NotEmpty(t, rec.Header().Get(echo.HeaderRetryAfter))
+		default:
+			t.Fatalf("unexpected status %d", rec.Code)
+		}
+	}
+	require.Len(t, uuids, 1)
+	require.Equal(t, 2, nTransactions())
+
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, qty.Quantity)
+
+	// nothing was committed, the key is free for the corrected request
+	require.Equal(t, http.StatusNotFound, sendFor("scan-3", "restock", "00000000-0000-0000-0000-000000000000", 1).Code)
+	require.Equal(t, http.StatusAccepted, send("scan-3", 1).Code)
+	require.Equal(t, 3, nTransactions())
+
+	// the failed withdrawal is recorded, so it's kept like a success
+	failed := sendFor("scan-4", "withdraw", item.Uuid.String(), 100)
+	require.Equal(t, http.StatusUnprocessableEntity, failed.Code)
+	retry = sendFor("scan-4", "withdraw", item.Uuid.String(), 100)
+	require.Equal(t, http.StatusUnprocessableEntity, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(handlers.HeaderIdempotentReplayed))
+	require.JSONEq(t, failed.Body.String(), retry.Body.String())
+	require.Equal(t, 4, nTransactions())
+}
@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	ImportMaxSize    = 10 << 20
+	TimeoutImportJob = 10 * time.Minute
+	// a running job is taken over after this, its instance must have died
+	importJobStaleAfter = TimeoutImportJob + time.Minute
+)
+
+var ErrUnknownImportFormat = errors.New("expected a .csv or .xlsx file")
+
+// the columns an import understands, with the usual ways to name them
+var importColumnAliases = map[string]string{
+	"name":             "name",
+	"item":             "name",
+	"item_name":        "name",
+	"sku":              "sku",
+	"article":          "sku",
+	"quantity":         "quantity",
+	"qty":              "quantity",
+	"opening_quantity": "quantity",
+	"opening_balance":  "quantity",
+}
+
+// ImportItem is a row of an import file, Errors are the ones found without
+// looking at the database.
+type ImportItem struct {
+	Row      int                  `json:"row"`
+	Name     string               `json:"name"`
+	SKU      string               `json:"sku,omitempty"`
+	Quantity int                  `json:"quantity,omitempty"`
+	Errors   []schemas.FieldError `json:"errors,omitempty"`
+}
+
+// HandleImportItems creates or updates items from a CSV or XLSX file. Items
+// are matched by SKU, then by name, and the quantity of the new ones is
+// posted as a restock. Large files go to a job that can be polled, the jobs
+// are run by RunImportJobs.
+func (app App) HandleImportItems(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuidStr, ok := c.Get("userID").(string)
+	if uuidStr == "" || !ok {
+		return echo.ErrForbidden
+	}
+	userUUID, err := UUIDFromString(uuidStr)
+	if err != nil {
+		return echo.ErrForbidden
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, ImportMaxSize+64<<10)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			return echo.ErrStatusRequestEntityTooLarge
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "expected a multipart form with a file field")
+	}
+	if fh.Size > ImportMaxSize {
+		return echo.ErrStatusRequestEntityTooLarge
+	}
+
+	// query or form values, the file is sent as a form anyway
+	dryRun, err := parseFormBool(c.FormValue("dry_run"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be true or false")
+	}
+	async, err := parseFormBool(c.FormValue("async"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "async must be true or false")
+	}
+	var mapping map[string]string
+	if m := c.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "mapping must be a JSON object of column names to fields")
+		}
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	items, err := ReadImportFile(fh.Filename, data, mapping)
+	if err != nil {
+		if errors.Is(err, ErrUnknownImportFormat) {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if async || len(items) > schemas.ImportSyncMaxRows {
+		itemsJSON, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+		defer cancel()
+		job, err := app.DB.Queries.CreateImportJob(ctx, database.CreateImportJobParams{
+			UserID:   userUUID,
+			Filename: filepath.Base(fh.Filename),
+			DryRun:   dryRun,
+			NRows:    int32(len(items)),
+			Items:    itemsJSON,
+		})
+		if err != nil {
+			app.Logger.Error("error creating import job", zap.Error(err))
+			return err
+		}
+
+		c.Response().Header().Set(echo.HeaderLocation, "/items/import/"+job.ID.String())
+		return c.JSON(http.StatusAccepted, importJobFromRow(job))
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*time.Duration(4+len(items)))
+	defer cancel()
+	res, err := app.importItems(ctx, userUUID, items, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if res.NFailed > 0 && !dryRun {
+		return c.JSON(http.StatusUnprocessableEntity, res)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (app App) HandleGetImportJob(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	job, err := app.DB.Queries.GetImportJob(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, importJobFromRow(job))
+}
+
+// RunImportJobs runs the pending import jobs every so often until ctx is
+// done.
+func (app App) RunImportJobs(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := app.RunPendingImportJobs(ctx); err != nil && ctx.Err() == nil {
+				app.Logger.Error("error running import jobs", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunPendingImportJobs runs the jobs waiting for an instance one at a time,
+// and returns how many it ran.
+func (app App) RunPendingImportJobs(ctx context.Context) (int, error) {
+	n := 0
+	for ctx.Err() == nil {
+		dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+		job, err := app.DB.Queries.ClaimImportJob(dbCtx, pgtype.Timestamptz{Time: time.Now().Add(-importJobStaleAfter), Valid: true})
+		cancel()
+		if errors.Is(err, pgx.ErrNoRows) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		app.runImportJob(ctx, job)
+		n++
+	}
+	return n, ctx.Err()
+}
+
+// runImportJob is importItems for the claimed job.
+func (app App) runImportJob(ctx context.Context, job database.ImportJob) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutImportJob)
+	defer cancel()
+
+	params := database.FinishImportJobParams{
+		ID:     job.ID,
+		Status: string(schemas.ImportJobStatusSucceeded),
+	}
+	var items []ImportItem
+	err := json.Unmarshal(job.Items, &items)
+	if err != nil {
+		err = fmt.Errorf("decoding rows: %w", err)
+	} else {
+		var res schemas.ImportResult
+		res, err = app.importItems(ctx, job.UserID, items, job.DryRun)
+		if err == nil {
+			if res.NFailed > 0 && !job.DryRun {
+				params.Status = string(schemas.ImportJobStatusFailed)
+			}
+			params.Result, err = json.Marshal(res)
+		}
+	}
+	if err != nil && errors.Is(err, context.Canceled) {
+		// shutting down, rolled back and left for the next instance
+		app.Logger.Warn("import job interrupted", zap.String("job", job.ID.String()))
+		return
+	}
+	if err != nil {
+		app.Logger.Error("import job failed", zap.String("job", job.ID.String()), zap.Error(err))
+		msg := ProblemFromError(err).Title
+		params.Status = string(schemas.ImportJobStatusFailed)
+		params.Result = nil
+		params.Error = &msg
+	}
+
+	// the job has to be finished even when the import ran out of time
+	finishCtx, cancelFinish := context.WithTimeout(context.WithoutCancel(ctx), TimeoutDatabase)
+	defer cancelFinish()
+	if err := app.DB.Queries.FinishImportJob(finishCtx, params); err != nil {
+		app.Logger.Error("error finishing import job", zap.String("job", job.ID.String()), zap.Error(err))
+	}
+}
+
+// importItems writes the items in a single transaction, which is rolled
+// back on a dry run or when any row fails, so the result tells what would
+// happen either way.
+func (app App) importItems(ctx context.Context, userUUID pgtype.UUID, items []ImportItem, dryRun bool) (schemas.ImportResult, error) {
+	res := schemas.ImportResult{
+		DryRun: dryRun,
+		NRows:  len(items),
+		Rows:   make([]schemas.ImportRow, len(items)),
+	}
+
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, item := range items {
+		row := &res.Rows[i]
+		row.Row = item.Row
+		row.Errors = item.Errors
+		if len(row.Errors) == 0 {
+			err := app.importItemSavepoint(ctx, tx, userUUID, item, row)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return res, err
+			}
+			if err != nil {
+				row.Errors = append(row.Errors, importRowError(err))
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			res.NFailed++
+		case row.Action == schemas.ImportActionCreated:
+			res.NCreated++
+		case row.Action == schemas.ImportActionUpdated:
+			res.NUpdated++
+		default:
+			res.NUnchanged++
+		}
+	}
+
+	if dryRun || res.NFailed > 0 {
+		return res, nil
+	}
+	return res, tx.Commit(ctx)
+}
+
+func (app App) importItemSavepoint(ctx context.Context, tx pgx.Tx, userUUID pgtype.UUID, item ImportItem, row *schemas.ImportRow) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx)
+	q := app.DB.Queries.WithTx(sp)
+
+	found, err := q.GetItemBySKUOrName(ctx, database.GetItemBySKUOrNameParams{
+		Sku:  PtrFromString(item.SKU),
+		Name: item.Name,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		created, err := q.CreateItem(ctx, database.CreateItemParams{
+			Name: item.Name,
+			Sku:  PtrFromString(item.SKU),
+		})
+		if err != nil {
+			return err
+		}
+		row.Action = schemas.ImportActionCreated
+		row.ItemUUID = created.Uuid.String()
+
+		if item.Quantity > 0 {
+			_, err := app.applyTransaction(ctx, q, userUUID, created.Uuid, schemas.CreateTransactionRequest{
+				Type:   schemas.TransactionTypeRestock,
+				Amount: item.Quantity,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return err
+	default:
+		row.ItemUUID = found.Uuid.String()
+		row.Action = schemas.ImportActionUnchanged
+
+		var params database.PatchItemParams
+		if found.Name != item.Name {
+			params.Name = &item.Name
+		}
+		if item.SKU != "" && StringFromPtr(found.Sku) != item.SKU {
+			params.Sku = &item.SKU
+		}
+		if params.Name != nil || params.Sku != nil {
+			params.Uuid = found.Uuid
+			if _, err := q.PatchItem(ctx, params); err != nil {
+				return err
+			}
+			row.Action = schemas.ImportActionUpdated
+		}
+		if item.Quantity > 0 {
+			row.Notes = append(row.Notes, "the item exists, its quantity is left as it is")
+		}
+	}
+
+	return sp.Commit(ctx)
+}
+
+// importRowError puts a database error on the column it's about.
+func importRowError(err error) schemas.FieldError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return schemas.FieldError{
+			Field:   constraintSubject(pgErr),
+			Rule:    "unique",
+			Message: "is already used by another item",
+		}
+	}
+	p := ProblemFromError(err)
+	msg := p.Detail
+	if msg == "" {
+		msg = strings.ToLower(p.Title)
+	}
+	return schemas.FieldError{Message: msg}
+}
+
+// ReadImportFile parses a CSV or XLSX file with a header row. The mapping
+// renames columns to fields, the known column names don't need it.
+func ReadImportFile(filename string, data []byte, mapping map[string]string) ([]ImportItem, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSV(data)
+	case ".xlsx":
+		records, err = readXLSX(data)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+	if len(records)-1 > schemas.ImportMaxRows {
+		return nil, fmt.Errorf("too many rows, at most %d are allowed", schemas.ImportMaxRows)
+	}
+
+	columns, err := importColumns(records[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ImportItem, 0, len(records)-1)
+	seenNames := map[string]int{}
+	seenSKUs := map[string]int{}
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		item := ImportItem{Row: i + 2}
+		cell := func(field string) string {
+			idx, ok := columns[field]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		item.Name = cell("name")
+		item.SKU = cell("sku")
+		if item.Name == "" {
+			item.Errors = append(item.Errors, schemas.FieldError{Field: "name", Rule: "required", Message: "is required"})
+		} else if row, ok := seenNames[item.Name]; ok {
+			item.Errors = append(item.Errors, schemas.FieldError{Field: "name", Rule: "unique", Message: fmt.Sprintf("is the same as in row %d", row)})
+		} else {
+			seenNames[item.Name] = item.Row
+		}
+		if item.SKU != "" {
+			if row, ok := seenSKUs[item.SKU]; ok {
+				item.Errors = append(item.Errors, schemas.FieldError{Field: "sku", Rule: "unique", Message: fmt.Sprintf("is the same as in row %d", row)})
+			} else {
+				seenSKUs[item.SKU] = item.Row
+			}
+		}
+		if q := cell("quantity"); q != "" {
+			n, err := strconv.Atoi(q)
+			switch {
+			case err != nil:
+				item.Errors = append(item.Errors, schemas.FieldError{Field: "quantity", Rule: "number", Message: "must be a whole number"})
+			case n < 0:
+				item.Errors = append(item.Errors, schemas.FieldError{Field: "quantity", Rule: "min", Param: "0", Message: "must be at least 0"})
+			case n > math.MaxInt32:
+				// stored as an integer
+				item.Errors = append(item.Errors, schemas.FieldError{Field: "quantity", Rule: "max", Param: strconv.Itoa(math.MaxInt32), Message: "must be at most " + strconv.Itoa(math.MaxInt32)})
+			default:
+				item.Quantity = n
+			}
+		}
+
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// importColumns finds the index of every field in the header.
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	normalized := make(map[string]string, len(mapping))
+	for col, field := range mapping {
+		if importColumnAliases[field] != field {
+			return nil, fmt.Errorf("unknown field %q in mapping, expected name, sku or quantity", field)
+		}
+		normalized[normalizeColumn(col)] = field
+	}
+
+	columns := map[string]int{}
+	for i, h := range header {
+		col := normalizeColumn(h)
+		field, ok := normalized[col]
+		if !ok {
+			field, ok = importColumnAliases[col]
+		}
+		if !ok {
+			continue // unknown columns are fine, spreadsheets have notes and such
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("more than one column for %s", field)
+		}
+		columns[field] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("no name column in the header")
+	}
+	return columns, nil
+}
+
+func normalizeColumn(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM from Excel
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	// Excel in most of Europe saves with semicolons
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return records, nil
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{
+		UnzipSizeLimit:    ImportMaxSize * 10,
+		UnzipXMLSizeLimit: ImportMaxSize * 10,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+	// only the first sheet, like the CSV export of a workbook
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseFormBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+func importJobFromRow(job database.ImportJob) schemas.ImportJob {
+	res := schemas.ImportJob{
+		UUID:       job.ID.String(),
+		Filename:   job.Filename,
+		DryRun:     job.DryRun,
+		Status:     schemas.ImportJobStatus(job.Status),
+		NRows:      int(job.NRows),
+		Error:      StringFromPtr(job.Error),
+		CreatedAt:  job.CreatedAt.Time.Unix(),
+		FinishedAt: UnixOrZero(job.FinishedAt),
+	}
+	if len(job.Result) > 0 {
+		var result schemas.ImportResult
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			res.Result = &result
+		}
+	}
+	return res
+}
@@ -0,0 +1,227 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadImportFileCSV(t *testing.T) {
+	data := "\xef\xbb\xbfItem Name;SKU;Qty;Comment\n" +
+		"drill;DR-1;5;top shelf\n" +
+		";;;\n" +
+		"saw;;-1;\n" +
+		";HM-1;x;\n" +
+		"drill;DR-1;;\n" +
+		"bolt;;4294967296;\n"
+
+	items, err := handlers.ReadImportFile("stock.csv", []byte(data), nil)
+	require.NoError(t, err)
+	require.Len(t, items, 5)
+
+	require.Equal(t, 2, items[0].Row)
+	require.Equal(t, "drill", items[0].Name)
+	require.Equal(t, "DR-1", items[0].SKU)
+	require.Equal(t, 5, items[0].Quantity)
+	require.Empty(t, items[0].Errors)
+
+	// the blank row is skipped but still counted
+	require.Equal(t, 4, items[1].Row)
+	require.Len(t, items[1].Errors, 1)
+	require.Equal(t, "quantity", items[1].Errors[0].Field)
+	require.Equal(t, "min", items[1].Errors[0].Rule)
+
+	require.Len(t, items[2].Errors, 2)
+	require.Equal(t, "name", items[2].Errors[0].Field)
+	require.Equal(t, "required", items[2].Errors[0].Rule)
+	require.Equal(t, "quantity", items[2].Errors[1].Field)
+
+	require.Len(t, items[3].Errors, 2)
+	require.Equal(t, "unique", items[3].Errors[0].Rule)
+	require.Equal(t, "is the same as in row 2", items[3].Errors[0].Message)
+
+	// too big for the quantity column rather than wrapped around
+	require.Len(t, items[4].Errors, 1)
+	require.Equal(t, schemas.FieldError{Field: "quantity", Rule: "max", Param: "2147483647", Message: "must be at most 2147483647"}, items[4].Errors[0])
+	require.Zero(t, items[4].Quantity)
+}
+
+func TestReadImportFileMapping(t *testing.T) {
+	data := "Bezeichnung,Artikelnummer,Bestand\nhammer,HM-1,3\n"
+
+	_, err := handlers.ReadImportFile("stock.csv", []byte(data), nil)
+	require.Error(t, err)
+
+	items, err := handlers.ReadImportFile("stock.csv", []byte(data), map[string]string{
+		"Bezeichnung":   "name",
+		"Artikelnummer": "sku",
+		"Bestand":       "quantity",
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "hammer", items[0].Name)
+	require.Equal(t, "HM-1", items[0].SKU)
+	require.Equal(t, 3, items[0].Quantity)
+
+	_, err = handlers.ReadImportFile("stock.csv", []byte(data), map[string]string{"Bestand": "price"})
+	require.Error(t, err)
+
+	_, err = handlers.ReadImportFile("stock.pdf", []byte(data), nil)
+	require.ErrorIs(t, err, handlers.ErrUnknownImportFormat)
+}
+
+func TestReadImportFileXLSX(t *testing.T) {
+	f := excelize.NewFile()
+	t.Cleanup(func() { f.Close() })
+	rows := [][]any{
+		{"name", "sku", "opening quantity"},
+		{"wrench", "WR-1", 12},
+		{"pliers", nil, 0},
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+
+	items, err := handlers.ReadImportFile("stock.xlsx", buf.Bytes(), nil)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "wrench", items[0].Name)
+	require.Equal(t, "WR-1", items[0].SKU)
+	require.Equal(t, 12, items[0].Quantity)
+	require.Equal(t, 3, items[1].Row)
+	require.Equal(t, "pliers", items[1].Name)
+	require.Empty(t, items[1].SKU)
+	require.Empty(t, items[1].Errors)
+}
+
+func TestImportItems(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	drill := newTestItem(t, app, "drill")
+	e := newTestEcho(app, usr)
+	e.POST("/items/import", app.HandleImportItems)
+	e.GET("/items/import/:uuid", app.HandleGetImportJob)
+	upload := func(csv string, fields ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for i := 0; i+1 < len(fields); i += 2 {
+			require.NoError(t, mw.WriteField(fields[i], fields[i+1]))
+		}
+		fw, err := mw.CreateFormFile("file", "stock.csv")
+		require.NoError(t, err)
+		fw.Write([]byte(csv))
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/items/import", &body)
+		req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	result := func(rec *httptest.ResponseRecorder) schemas.ImportResult {
+		t.Helper()
+		var res schemas.ImportResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+	find := func(sku, name string) (database.GetItemBySKUOrNameRow, error) {
+		return app.DB.Queries.GetItemBySKUOrName(ctx, database.GetItemBySKUOrNameParams{Sku: handlers.PtrFromString(sku), Name: name})
+	}
+	const file = "name,sku,quantity\ndrill,DR-1,5\nsaw,SW-1,7\n"
+
+	// a dry run tells what would happen and writes nothing
+	rec := upload(file, "dry_run", "true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	res := result(rec)
+	require.True(t, res.DryRun)
+	require.Equal(t, 1, res.NCreated)
+	require.Equal(t, 1, res.NUpdated)
+	_, err := find("SW-1", "saw")
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// matched by name, the new one gets its opening balance as a restock
+	rec = upload(file)
+	require.Equal(t, http.StatusOK, rec.Code)
+	res = result(rec)
+	require.Equal(t, schemas.ImportActionUpdated, res.Rows[0].Action)
+	require.Equal(t, drill.Uuid.String(), res.Rows[0].ItemUUID)
+	require.NotEmpty(t, res.Rows[0].Notes)
+	require.Equal(t, schemas.ImportActionCreated, res.Rows[1].Action)
+	saw, err := find("SW-1", "saw")
+	require.NoError(t, err)
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, saw.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 7, qty.Quantity)
+	var restocks int
+	err = app.DB.Pool.QueryRow(ctx,
+		"SELECT count(*) FROM transactions WHERE item_id = $1 AND type = 'restock' AND amount = 7", saw.Uuid,
+	).Scan(&restocks)
+	require.NoError(t, err)
+	require.Equal(t, 1, restocks)
+	// the existing item keeps its quantity
+	qty, err = app.DB.Queries.GetItemQuantity(ctx, drill.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, qty.Quantity)
+
+	// matched by SKU first, so the name can change
+	rec = upload("name,sku\ncordless drill,DR-1\n")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, result(rec).NUpdated)
+	renamed, err := find("DR-1", "")
+	require.NoError(t, err)
+	require.Equal(t, drill.Uuid, renamed.Uuid)
+	require.Equal(t, "cordless drill", renamed.Name)
+
+	// one bad row and nothing is written
+	rec = upload("name,quantity\nbolt,3\nsaw,x\n")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	res = result(rec)
+	require.Equal(t, 1, res.NFailed)
+	require.Equal(t, schemas.ImportActionCreated, res.Rows[0].Action)
+	_, err = find("", "bolt")
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// a job waits for the worker
+	rec = upload("name,quantity\nnut,40\n", "async", "true")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var job schemas.ImportJob
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	require.Equal(t, schemas.ImportJobStatusPending, job.Status)
+	require.Equal(t, "/items/import/"+job.UUID, rec.Header().Get(echo.HeaderLocation))
+
+	n, err := app.RunPendingImportJobs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	rec = serve(e, http.MethodGet, "/items/import/"+job.UUID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	require.Equal(t, schemas.ImportJobStatusSucceeded, job.Status)
+	require.NotZero(t, job.FinishedAt)
+	require.Equal(t, 1, job.Result.NCreated)
+	_, err = find("", "nut")
+	require.NoError(t, err)
+
+	// nothing left to run
+	n, err = app.RunPendingImportJobs(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
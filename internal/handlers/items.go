@@ -3,12 +3,16 @@ package handlers
 import (
 	"context"
 	"errors"
+	"math"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/bigelle/warehouse/internal/database"
-	"github.com/bigelle/warehouse/internal/schemas"
+	"github.com/bigelle/warehouse/pkg/schemas"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -22,23 +26,37 @@ func (app App) HandleCreateItem(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
-	defer cancel()
-	item, err := app.DB.Queries.CreateItem(ctx, req.Name)
+	res, err := app.CreateItem(c.Request().Context(), req)
 	if err != nil {
-		var uniqueErr *pgconn.PgError
-		if ok := errors.As(err, &uniqueErr); ok && uniqueErr.Code == "23505" {
-			return echo.NewHTTPError(http.StatusConflict, "item with this name already exists")
-		}
 		return err
 	}
+	return c.JSON(200, res)
+}
+
+// CreateItem is shared by the REST and gRPC APIs, like the other App
+// methods that take a context instead of echo.Context. The request is
+// expected to be validated.
+func (app App) CreateItem(ctx context.Context, req schemas.CreateItemRequest) (schemas.CreateItemResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	item, err := app.DB.Queries.CreateItem(ctx, database.CreateItemParams{
+		Name: req.Name,
+		Sku:  PtrFromString(req.SKU),
+	})
+	if err != nil {
+		return schemas.CreateItemResponse{}, err
+	}
 
-	return c.JSON(200, schemas.CreateItemResponse{
+	return schemas.CreateItemResponse{
 		UUID:      item.Uuid.String(),
 		Name:      item.Name,
+		SKU:       StringFromPtr(item.Sku),
 		CreatedAt: item.CreatedAt.Time.Unix(),
-	})
+	}, nil
 }
 
 func (app App) HandleGetItems(c echo.Context) error {
@@ -50,42 +68,174 @@ func (app App) HandleGetItems(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
 
+	res, err := app.GetItems(c.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	if res.NResults == 0 {
+		return echo.ErrNotFound
+	}
+	setPageLinks(c, res.NextCursor, res.PrevCursor)
+	return c.JSON(200, res)
+}
+
+// GetItems finds a page of items, an empty one if nothing matches.
+func (app App) GetItems(ctx context.Context, req schemas.GetItemsRequest) (schemas.GetItemsResponse, error) {
+	var none schemas.GetItemsResponse
 	if req.Limit == 0 {
 		req.Limit = schemas.GetItemsRequestDefaultLimit
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
-	defer cancel()
-	found, err := app.DB.Queries.GetNItemsOffset(ctx, database.GetNItemsOffsetParams{
-		Limit:  int32(req.Limit),
-		Offset: int32(req.Offset),
-	})
+	archived, err := archivedFilter(req.Archived)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
+		return none, err
+	}
+
+	params, err := searchItemsParams(req)
+	if err != nil {
+		return none, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	params.Archived = archived
+
+	// keyset pagination, offset is kept for older clients
+	filters := itemsFilters(req)
+	var backward bool
+	if req.Cursor != "" {
+		if req.Offset != 0 {
+			return none, echo.NewHTTPError(http.StatusBadRequest, "cursor can't be combined with offset")
 		}
+		var cur itemsCursor
+		if err := DecodeCursor(req.Cursor, &cur); err != nil {
+			return none, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if cur.Filters != filters {
+			return none, echo.NewHTTPError(http.StatusBadRequest, "cursor was issued for a different sort or filters")
+		}
+		params.Keyset = cur.keyset()
+		backward = cur.Backward
+	}
+	// one more to know if there is a next page
+	params.Limit++
+
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.SearchItems(ctx, params)
+	if err != nil {
 		app.Logger.Error("getting rows", zap.Error(err))
-		return err
+		return none, err
 	}
 
+	found, hasMore := trimPage(found, req.Limit, backward)
 	nFound := len(found)
 	if nFound == 0 {
-		return echo.ErrNotFound
+		return schemas.GetItemsResponse{Items: []schemas.Item{}}, nil
+	}
+
+	// there is always a page in the direction we came from
+	var next, prev string
+	if hasMore || backward {
+		next, err = EncodeCursor(newItemsCursor(found[nFound-1], filters, false))
+		if err != nil {
+			return none, err
+		}
+	}
+	if (hasMore && backward) || (!backward && (req.Cursor != "" || req.Offset > 0)) {
+		prev, err = EncodeCursor(newItemsCursor(found[0], filters, true))
+		if err != nil {
+			return none, err
+		}
 	}
 
 	items := make([]schemas.Item, nFound)
+	var productIDs []pgtype.UUID
 	for i := range nFound {
-		items[i] = schemas.Item{
-			UUID:     found[i].Uuid.String(),
-			Name:     found[i].Name,
-			Quantity: int(found[i].Quantity),
+		items[i] = itemFromRow(found[i].GetItemRow)
+		if found[i].ProductID.Valid && !slices.Contains(productIDs, found[i].ProductID) {
+			productIDs = append(productIDs, found[i].ProductID)
 		}
 	}
-	return c.JSON(200, schemas.GetItemsResponse{
-		NResults: nFound,
-		Items:    items,
-	})
+
+	// aggregating stock of the variants over all their siblings, not only this page
+	var products []schemas.ProductStock
+	if len(productIDs) > 0 {
+		stock, err := app.DB.Queries.GetProductsStock(ctx, productIDs)
+		if err != nil {
+			app.Logger.Error("getting product stock", zap.Error(err))
+			return none, err
+		}
+		products = make([]schemas.ProductStock, len(stock))
+		for i := range stock {
+			products[i] = schemas.ProductStock{
+				UUID:      stock[i].Uuid.String(),
+				Name:      stock[i].Name,
+				NVariants: int(stock[i].NVariants),
+				Quantity:  int(stock[i].Quantity),
+			}
+		}
+	}
+
+	return schemas.GetItemsResponse{
+		NResults:   nFound,
+		Items:      items,
+		Products:   products,
+		NextCursor: next,
+		PrevCursor: prev,
+	}, nil
+}
+
+// searchItemsParams checks the filters of the request and converts them for the query.
+func searchItemsParams(req schemas.GetItemsRequest) (database.SearchItemsParams, error) {
+	if req.Limit < 0 || req.Offset < 0 {
+		return database.SearchItemsParams{}, errors.New("limit and offset can't be negative")
+	}
+
+	params := database.SearchItemsParams{
+		Limit:         int32(req.Limit),
+		Offset:        int32(req.Offset),
+		Name:          PtrFromString(strings.TrimSpace(req.Name)),
+		Sku:           PtrFromString(strings.TrimSpace(req.SKU)),
+		Query:         PtrFromString(strings.TrimSpace(req.Query)),
+		CreatedAfter:  TimestampFromUnix(req.CreatedAfter),
+		CreatedBefore: TimestampFromUnix(req.CreatedBefore),
+		UpdatedAfter:  TimestampFromUnix(req.UpdatedAfter),
+		UpdatedBefore: TimestampFromUnix(req.UpdatedBefore),
+	}
+
+	if req.MinQuantity != nil {
+		if *req.MinQuantity < math.MinInt32 || *req.MinQuantity > math.MaxInt32 {
+			return params, errors.New("min_quantity is out of range")
+		}
+		v := int32(*req.MinQuantity)
+		params.MinQuantity = &v
+	}
+	if req.MaxQuantity != nil {
+		if *req.MaxQuantity < math.MinInt32 || *req.MaxQuantity > math.MaxInt32 {
+			return params, errors.New("max_quantity is out of range")
+		}
+		v := int32(*req.MaxQuantity)
+		params.MaxQuantity = &v
+	}
+	if params.MinQuantity != nil && params.MaxQuantity != nil && *params.MinQuantity > *params.MaxQuantity {
+		return params, errors.New("min_quantity is greater than max_quantity")
+	}
+	if req.CreatedAfter != 0 && req.CreatedBefore != 0 && req.CreatedAfter >= req.CreatedBefore {
+		return params, errors.New("created_after has to be earlier than created_before")
+	}
+	if req.UpdatedAfter != 0 && req.UpdatedBefore != 0 && req.UpdatedAfter >= req.UpdatedBefore {
+		return params, errors.New("updated_after has to be earlier than updated_before")
+	}
+
+	sort, err := ParseItemSort(req.Sort)
+	if err != nil {
+		return params, err
+	}
+	params.Sort = sort
+
+	return params, nil
 }
 
 func (app App) HandleGetSingleItem(c echo.Context) error {
@@ -103,21 +253,109 @@ func (app App) HandleGetSingleItem(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	item, err := app.GetItem(c.Request().Context(), strUuid)
+	if err != nil {
+		return err
+	}
+
+	etag := ItemETag(int32(item.Version))
+	c.Response().Header().Set(HeaderETag, etag)
+	if MatchesIfNoneMatch(c.Request().Header.Get(HeaderIfNoneMatch), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(200, item)
+}
+
+func (app App) GetItem(ctx context.Context, uuid pgtype.UUID) (schemas.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
-	item, err := app.DB.Queries.GetItem(ctx, strUuid)
+	item, err := app.DB.Queries.GetItem(ctx, uuid)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
+			return schemas.Item{}, echo.ErrNotFound
 		}
+		return schemas.Item{}, err
+	}
+
+	return itemFromRow(item), nil
+}
+
+// GetItemsByUUID is GetItem for a batch, the items that don't exist are left
+// out.
+func (app App) GetItemsByUUID(ctx context.Context, uuids []pgtype.UUID) ([]schemas.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.GetItems(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]schemas.Item, len(found))
+	for i, item := range found {
+		items[i] = itemFromRow(database.GetItemRow(item))
+	}
+	return items, nil
+}
+
+// HandleGetItemStock answers with the quantity of the item at any point in
+// time, as the ledger has it.
+func (app App) HandleGetItemStock(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.GetItemStockRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
 		return err
 	}
 
-	return c.JSON(200, schemas.Item{
-		UUID:     item.Uuid.String(),
-		Name:     item.Name,
-		Quantity: int(item.Quantity),
+	stock, err := app.GetItemStock(c.Request().Context(), uuid, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, stock)
+}
+
+func (app App) GetItemStock(ctx context.Context, uuid pgtype.UUID, req schemas.GetItemStockRequest) (schemas.ItemStock, error) {
+	asOf := TimestampFromUnix(req.AsOf)
+	if req.AsOf == 0 {
+		// not truncated to seconds, the movements made just now count too
+		asOf = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase*2)
+	defer cancel()
+	if _, err := app.DB.Queries.GetItemQuantity(ctx, uuid); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return schemas.ItemStock{}, echo.ErrNotFound
+		}
+		return schemas.ItemStock{}, err
+	}
+
+	stock, err := app.DB.Queries.GetItemStockAsOf(ctx, database.GetItemStockAsOfParams{
+		ItemID: uuid,
+		AsOf:   asOf,
 	})
+	if err != nil {
+		return schemas.ItemStock{}, err
+	}
+
+	return schemas.ItemStock{
+		ItemUUID:       uuid.String(),
+		AsOf:           asOf.Time.Unix(),
+		Quantity:       int(stock.Quantity),
+		NMovements:     int(stock.NMovements),
+		LastMovementAt: UnixOrZero(stock.LastMovementAt),
+	}, nil
 }
 
 func (app App) HandlePatchItem(c echo.Context) error {
@@ -134,31 +372,92 @@ func (app App) HandlePatchItem(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
 
 	uuid, err := UUIDFromString(strUUID)
 	if err != nil {
 		return echo.ErrBadRequest
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	versions, err := requireIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	// admins are trusted, but who did it still goes to the ledger
+	userID, _ := c.Get("userID").(string)
+	userUUID, _ := UUIDFromString(userID)
+
+	item, err := app.PatchItem(c.Request().Context(), userUUID, uuid, versions, req)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(HeaderETag, ItemETag(int32(item.Version)))
+	return c.JSON(200, item)
+}
+
+// PatchItem changes the item if it still has one of the versions, any
+// version if nil.
+func (app App) PatchItem(ctx context.Context, userUUID, uuid pgtype.UUID, versions []int32, req schemas.PatchRequest) (schemas.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase*2)
 	defer cancel()
-	item, err := app.DB.Queries.PatchItem(ctx, database.PatchItemParams{
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return schemas.Item{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := app.DB.Queries.WithTx(tx)
+
+	item, err := q.PatchItem(ctx, database.PatchItemParams{
 		Uuid:     uuid,
 		Name:     req.Name,
+		Sku:      req.SKU,
 		Quantity: req.Quantity,
+		Versions: versions,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
+			return schemas.Item{}, app.itemPreconditionFailed(ctx, uuid)
 		}
-		return err
+		return schemas.Item{}, err
 	}
 
-	return c.JSON(200, schemas.Item{
-		UUID:     item.Uuid.String(),
-		Name:     item.Name,
-		Quantity: int(item.Quantity),
-	})
+	// setting the quantity is an adjustment like any other, so that the
+	// ledger still adds up
+	if delta := item.Quantity - item.PreviousQuantity; delta != 0 {
+		err := q.AppendStockMovement(ctx, database.AppendStockMovementParams{
+			ItemID: item.Uuid,
+			Delta:  delta,
+			Kind:   schemas.StockMovementAdjustment,
+			UserID: userUUID,
+		})
+		if err != nil {
+			app.Logger.Error("error recording stock movement", zap.Error(err))
+			return schemas.Item{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return schemas.Item{}, err
+	}
+
+	// the quantity before the patch is only for the ledger
+	return itemFromRow(database.GetItemRow{
+		Uuid:           item.Uuid,
+		Name:           item.Name,
+		Sku:            item.Sku,
+		Quantity:       item.Quantity,
+		ProductID:      item.ProductID,
+		VariantOptions: item.VariantOptions,
+		ArchivedAt:     item.ArchivedAt,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		Version:        item.Version,
+	}), nil
 }
 
 func (app App) HandleDeleteItem(c echo.Context) error {
@@ -176,15 +475,209 @@ func (app App) HandleDeleteItem(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
+	versions, err := requireIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	// archiving instead of deleting, the history of the item has to stay
 	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
 	defer cancel()
-	err = app.DB.Queries.DeleteItem(ctx, uuid)
+	_, err = app.DB.Queries.ArchiveItem(ctx, database.ArchiveItemParams{
+		Uuid:     uuid,
+		Versions: versions,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
+			return app.itemPreconditionFailed(ctx, uuid)
 		}
 		return err
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (app App) HandleArchiveItem(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	item, err := app.ArchiveItem(c.Request().Context(), uuid, optionalIfMatch(c))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(HeaderETag, ItemETag(int32(item.Version)))
+	return c.JSON(200, item)
+}
+
+func (app App) ArchiveItem(ctx context.Context, uuid pgtype.UUID, versions []int32) (schemas.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	item, err := app.DB.Queries.ArchiveItem(ctx, database.ArchiveItemParams{
+		Uuid:     uuid,
+		Versions: versions,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return schemas.Item{}, app.itemPreconditionFailed(ctx, uuid)
+		}
+		return schemas.Item{}, err
+	}
+
+	return itemFromRow(database.GetItemRow(item)), nil
+}
+
+func (app App) HandleUnarchiveItem(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	item, err := app.UnarchiveItem(c.Request().Context(), uuid, optionalIfMatch(c))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(HeaderETag, ItemETag(int32(item.Version)))
+	return c.JSON(200, item)
+}
+
+func (app App) UnarchiveItem(ctx context.Context, uuid pgtype.UUID, versions []int32) (schemas.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	item, err := app.DB.Queries.UnarchiveItem(ctx, database.UnarchiveItemParams{
+		Uuid:     uuid,
+		Versions: versions,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return schemas.Item{}, app.itemPreconditionFailed(ctx, uuid)
+		}
+		return schemas.Item{}, err
+	}
+
+	return itemFromRow(database.GetItemRow(item)), nil
+}
+
+// HandlePurgeItem removes the item for good, which is only allowed while
+// it has no stock history.
+func (app App) HandlePurgeItem(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	versions, err := requireIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*3)
+	defer cancel()
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := app.DB.Queries.WithTx(tx)
+
+	// locked first, attachments can't be added in the meantime and be
+	// removed by the cascade without their files
+	if _, err := q.LockItems(ctx, []pgtype.UUID{uuid}); err != nil {
+		return err
+	}
+	attachments, err := q.GetItemAttachmentKeys(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	n, err := q.PurgeItem(ctx, database.PurgeItemParams{
+		Uuid:     uuid,
+		Versions: versions,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		item, err := q.GetItem(ctx, uuid)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return echo.ErrNotFound
+			}
+			return err
+		}
+		if versions != nil && !slices.Contains(versions, item.Version) {
+			return ErrItemModified
+		}
+		return echo.NewHTTPError(http.StatusConflict, "item has stock history, archive it instead")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// the rows went with the item
+	for _, a := range attachments {
+		app.removeStored(c.Request().Context(), a.StorageKey, StringFromPtr(a.ThumbnailKey))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+var ErrItemModified = errors.New("item was modified, fetch it again")
+
+// itemPreconditionFailed tells why a conditional update of the item matched
+// no rows: it's either gone or has another version by now.
+func (app App) itemPreconditionFailed(ctx context.Context, uuid pgtype.UUID) error {
+	if _, err := app.DB.Queries.GetItem(ctx, uuid); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	return ErrItemModified
+}
+
+// itemFromRow converts an item as the queries return it, the rows of the
+// other item queries convert to database.GetItemRow.
+func itemFromRow(row database.GetItemRow) schemas.Item {
+	return schemas.Item{
+		UUID:        row.Uuid.String(),
+		Name:        row.Name,
+		SKU:         StringFromPtr(row.Sku),
+		Quantity:    int(row.Quantity),
+		ProductUUID: row.ProductID.String(),
+		Options:     VariantOptionsFromJSON(row.VariantOptions),
+		ArchivedAt:  UnixOrZero(row.ArchivedAt),
+		Version:     int(row.Version),
+	}
+}
+
+// archivedFilter converts the archived filter of the item lists, nil means
+// both archived and active ones.
+func archivedFilter(s string) (*bool, error) {
+	switch s {
+	case "", "exclude":
+		return new(bool), nil
+	case "only":
+		archived := true
+		return &archived, nil
+	case "include":
+		return nil, nil
+	default:
+		return nil, echo.ErrBadRequest
+	}
+}
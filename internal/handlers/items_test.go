@@ -0,0 +1,143 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveItem(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	item := newTestItem(t, app, "discontinued")
+	e := newTestEcho(app, usr)
+	e.POST("/items/:uuid/archive", app.HandleArchiveItem)
+	e.POST("/items/:uuid/unarchive", app.HandleUnarchiveItem)
+	e.POST("/transactions", app.HandleCreateTransaction)
+	archive := func(action string) schemas.Item {
+		t.Helper()
+		rec := serve(e, http.MethodPost, "/items/"+item.Uuid.String()+"/"+action, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res schemas.Item
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+	restock := func() int {
+		body := fmt.Sprintf(`{"type": "restock", "item_uuid": %q, "amount": 1}`, item.Uuid.String())
+		return serve(e, http.MethodPost, "/transactions", body).Code
+	}
+
+	archived := archive("archive")
+	require.NotZero(t, archived.ArchivedAt)
+	// archiving again keeps the date
+	require.Equal(t, archived.ArchivedAt, archive("archive").ArchivedAt)
+	require.Equal(t, http.StatusConflict, restock())
+
+	require.Zero(t, archive("unarchive").ArchivedAt)
+	require.Equal(t, http.StatusAccepted, restock())
+
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, qty.Quantity)
+}
+
+func TestPurgeItem(t *testing.T) {
+	app := testApp(t)
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir)
+	require.NoError(t, err)
+	app.Storage = store
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	mistake := newTestItem(t, app, "typo")
+	stocked := newTestItem(t, app, "stocked")
+	e := newTestEcho(app, usr)
+	e.DELETE("/items/:uuid/purge", app.HandlePurgeItem)
+	e.POST("/items/:uuid/attachments", app.HandleUploadItemAttachment)
+	e.POST("/transactions", app.HandleCreateTransaction)
+	purge := func(uuid, ifMatch string) int {
+		return serve(e, http.MethodDelete, "/items/"+uuid+"/purge", "", handlers.HeaderIfMatch, ifMatch).Code
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "photo.png")
+	require.NoError(t, err)
+	fw.Write(encodePNG(t, 32, 32))
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, "/items/"+mistake.Uuid.String()+"/attachments", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	files := func() []string {
+		found, err := filepath.Glob(filepath.Join(dir, "attachments", "*"))
+		require.NoError(t, err)
+		return found
+	}
+	// the original and its thumbnail
+	require.Len(t, files(), 2)
+
+	require.Equal(t, http.StatusPreconditionFailed, purge(mistake.Uuid.String(), handlers.ItemETag(7)))
+	require.Equal(t, http.StatusNoContent, purge(mistake.Uuid.String(), "*"))
+	require.Empty(t, files())
+	require.Equal(t, http.StatusNotFound, purge(mistake.Uuid.String(), "*"))
+
+	// with stock history it can only be archived
+	rec = serve(e, http.MethodPost, "/transactions", fmt.Sprintf(`{"type": "restock", "item_uuid": %q, "amount": 1}`, stocked.Uuid.String()))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Equal(t, http.StatusConflict, purge(stocked.Uuid.String(), "*"))
+}
+
+func TestItemsCursor(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"bolt", "nut", "washer"} {
+		newTestItem(t, app, name)
+	}
+	ten, five := 10, 5
+	page := func(req schemas.GetItemsRequest) schemas.GetItemsResponse {
+		t.Helper()
+		res, err := app.GetItems(ctx, req)
+		require.NoError(t, err)
+		return res
+	}
+
+	first := page(schemas.GetItemsRequest{Limit: 2, Sort: "name", MaxQuantity: &ten})
+	require.Equal(t, 2, first.NResults)
+	require.NotEmpty(t, first.NextCursor)
+	second := page(schemas.GetItemsRequest{Limit: 2, Sort: "name", MaxQuantity: &ten, Cursor: first.NextCursor})
+	require.Equal(t, 1, second.NResults)
+	require.Equal(t, "washer", second.Items[0].Name)
+
+	// any other filter makes the position meaningless
+	for _, req := range []schemas.GetItemsRequest{
+		{Limit: 2, Sort: "-name", MaxQuantity: &ten},
+		{Limit: 2, Sort: "name", MaxQuantity: &five},
+		{Limit: 2, Sort: "name", MaxQuantity: &ten, Name: "w"},
+		{Limit: 2, Sort: "name", MaxQuantity: &ten, Archived: "include"},
+	} {
+		req.Cursor = first.NextCursor
+		_, err := app.GetItems(ctx, req)
+		var he *echo.HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusBadRequest, he.Code)
+	}
+	// the page size doesn't matter
+	require.Equal(t, 1, page(schemas.GetItemsRequest{Limit: 5, Sort: "name", MaxQuantity: &ten, Cursor: first.NextCursor}).NResults)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/labels"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MIMEImageSVG = "image/svg+xml"
+	MIMEPDF      = "application/pdf"
+)
+
+func (app App) HandleGetItemLabel(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.GetLabelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	found, err := app.DB.Queries.GetItemLabels(ctx, []pgtype.UUID{uuid})
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return echo.ErrNotFound
+	}
+
+	return renderLabel(c, req, itemLabel(found[0].Uuid, found[0].Name, found[0].Barcode).Content)
+}
+
+func (app App) HandleGetItemLabelBySKU(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	sku := c.Param("sku")
+	if sku == "" {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.GetLabelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	item, err := app.DB.Queries.GetItemLabelBySKU(ctx, &sku)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	return renderLabel(c, req, itemLabel(item.Uuid, item.Name, item.Barcode).Content)
+}
+
+func (app App) HandleGetLocationLabel(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	code := c.Param("code")
+	if code == "" {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.GetLabelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	return renderLabel(c, req, code)
+}
+
+func (app App) HandleCreateLabelSheet(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleStocker) {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.LabelSheetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	n := len(req.Items) + len(req.Locations)
+	if n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "no labels requested")
+	}
+	if n > schemas.LabelSheetMaxLabels {
+		return echo.NewHTTPError(http.StatusBadRequest, "too many labels in one sheet")
+	}
+	if req.Layout == "" {
+		req.Layout = string(labels.LayoutA4)
+	}
+	if req.Symbology == "" {
+		req.Symbology = string(labels.SymbologyCode128)
+	}
+
+	uuids := make([]pgtype.UUID, len(req.Items))
+	var err error
+	for i, str := range req.Items {
+		uuids[i], err = UUIDFromString(str)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*2)
+	defer cancel()
+	found, err := app.DB.Queries.GetItemLabels(ctx, uuids)
+	if err != nil {
+		return err
+	}
+	byUUID := make(map[pgtype.UUID]labels.Label, len(found))
+	for _, item := range found {
+		byUUID[item.Uuid] = itemLabel(item.Uuid, item.Name, item.Barcode)
+	}
+
+	// in the order requested, an item may be printed several times
+	ls := make([]labels.Label, 0, n)
+	for i, uuid := range uuids {
+		l, ok := byUUID[uuid]
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "item "+req.Items[i]+" not found")
+		}
+		ls = append(ls, l)
+	}
+	for _, code := range req.Locations {
+		ls = append(ls, labels.Label{
+			Title:   code,
+			Content: code,
+		})
+	}
+
+	var buf bytes.Buffer
+	err = labels.WriteSheet(&buf, labels.Layout(req.Layout), labels.Symbology(req.Symbology), ls)
+	if err != nil {
+		if errors.Is(err, labels.ErrUnknownLayout) || errors.Is(err, labels.ErrUnknownSymbology) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="labels.pdf"`)
+	return c.Blob(http.StatusOK, MIMEPDF, buf.Bytes())
+}
+
+// itemLabel prefers the first registered barcode of the item, so printed
+// labels can be scanned straight into a transaction, and falls back to UUID.
+func itemLabel(uuid pgtype.UUID, name string, barcode *string) labels.Label {
+	content := uuid.String()
+	if barcode != nil {
+		content = *barcode
+	}
+	return labels.Label{
+		Title:   name,
+		Content: content,
+	}
+}
+
+func renderLabel(c echo.Context, req schemas.GetLabelRequest, content string) error {
+	if req.Symbology == "" {
+		req.Symbology = string(labels.SymbologyCode128)
+	}
+
+	bc, err := labels.Encode(labels.Symbology(req.Symbology), content)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var buf bytes.Buffer
+	switch req.Format {
+	case "", "png":
+		if err := labels.WritePNG(&buf, bc, req.Scale); err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, "image/png", buf.Bytes())
+	case "svg":
+		if err := labels.WriteSVG(&buf, bc, req.Scale); err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, MIMEImageSVG, buf.Bytes())
+	default:
+		return echo.ErrBadRequest
+	}
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemLabels(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleStocker)
+	sku := "HAM-01"
+	hammer, err := app.DB.Queries.CreateItem(ctx, database.CreateItemParams{Name: "hammer", Sku: &sku})
+	require.NoError(t, err)
+	_, err = app.DB.Queries.CreateItemBarcode(ctx, database.CreateItemBarcodeParams{
+		ItemID: hammer.Uuid,
+		Code:   "04006381333931",
+		Format: string(schemas.BarcodeFormatEAN13),
+	})
+	require.NoError(t, err)
+	nails := newTestItem(t, app, "nails")
+
+	e := newTestEcho(app, usr)
+	e.GET("/labels/items/:uuid", app.HandleGetItemLabel)
+	e.GET("/labels/items/by-sku/:sku", app.HandleGetItemLabelBySKU)
+	e.POST("/labels/sheet", app.HandleCreateLabelSheet)
+
+	byUUID := serve(e, http.MethodGet, "/labels/items/"+hammer.Uuid.String()+"?format=svg", "")
+	require.Equal(t, http.StatusOK, byUUID.Code)
+	require.Equal(t, handlers.MIMEImageSVG, byUUID.Header().Get("Content-Type"))
+	bySKU := serve(e, http.MethodGet, "/labels/items/by-sku/HAM-01?format=svg", "")
+	require.Equal(t, http.StatusOK, bySKU.Code)
+	require.Equal(t, byUUID.Body.String(), bySKU.Body.String())
+	require.Equal(t, http.StatusNotFound, serve(e, http.MethodGet, "/labels/items/by-sku/NOPE", "").Code)
+
+	sheet := func(items ...string) int {
+		body := fmt.Sprintf(`{"items": [%q, %q, %q]}`, items[0], items[1], items[2])
+		return serve(e, http.MethodPost, "/labels/sheet", body).Code
+	}
+	require.Equal(t, http.StatusOK, sheet(hammer.Uuid.String(), nails.Uuid.String(), hammer.Uuid.String()))
+	require.Equal(t, http.StatusNotFound, sheet(hammer.Uuid.String(), nails.Uuid.String(), "0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b"))
+}
@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bigelle/warehouse/internal/openapi"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// apiRoute documents a route of router.New, see OpenAPI.
+type apiRoute struct {
+	Method, Path string
+	Summary      string
+	Description  string
+	// RoleUndefined for the routes without a token
+	Role schemas.Role
+	// a struct with query tags
+	Query any
+	// a JSON request body, or Form for a multipart one
+	Body any
+	Form *openapi.Schema
+	// request headers
+	Headers []openapi.Parameter
+	// the status and JSON body of success, or Content for other media types
+	Status   int
+	Response any
+	Content  map[string]*openapi.Schema
+	// response headers of success
+	ResponseHeaders map[string]openapi.Header
+	// besides 400, 401, 403 and 500 which any route may return
+	Errors []int
+	// other responses that aren't errors, e.g. 304
+	Other map[int]string
+}
+
+var (
+	binarySchema = &openapi.Schema{Type: "string", ContentMediaType: "application/octet-stream"}
+	fileForm     = &openapi.Schema{
+		Type:       "object",
+		Required:   []string{"file"},
+		Properties: map[string]*openapi.Schema{"file": binarySchema},
+	}
+	linkHeader = map[string]openapi.Header{
+		"Link": {Description: `next and prev pages, e.g. </items?cursor=...>; rel="next"`, Schema: &openapi.Schema{Type: "string"}},
+	}
+	etagHeader = map[string]openapi.Header{
+		HeaderETag: {Description: "version of the item, for If-Match", Schema: &openapi.Schema{Type: "string"}},
+	}
+	ifMatch = openapi.Parameter{
+		Name:        HeaderIfMatch,
+		In:          "header",
+		Description: "ETag of the item as last seen, or *",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	idempotencyKey = openapi.Parameter{
+		Name:        HeaderIdempotencyKey,
+		In:          "header",
+		Description: "retries with the same key get the response of the first request",
+		Schema:      &openapi.Schema{Type: "string", MaxLength: intPtr(IdempotencyKeyMaxLength)},
+	}
+	exportFormat = openapi.Parameter{
+		Name:        "format",
+		In:          "query",
+		Description: "overrides the Accept header",
+		Schema:      &openapi.Schema{Type: "string", Enum: []any{"csv", "ndjson", "xlsx"}},
+	}
+	eventsParameters = []openapi.Parameter{
+		{
+			Name: HeaderLastEventID, In: "header", Description: "resume after this event",
+			Schema: &openapi.Schema{Type: "integer"},
+		},
+		{
+			Name: "ticket", In: "query", Description: "of POST /events/ticket, for browsers instead of the Authorization header",
+			Schema: &openapi.Schema{Type: "string"},
+		},
+	}
+	exportContent = map[string]*openapi.Schema{
+		MIMETextCSV: {Type: "string"},
+		MIMENDJSON:  {Type: "string"},
+		MIMEXLSX:    binarySchema,
+	}
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func optional(p openapi.Parameter) openapi.Parameter {
+	p.Required = false
+	return p
+}
+
+// apiRoutes has to list every route of router.New, a test there checks it.
+var apiRoutes = []apiRoute{
+	// auth
+	{
+		Method: http.MethodPost, Path: "/auth/register", Summary: "Register a user",
+		Role: schemas.RoleUndefined, Body: schemas.RegisterRequest{},
+		Status: http.StatusOK, Response: schemas.RegisterResponse{}, Errors: []int{409, 422},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/login", Summary: "Log in",
+		Description: "Returns an access token and sets the refresh cookie.",
+		Role:        schemas.RoleUndefined, Body: schemas.LoginRequest{},
+		Status: http.StatusOK, Response: schemas.LoginResponse{}, Errors: []int{401, 422, 429},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/refresh", Summary: "Get a new access token",
+		Description: "Uses the refresh cookie set by the login.",
+		Role:        schemas.RoleUndefined,
+		Status:      http.StatusOK, Response: schemas.LoginResponse{}, Errors: []int{401, 429},
+	},
+
+	// items
+	{
+		Method: http.MethodGet, Path: "/items", Summary: "List and search items",
+		Role: schemas.RoleUser, Query: schemas.GetItemsRequest{},
+		Status: http.StatusOK, Response: schemas.GetItemsResponse{}, ResponseHeaders: linkHeader, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/export", Summary: "Export items",
+		Description: "Streams all the items matching the filters of GET /items as CSV, NDJSON or XLSX.",
+		Role:        schemas.RoleUser, Query: schemas.GetItemsRequest{}, Headers: []openapi.Parameter{exportFormat},
+		Status: http.StatusOK, Content: exportContent, Errors: []int{406},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/:uuid", Summary: "Get an item",
+		Role: schemas.RoleUser,
+		Headers: []openapi.Parameter{{
+			Name: HeaderIfNoneMatch, In: "header", Schema: &openapi.Schema{Type: "string"},
+		}},
+		Status: http.StatusOK, Response: schemas.Item{}, ResponseHeaders: etagHeader, Errors: []int{404},
+		Other: map[int]string{http.StatusNotModified: "the item still matches If-None-Match"},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/by-barcode/:code", Summary: "Find an item by barcode",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.Item{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/:uuid/barcodes", Summary: "List barcodes of an item",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.GetItemBarcodesResponse{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/:uuid/attachments", Summary: "List attachments of an item",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.GetAttachmentsResponse{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/:uuid/transactions", Summary: "List transactions of an item",
+		Role: schemas.RoleUser, Query: schemas.GetAllTransactionsRequest{},
+		Status: http.StatusOK, Response: schemas.GetAllTransactionsResponse{}, ResponseHeaders: linkHeader, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/:uuid/stock", Summary: "Get the stock of an item at a point in time",
+		Role: schemas.RoleUser, Query: schemas.GetItemStockRequest{},
+		Status: http.StatusOK, Response: schemas.ItemStock{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPost, Path: "/items/:uuid/attachments", Summary: "Attach a file to an item",
+		Role: schemas.RoleStocker, Form: fileForm,
+		Status: http.StatusOK, Response: schemas.Attachment{}, Errors: []int{404, 413, 415},
+	},
+	{
+		Method: http.MethodPost, Path: "/items", Summary: "Create an item",
+		Role: schemas.RoleAdmin, Body: schemas.CreateItemRequest{},
+		Status: http.StatusOK, Response: schemas.CreateItemResponse{}, Errors: []int{409, 422},
+	},
+	{
+		Method: http.MethodPost, Path: "/items/import", Summary: "Import items from CSV or XLSX",
+		Description: "Items are matched by SKU, then by name. Large files, or async=true, are imported in the background.",
+		Role:        schemas.RoleAdmin,
+		Form: &openapi.Schema{
+			Type:     "object",
+			Required: []string{"file"},
+			Properties: map[string]*openapi.Schema{
+				"file":    binarySchema,
+				"dry_run": {Type: "boolean"},
+				"async":   {Type: "boolean"},
+				"mapping": {Type: "string", Description: `JSON object of column names to fields, e.g. {"Bezeichnung": "name"}`},
+			},
+		},
+		Status: http.StatusOK, Response: schemas.ImportResult{}, Errors: []int{413, 415, 422},
+		Other: map[int]string{http.StatusAccepted: "an ImportJob, polled at the Location header"},
+	},
+	{
+		Method: http.MethodGet, Path: "/items/import/:uuid", Summary: "Get an import job",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusOK, Response: schemas.ImportJob{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPatch, Path: "/items/:uuid", Summary: "Update an item",
+		Role: schemas.RoleAdmin, Body: schemas.PatchRequest{}, Headers: []openapi.Parameter{ifMatch},
+		Status: http.StatusOK, Response: schemas.Item{}, ResponseHeaders: etagHeader, Errors: []int{404, 409, 412, 422, 428},
+	},
+	{
+		Method: http.MethodDelete, Path: "/items/:uuid", Summary: "Archive an item",
+		Role: schemas.RoleAdmin, Headers: []openapi.Parameter{ifMatch},
+		Status: http.StatusNoContent, Errors: []int{404, 412, 428},
+	},
+	{
+		Method: http.MethodPost, Path: "/items/:uuid/archive", Summary: "Archive an item",
+		Role: schemas.RoleAdmin, Headers: []openapi.Parameter{optional(ifMatch)},
+		Status: http.StatusOK, Response: schemas.Item{}, ResponseHeaders: etagHeader, Errors: []int{404, 412},
+	},
+	{
+		Method: http.MethodPost, Path: "/items/:uuid/unarchive", Summary: "Unarchive an item",
+		Role: schemas.RoleAdmin, Headers: []openapi.Parameter{optional(ifMatch)},
+		Status: http.StatusOK, Response: schemas.Item{}, ResponseHeaders: etagHeader, Errors: []int{404, 412},
+	},
+	{
+		Method: http.MethodDelete, Path: "/items/:uuid/purge", Summary: "Delete an item for good",
+		Description: "Only for archived items without stock history.",
+		Role:        schemas.RoleAdmin, Headers: []openapi.Parameter{ifMatch},
+		Status: http.StatusNoContent, Errors: []int{404, 409, 412, 428},
+	},
+	{
+		Method: http.MethodPost, Path: "/items/:uuid/barcodes", Summary: "Add a barcode to an item",
+		Role: schemas.RoleAdmin, Body: schemas.AddBarcodeRequest{},
+		Status: http.StatusOK, Response: schemas.Barcode{}, Errors: []int{404, 409, 422},
+	},
+	{
+		Method: http.MethodDelete, Path: "/items/:uuid/barcodes/:code", Summary: "Remove a barcode from an item",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusNoContent, Errors: []int{404},
+	},
+
+	// products
+	{
+		Method: http.MethodGet, Path: "/products", Summary: "List products",
+		Role: schemas.RoleUser, Query: schemas.GetProductsRequest{},
+		Status: http.StatusOK, Response: schemas.GetProductsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/products/:uuid", Summary: "Get a product with its variants",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.Product{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPost, Path: "/products", Summary: "Create a product and its variants",
+		Role: schemas.RoleAdmin, Body: schemas.CreateProductRequest{},
+		Status: http.StatusOK, Response: schemas.Product{}, Errors: []int{409, 422},
+	},
+
+	// transactions
+	{
+		Method: http.MethodGet, Path: "/transactions", Summary: "List transactions",
+		Role: schemas.RoleUser, Query: schemas.GetAllTransactionsRequest{},
+		Status: http.StatusOK, Response: schemas.GetAllTransactionsResponse{}, ResponseHeaders: linkHeader,
+	},
+	{
+		Method: http.MethodGet, Path: "/transactions/export", Summary: "Export transactions",
+		Description: "Streams all the transactions matching the filters of GET /transactions as CSV, NDJSON or XLSX.",
+		Role:        schemas.RoleUser, Query: schemas.GetAllTransactionsRequest{}, Headers: []openapi.Parameter{exportFormat},
+		Status: http.StatusOK, Content: exportContent, Errors: []int{406},
+	},
+	{
+		Method: http.MethodGet, Path: "/transactions/:uuid", Summary: "Get a transaction",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.Transaction{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/transactions/:uuid/attachments", Summary: "List attachments of a transaction",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.GetAttachmentsResponse{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions", Summary: "Restock or withdraw an item",
+		Description: "A withdrawal of more than is in stock is recorded as failed and returned with 422.",
+		Role:        schemas.RoleStocker, Body: schemas.CreateTransactionRequest{}, Headers: []openapi.Parameter{idempotencyKey},
+		Status: http.StatusAccepted, Response: schemas.Transaction{}, Errors: []int{404, 409, 422},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions/batch", Summary: "Create several transactions at once",
+		Role: schemas.RoleStocker, Body: schemas.CreateTransactionBatchRequest{}, Headers: []openapi.Parameter{idempotencyKey},
+		Status: http.StatusAccepted, Response: schemas.CreateTransactionBatchResponse{}, Errors: []int{404, 409, 422},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions/:uuid/attachments", Summary: "Attach a file to a transaction",
+		Role: schemas.RoleStocker, Form: fileForm,
+		Status: http.StatusOK, Response: schemas.Attachment{}, Errors: []int{404, 413, 415},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions/:uuid/reverse", Summary: "Reverse a transaction",
+		Description: "Records a compensating transaction, the original one is kept.",
+		Role:        schemas.RoleAdmin, Body: schemas.ReverseTransactionRequest{},
+		Status: http.StatusAccepted, Response: schemas.ReverseTransactionResponse{}, Errors: []int{404, 409, 422},
+	},
+
+	// users
+	{
+		Method: http.MethodGet, Path: "/users/:uuid/transactions", Summary: "List transactions of a user",
+		Role: schemas.RoleUser, Query: schemas.GetAllTransactionsRequest{},
+		Status: http.StatusOK, Response: schemas.GetAllTransactionsResponse{}, ResponseHeaders: linkHeader, Errors: []int{404},
+	},
+
+	// attachments
+	{
+		Method: http.MethodGet, Path: "/attachments/:uuid", Summary: "Download an attachment",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{"*/*": binarySchema}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/attachments/:uuid/thumbnail", Summary: "Download the thumbnail of an image attachment",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{"image/png": binarySchema}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodDelete, Path: "/attachments/:uuid", Summary: "Delete an attachment",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusNoContent, Errors: []int{404},
+	},
+
+	// labels
+	{
+		Method: http.MethodGet, Path: "/labels/items/:uuid", Summary: "Render the label of an item",
+		Role: schemas.RoleUser, Query: schemas.GetLabelRequest{},
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{"image/png": binarySchema, MIMEImageSVG: {Type: "string"}}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/labels/items/by-sku/:sku", Summary: "Render the label of an item found by SKU",
+		Role: schemas.RoleUser, Query: schemas.GetLabelRequest{},
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{"image/png": binarySchema, MIMEImageSVG: {Type: "string"}}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/labels/locations/:code", Summary: "Render the label of a location",
+		Role: schemas.RoleUser, Query: schemas.GetLabelRequest{},
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{"image/png": binarySchema, MIMEImageSVG: {Type: "string"}},
+	},
+	{
+		Method: http.MethodPost, Path: "/labels/sheet", Summary: "Render a printable sheet of labels",
+		Role: schemas.RoleStocker, Body: schemas.LabelSheetRequest{},
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{MIMEPDF: binarySchema}, Errors: []int{404, 422},
+	},
+
+	// graphql
+	{
+		Method: http.MethodPost, Path: "/graphql", Summary: "Query items, transactions and users with GraphQL",
+		Description: "Items with their recent transactions and their owners in one round-trip. " +
+			"Errors of the query come with 200 in the errors of the body, with the problem as their extensions. " +
+			"Queries can't be nested deeper than 8 fields or cost more than 5000, " +
+			"where fields with a limit argument cost their limit times what's under them.",
+		Role: schemas.RoleUser, Body: schemas.GraphQLRequest{},
+		Status: http.StatusOK, Response: schemas.GraphQLResponse{}, Errors: []int{429},
+	},
+
+	// events
+	{
+		Method: http.MethodPost, Path: "/events/ticket", Summary: "Get a ticket to open the streams with",
+		Description: "Browsers can't set the Authorization header of EventSource and WebSocket, " +
+			"the ticket goes in their ticket parameter instead of the access token. It expires in 30 seconds.",
+		Role:   schemas.RoleUser,
+		Status: http.StatusOK, Response: schemas.EventsTicketResponse{}, Errors: []int{429},
+	},
+	{
+		Method: http.MethodGet, Path: "/events", Summary: "Stream stock changes as Server-Sent Events",
+		Description: "Each event is sent with its id, its type as the event name and an Event as JSON data. " +
+			"Only new events are sent unless Last-Event-ID is given, events are kept for a week.",
+		Role: schemas.RoleUser, Query: schemas.EventsRequest{}, Headers: eventsParameters,
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{MIMEEventStream: {Type: "string"}}, Errors: []int{429, 503},
+	},
+	{
+		Method: http.MethodGet, Path: "/events/ws", Summary: "Stream stock changes over a WebSocket",
+		Description: "The same events as GET /events, each an Event as a JSON text message. " +
+			"The connection is closed with 1013 if the client falls behind, it may resume with last_event_id.",
+		Role: schemas.RoleUser, Query: schemas.EventsRequest{}, Headers: eventsParameters,
+		Status: http.StatusSwitchingProtocols, Errors: []int{429, 503},
+	},
+
+	// webhooks
+	{
+		Method: http.MethodPost, Path: "/webhooks", Summary: "Subscribe a URL to events",
+		Description: "The events of GET /events are posted to the URL as JSON, signed in " + HeaderWebhookSignature +
+			" with sha256= and the hex HMAC-SHA256 of " + HeaderWebhookTimestamp + ", a dot and the body, keyed with the secret. " +
+			"Any answer but 2xx is retried with exponential backoff, up to 10 attempts, " +
+			"and the webhook is disabled after 20 failed attempts in a row. The secret is only returned here.",
+		Role: schemas.RoleAdmin, Body: schemas.CreateWebhookRequest{},
+		Status: http.StatusOK, Response: schemas.Webhook{},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks", Summary: "List webhooks",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusOK, Response: schemas.GetWebhooksResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/:uuid", Summary: "Get a webhook",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusOK, Response: schemas.Webhook{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPatch, Path: "/webhooks/:uuid", Summary: "Update a webhook",
+		Description: "Enabling it again resets its failures, the pending deliveries are attempted again.",
+		Role:        schemas.RoleAdmin, Body: schemas.PatchWebhookRequest{},
+		Status: http.StatusOK, Response: schemas.Webhook{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodDelete, Path: "/webhooks/:uuid", Summary: "Delete a webhook and its deliveries",
+		Role:   schemas.RoleAdmin,
+		Status: http.StatusNoContent, Errors: []int{404},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/:uuid/deliveries", Summary: "List deliveries of a webhook",
+		Description: "The newest first, finished ones are kept for a week.",
+		Role:        schemas.RoleAdmin, Query: schemas.GetWebhookDeliveriesRequest{},
+		Status: http.StatusOK, Response: schemas.GetWebhookDeliveriesResponse{}, Errors: []int{404},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks/:uuid/deliveries/:delivery/replay", Summary: "Send a delivery again",
+		Description: "Queues a new delivery of the same event.",
+		Role:        schemas.RoleAdmin,
+		Status:      http.StatusAccepted, Response: schemas.WebhookDelivery{}, Errors: []int{404},
+	},
+
+	// docs
+	{
+		Method: http.MethodGet, Path: "/openapi.json", Summary: "This document",
+		Role:   schemas.RoleUndefined,
+		Status: http.StatusOK, Content: map[string]*openapi.Schema{echo.MIMEApplicationJSON: {Type: "object"}},
+	},
+}
+
+// OpenAPI describes the API, the schemas come from the structs in
+// pkg/schemas.
+func OpenAPI() *openapi.Document {
+	g := openapi.New(openapi.Info{
+		Title:   "Warehouse API",
+		Version: "1.0.0",
+		Description: "Errors are RFC 7807 problems served as application/problem+json. " +
+			"Roles are ordered: user < stocker < admin, a route allows its role and the ones above.",
+	})
+	g.SecurityScheme("bearer", openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "the access token of POST /auth/login",
+	})
+
+	g.Override(schemas.Role(0), enumSchema("user", "stocker", "admin"))
+	g.Override(schemas.TransactionType(""), enumSchema(schemas.TransactionTypeRestock, schemas.TransactionTypeWithdraw))
+	g.Override(schemas.TransactionStatus(""), enumSchema(
+		schemas.TransactionStatusSucceeded, schemas.TransactionStatusFailed, schemas.TransactionStatusReversed,
+	))
+	g.Override(schemas.BatchMode(""), enumSchema(schemas.BatchModeAtomic, schemas.BatchModeBestEffort))
+	g.Override(schemas.BarcodeFormat(""), enumSchema(
+		schemas.BarcodeFormatEAN8, schemas.BarcodeFormatUPCA, schemas.BarcodeFormatEAN13,
+		schemas.BarcodeFormatGTIN14, schemas.BarcodeFormatCode128,
+	))
+	g.Override(schemas.ImportAction(""), enumSchema(
+		schemas.ImportActionCreated, schemas.ImportActionUpdated, schemas.ImportActionUnchanged,
+	))
+	g.Override(schemas.ImportJobStatus(""), enumSchema(
+		schemas.ImportJobStatusPending, schemas.ImportJobStatusRunning,
+		schemas.ImportJobStatusSucceeded, schemas.ImportJobStatusFailed,
+	))
+	g.Override(schemas.EventType(""), enumSchema(
+		schemas.EventItemCreated, schemas.EventItemUpdated, schemas.EventItemDeleted, schemas.EventTransactionCreated,
+	))
+	g.Override(schemas.WebhookDeliveryStatus(""), enumSchema(
+		schemas.WebhookDeliveryPending, schemas.WebhookDeliverySucceeded, schemas.WebhookDeliveryFailed,
+	))
+	problem := g.Schema(schemas.Problem{})
+
+	for _, r := range apiRoutes {
+		tag, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+		tag = strings.TrimSuffix(tag, ".json")
+		op := &openapi.Operation{
+			OperationID: operationID(r.Method, r.Path),
+			Summary:     r.Summary,
+			Description: r.Description,
+			Tags:        []string{tag},
+			Responses:   map[string]*openapi.Response{},
+		}
+		if r.Role != schemas.RoleUndefined {
+			op.Security = []openapi.SecurityRequirement{{"bearer": {}}}
+			if r.Role > schemas.RoleUser {
+				op.Description = strings.TrimSpace("Requires the " + r.Role.String() + " role. " + op.Description)
+			}
+		}
+
+		op.Parameters = append(op.Parameters, r.Headers...)
+		if r.Query != nil {
+			op.Parameters = append(op.Parameters, g.QueryParameters(r.Query)...)
+		}
+		switch {
+		case r.Body != nil:
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{echo.MIMEApplicationJSON: {Schema: g.Schema(r.Body)}},
+			}
+		case r.Form != nil:
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{echo.MIMEMultipartForm: {Schema: r.Form}},
+			}
+		}
+
+		ok := &openapi.Response{Description: http.StatusText(r.Status), Headers: r.ResponseHeaders}
+		switch {
+		case r.Response != nil:
+			ok.Content = map[string]openapi.MediaType{echo.MIMEApplicationJSON: {Schema: g.Schema(r.Response)}}
+		case r.Content != nil:
+			ok.Content = map[string]openapi.MediaType{}
+			for mime, s := range r.Content {
+				ok.Content[mime] = openapi.MediaType{Schema: s}
+			}
+		}
+		op.Responses[strconv.Itoa(r.Status)] = ok
+		for status, desc := range r.Other {
+			op.Responses[strconv.Itoa(status)] = &openapi.Response{Description: desc}
+		}
+		for _, status := range r.Errors {
+			op.Responses[strconv.Itoa(status)] = &openapi.Response{
+				Description: http.StatusText(status),
+				Content:     map[string]openapi.MediaType{MIMEProblemJSON: {Schema: problem}},
+			}
+		}
+		op.Responses["default"] = &openapi.Response{
+			Description: "an error",
+			Content:     map[string]openapi.MediaType{MIMEProblemJSON: {Schema: problem}},
+		}
+
+		g.Add(r.Method, r.Path, op)
+	}
+	return g.Document()
+}
+
+func enumSchema[T ~string](values ...T) *openapi.Schema {
+	s := &openapi.Schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, string(v))
+	}
+	return s
+}
+
+// operationID is e.g. getItemsUuidBarcodes for GET /items/:uuid/barcodes.
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '.' || r == '_'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(OpenAPI())
+})
+
+func (app App) HandleOpenAPI(c echo.Context) error {
+	b, err := openAPIJSON()
+	if err != nil {
+		return err
+	}
+	return c.JSONBlob(http.StatusOK, b)
+}
+
+// swaggerUIPage loads the bundled Swagger UI, nothing comes from a CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Warehouse API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+  <link rel="icon" type="image/png" href="/docs/favicon-32x32.png">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script src="/docs/swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "/openapi.json",
+      dom_id: "#swagger-ui",
+      presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+      layout: "StandaloneLayout",
+    });
+  </script>
+</body>
+</html>
+`
+
+func (app App) HandleSwaggerUI(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
+
+var swaggerUIAssets = echo.WrapHandler(http.StripPrefix("/docs/", http.FileServerFS(swaggerFiles.FS)))
+
+// HandleSwaggerUIAssets serves the files of Swagger UI under /docs/, its own
+// index.html points to the petstore example.
+func (app App) HandleSwaggerUIAssets(c echo.Context) error {
+	switch c.Param("*") {
+	case "", "index.html", "swagger-initializer.js":
+		return c.Redirect(http.StatusMovedPermanently, "/docs")
+	}
+	return swaggerUIAssets(c)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor packs the position of a page into an opaque string.
+func EncodeCursor(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func DecodeCursor(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// setPageLinks adds a Link header pointing to the neighbouring pages, the
+// rest of the query stays as it was.
+func setPageLinks(c echo.Context, next, prev string) {
+	var links []string
+	for _, l := range []struct{ cursor, rel string }{{next, "next"}, {prev, "prev"}} {
+		if l.cursor == "" {
+			continue
+		}
+		u := *c.Request().URL
+		q := u.Query()
+		q.Del("offset")
+		q.Set("cursor", l.cursor)
+		u.RawQuery = q.Encode()
+		links = append(links, "<"+u.RequestURI()+`>; rel="`+l.rel+`"`)
+	}
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// itemsCursor keeps the sort keys of the first or the last item of a page.
+type itemsCursor struct {
+	// the cursor is only valid for the same sort and filters
+	Filters string `json:"f"`
+
+	ID       int64   `json:"id"`
+	Name     string  `json:"n,omitempty"`
+	Sku      *string `json:"k,omitempty"`
+	Quantity int32   `json:"qt,omitempty"`
+	// unix microseconds, the precision of postgres
+	CreatedAt  int64   `json:"c,omitempty"`
+	UpdatedAt  int64   `json:"u,omitempty"`
+	Rank       float32 `json:"r,omitempty"`
+	Similarity float32 `json:"m,omitempty"`
+	Backward   bool    `json:"b,omitempty"`
+}
+
+func newItemsCursor(row database.SearchItemsRow, filters string, backward bool) itemsCursor {
+	return itemsCursor{
+		Filters:    filters,
+		ID:         row.ID,
+		Name:       row.Name,
+		Sku:        row.Sku,
+		Quantity:   row.Quantity,
+		CreatedAt:  row.CreatedAt.Time.UnixMicro(),
+		UpdatedAt:  row.UpdatedAt.Time.UnixMicro(),
+		Rank:       row.Rank,
+		Similarity: row.Similarity,
+		Backward:   backward,
+	}
+}
+
+// itemsFilters hashes everything of the request that selects or orders the
+// items, pagination aside.
+func itemsFilters(req schemas.GetItemsRequest) string {
+	req.Limit, req.Offset, req.Cursor = 0, 0, ""
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func (cur itemsCursor) keyset() *database.ItemsKeyset {
+	return &database.ItemsKeyset{
+		ID:         cur.ID,
+		Name:       cur.Name,
+		Sku:        cur.Sku,
+		Quantity:   cur.Quantity,
+		CreatedAt:  time.UnixMicro(cur.CreatedAt),
+		UpdatedAt:  time.UnixMicro(cur.UpdatedAt),
+		Rank:       cur.Rank,
+		Similarity: cur.Similarity,
+		Backward:   cur.Backward,
+	}
+}
+
+type transactionsCursor struct {
+	// unix microseconds
+	CreatedAt int64  `json:"c"`
+	ID        string `json:"id"`
+	Backward  bool   `json:"b,omitempty"`
+}
+
+// trimPage drops the extra row fetched to find out whether there is one more
+// page in the direction of reading.
+func trimPage[T any](rows []T, limit int, backward bool) ([]T, bool) {
+	if len(rows) <= limit {
+		return rows, false
+	}
+	if backward {
+		return rows[len(rows)-limit:], true
+	}
+	return rows[:limit], true
+}
@@ -0,0 +1,27 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	type position struct {
+		ID   int64  `json:"id"`
+		Name string `json:"n"`
+	}
+
+	s, err := handlers.EncodeCursor(position{ID: 42, Name: "Glove (L, black)"})
+	require.NoError(t, err)
+	require.NotContains(t, s, "=") // has to be safe in a query string
+
+	var got position
+	require.NoError(t, handlers.DecodeCursor(s, &got))
+	require.Equal(t, position{ID: 42, Name: "Glove (L, black)"}, got)
+
+	// expect errors:
+	require.ErrorIs(t, handlers.DecodeCursor("not a cursor!", &got), handlers.ErrInvalidCursor)
+	require.ErrorIs(t, handlers.DecodeCursor("bm90IGpzb24", &got), handlers.ErrInvalidCursor)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (app App) HandleCreateProduct(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.CreateProductRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	if err := CheckProductOptions(req.Options); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	matrix := VariantMatrix(req.Options)
+
+	options, err := json.Marshal(req.Options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*3)
+	defer cancel()
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := app.DB.Queries.WithTx(tx)
+
+	product, err := q.CreateProduct(ctx, database.CreateProductParams{
+		Name:    req.Name,
+		Sku:     PtrFromString(req.SKU),
+		Options: options,
+	})
+	if err != nil {
+		return err
+	}
+
+	skuPrefix := req.SKU
+	if skuPrefix == "" {
+		skuPrefix = req.Name
+	}
+
+	variants := make([]schemas.Item, len(matrix))
+	for i, values := range matrix {
+		opts := make(map[string]string, len(values))
+		for j, v := range values {
+			opts[req.Options[j].Name] = v
+		}
+		optsJSON, err := json.Marshal(opts)
+		if err != nil {
+			return err
+		}
+
+		sku := VariantSKU(skuPrefix, values)
+		v, err := q.CreateVariantItem(ctx, database.CreateVariantItemParams{
+			Name:           VariantName(req.Name, values),
+			Sku:            &sku,
+			ProductID:      product.Uuid,
+			VariantOptions: optsJSON,
+		})
+		if err != nil {
+			return err
+		}
+		variants[i] = schemas.Item{
+			UUID:        v.Uuid.String(),
+			Name:        v.Name,
+			SKU:         StringFromPtr(v.Sku),
+			Quantity:    int(v.Quantity),
+			ProductUUID: v.ProductID.String(),
+			Options:     opts,
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, schemas.Product{
+		UUID:      product.Uuid.String(),
+		Name:      product.Name,
+		SKU:       StringFromPtr(product.Sku),
+		Options:   req.Options,
+		NVariants: len(variants),
+		Quantity:  0,
+		Variants:  variants,
+		CreatedAt: product.CreatedAt.Time.Unix(),
+	})
+}
+
+func (app App) HandleGetProducts(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.GetProductsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Limit == 0 {
+		req.Limit = schemas.GetProductsRequestDefaultLimit
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*2)
+	defer cancel()
+	found, err := app.DB.Queries.GetNProductsOffset(ctx, database.GetNProductsOffsetParams{
+		Limit:  int32(req.Limit),
+		Offset: int32(req.Offset),
+	})
+	if err != nil {
+		app.Logger.Error("getting rows", zap.Error(err))
+		return err
+	}
+
+	nFound := len(found)
+	if nFound == 0 {
+		return echo.ErrNotFound
+	}
+
+	ids := make([]pgtype.UUID, nFound)
+	products := make([]schemas.Product, nFound)
+	for i := range nFound {
+		ids[i] = found[i].Uuid
+		products[i] = schemas.Product{
+			UUID:      found[i].Uuid.String(),
+			Name:      found[i].Name,
+			SKU:       StringFromPtr(found[i].Sku),
+			Options:   productOptionsFromJSON(found[i].Options),
+			Variants:  []schemas.Item{},
+			CreatedAt: found[i].CreatedAt.Time.Unix(),
+		}
+	}
+
+	variants, err := app.DB.Queries.GetProductVariants(ctx, ids)
+	if err != nil {
+		app.Logger.Error("getting variants", zap.Error(err))
+		return err
+	}
+	groupVariants(products, variants)
+
+	return c.JSON(http.StatusOK, schemas.GetProductsResponse{
+		NResults: nFound,
+		Products: products,
+	})
+}
+
+func (app App) HandleGetSingleProduct(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*2)
+	defer cancel()
+	found, err := app.DB.Queries.GetProduct(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	variants, err := app.DB.Queries.GetProductVariants(ctx, []pgtype.UUID{uuid})
+	if err != nil {
+		return err
+	}
+
+	products := []schemas.Product{{
+		UUID:      found.Uuid.String(),
+		Name:      found.Name,
+		SKU:       StringFromPtr(found.Sku),
+		Options:   productOptionsFromJSON(found.Options),
+		Variants:  []schemas.Item{},
+		CreatedAt: found.CreatedAt.Time.Unix(),
+	}}
+	groupVariants(products, variants)
+
+	return c.JSON(http.StatusOK, products[0])
+}
+
+// groupVariants puts every variant under its parent and sums up the stock.
+func groupVariants(products []schemas.Product, variants []database.GetProductVariantsRow) {
+	idx := make(map[string]int, len(products))
+	for i := range products {
+		idx[products[i].UUID] = i
+	}
+
+	for _, v := range variants {
+		i, ok := idx[v.ProductID.String()]
+		if !ok {
+			continue
+		}
+		p := &products[i]
+		p.Variants = append(p.Variants, schemas.Item{
+			UUID:        v.Uuid.String(),
+			Name:        v.Name,
+			SKU:         StringFromPtr(v.Sku),
+			Quantity:    int(v.Quantity),
+			ProductUUID: v.ProductID.String(),
+			Options:     VariantOptionsFromJSON(v.VariantOptions),
+		})
+		p.NVariants++
+		p.Quantity += int(v.Quantity)
+	}
+}
+
+func productOptionsFromJSON(b []byte) []schemas.ProductOption {
+	var opts []schemas.ProductOption
+	if err := json.Unmarshal(b, &opts); err != nil {
+		return nil
+	}
+	return opts
+}
@@ -0,0 +1,61 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductStock(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	e := newTestEcho(app, newTestUser(t, app, schemas.RoleAdmin))
+	e.POST("/products", app.HandleCreateProduct)
+	e.GET("/products/:uuid", app.HandleGetSingleProduct)
+	e.POST("/items/:uuid/archive", app.HandleArchiveItem)
+	e.POST("/transactions", app.HandleCreateTransaction)
+
+	rec := serve(e, http.MethodPost, "/products", `{"name": "Glove", "options": [{"name": "size", "values": ["S", "M"]}]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var product schemas.Product
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &product))
+	require.Len(t, product.Variants, 2)
+	small, medium := product.Variants[0], product.Variants[1]
+
+	for _, restock := range []struct {
+		item   string
+		amount int
+	}{{small.UUID, 3}, {medium.UUID, 5}} {
+		body := fmt.Sprintf(`{"type": "restock", "item_uuid": %q, "amount": %d}`, restock.item, restock.amount)
+		require.Equal(t, http.StatusAccepted, serve(e, http.MethodPost, "/transactions", body).Code)
+	}
+
+	// the archived variant keeps its stock but isn't counted
+	require.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/items/"+small.UUID+"/archive", "").Code)
+
+	rec = serve(e, http.MethodGet, "/products/"+product.UUID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &product))
+	require.Equal(t, 1, product.NVariants)
+	require.Equal(t, 5, product.Quantity)
+	require.Len(t, product.Variants, 1)
+	require.Equal(t, medium.UUID, product.Variants[0].UUID)
+
+	res, err := app.GetItems(ctx, schemas.GetItemsRequest{Archived: "include", Name: "Glove"})
+	require.NoError(t, err)
+	require.Equal(t, 2, res.NResults)
+	require.Equal(t, []schemas.ProductStock{{UUID: product.UUID, Name: "Glove", NVariants: 1, Quantity: 5}}, res.Products)
+
+	smallID, err := handlers.UUIDFromString(small.UUID)
+	require.NoError(t, err)
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, smallID)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, qty.Quantity)
+}
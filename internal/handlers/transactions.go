@@ -3,17 +3,27 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/bigelle/warehouse/internal/database"
-	"github.com/bigelle/warehouse/internal/schemas"
+	"github.com/bigelle/warehouse/pkg/schemas"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
-const (
-	NotEnoughItemsMessage = "attempt to output a quantity of items exceeding their actual quantity"
+const NotEnoughItemsMessage = "attempt to output a quantity of items exceeding their actual quantity"
+
+var (
+	ErrNotEnoughItems = errors.New(NotEnoughItemsMessage)
+	ErrItemArchived   = errors.New("item is archived")
+	// wrapped with the reason
+	ErrNotReversible = errors.New("transaction can't be reversed")
 )
 
 func (app App) HandleCreateTransaction(c echo.Context) error {
@@ -35,15 +45,84 @@ func (app App) HandleCreateTransaction(c echo.Context) error {
 	if err = c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
+	if err = c.Validate(&req); err != nil {
+		return err
+	}
 
-	itemUUID, err := UUIDFromString(req.ItemUUID)
+	tr, err := app.CreateTransaction(c.Request().Context(), uuid, req)
+	if errors.Is(err, ErrNotEnoughItems) {
+		// still an error status, so that clients can't take it for a withdrawal
+		return c.JSON(http.StatusUnprocessableEntity, tr)
+	}
 	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, tr)
+}
+
+// CreateTransaction restocks or withdraws on behalf of the user. A failed
+// withdrawal is recorded as well and returned along with ErrNotEnoughItems.
+func (app App) CreateTransaction(ctx context.Context, userUUID pgtype.UUID, req schemas.CreateTransactionRequest) (schemas.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase*4) // 4 as in 4 requests per TX
+	defer cancel()
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return schemas.Transaction{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := app.DB.Queries.WithTx(tx)
+
+	itemUUID, err := resolveTransactionItem(ctx, q, req)
+	if err != nil {
+		return schemas.Transaction{}, err
+	}
+
+	tr, err := app.applyTransaction(ctx, q, userUUID, itemUUID, req)
+	if err != nil && !errors.Is(err, ErrNotEnoughItems) {
+		return schemas.Transaction{}, err
+	}
+
+	// the failed attempt is committed as well, for the audit trail
+	if err := commitTx(ctx, tx); err != nil {
+		return schemas.Transaction{}, err // what do I do here?
+	}
+	return tr, err
+}
+
+// HandleCreateTransactionBatch runs many transactions in one database
+// transaction. In the atomic mode the first failed line rolls back the whole
+// batch, in the best effort one every line gets its own result.
+func (app App) HandleCreateTransactionBatch(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleStocker) {
+		return echo.ErrForbidden
+	}
+
+	uuidStr, ok := c.Get("userID").(string)
+	if uuidStr == "" || !ok {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(uuidStr)
+	if err != nil {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.CreateTransactionBatchRequest
+	if err = c.Bind(&req); err != nil {
 		return echo.ErrBadRequest
 	}
+	if err = c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Mode == "" {
+		req.Mode = schemas.BatchModeAtomic
+	}
+	atomic := req.Mode == schemas.BatchModeAtomic
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*3) // 3 as in 3 requests per TX
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*time.Duration(4+len(req.Lines)))
 	defer cancel()
-	tx, err := app.DB.Conn.Begin(ctx)
+	tx, err := app.DB.Pool.Begin(ctx)
 	if err != nil {
 		app.Logger.Error("error starting transaction", zap.Error(err))
 		return err
@@ -51,94 +130,236 @@ func (app App) HandleCreateTransaction(c echo.Context) error {
 	defer tx.Rollback(ctx)
 	q := app.DB.Queries.WithTx(tx)
 
-	qty, err := q.GetItemQuantity(ctx, itemUUID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
+	itemUUIDs := make([]pgtype.UUID, len(req.Lines))
+	resolveErrs := make([]error, len(req.Lines))
+	found := make([]pgtype.UUID, 0, len(req.Lines))
+	for i, line := range req.Lines {
+		itemUUIDs[i], resolveErrs[i] = resolveTransactionItem(ctx, q, line)
+		if resolveErrs[i] != nil {
+			if atomic {
+				return batchLineError(i, resolveErrs[i])
+			}
+			continue
 		}
-		app.Logger.Error("error getting quantity", zap.Error(err))
+		found = append(found, itemUUIDs[i])
+	}
+
+	// Locking all the items up front and in the same order, otherwise two
+	// batches touching the same items could deadlock.
+	if _, err := q.LockItems(ctx, found); err != nil {
+		app.Logger.Error("error locking items", zap.Error(err))
 		return err
 	}
 
-	var tr database.CreateNewTransactionRow
-	switch req.Type {
-	case schemas.TransactionTypeRestock:
-		err = q.SetItemQuantity(ctx, database.SetItemQuantityParams{
-			Uuid:     itemUUID,
-			Quantity: qty.Quantity + int32(req.Amount),
-		})
-		if err != nil {
-			tx.Rollback(ctx)
-			app.Logger.Error("error setting quantity", zap.Error(err))
-			return err
-		}
-		tr, err = q.CreateNewTransaction(ctx, database.CreateNewTransactionParams{
-			UserID: uuid,
-			ItemID: itemUUID,
-			Type:   string(req.Type),
-			Amount: int32(req.Amount),
-			Status: string(schemas.TransactionStatusSucceeded),
-		})
-		if err != nil {
-			tx.Rollback(ctx)
-			app.Logger.Error("error creating transaction", zap.Error(err))
-			return err
+	res := schemas.CreateTransactionBatchResponse{
+		Mode:  req.Mode,
+		Lines: make([]schemas.TransactionBatchLine, len(req.Lines)),
+	}
+	for i, line := range req.Lines {
+		err := resolveErrs[i]
+		var tr schemas.Transaction
+		if err == nil {
+			if atomic {
+				tr, err = app.applyTransaction(ctx, q, uuid, itemUUIDs[i], line)
+				if err != nil {
+					return batchLineError(i, err)
+				}
+			} else {
+				tr, err = app.applyTransactionSavepoint(ctx, tx, uuid, itemUUIDs[i], line)
+				if errors.Is(err, context.DeadlineExceeded) {
+					return err
+				}
+			}
 		}
-	case schemas.TransactionTypeWithdraw:
-		if qty.Quantity-int32(req.Amount) < 0 {
-			msg := NotEnoughItemsMessage
-			tr, err = q.CreateNewTransaction(ctx, database.CreateNewTransactionParams{
-				UserID: uuid,
-				ItemID: itemUUID,
-				Type:   string(req.Type),
-				Amount: int32(req.Amount),
-				Status: string(schemas.TransactionStatusFailed),
-				Reason: &msg,
-			})
-			if err != nil {
-				tx.Rollback(ctx)
-				app.Logger.Error("error creating transaction", zap.Error(err))
-				return err
+
+		result := &res.Lines[i]
+		result.Index = i
+		switch {
+		case err == nil:
+			result.Status = http.StatusAccepted
+			result.Transaction = &tr
+			res.NSucceeded++
+		case errors.Is(err, ErrNotEnoughItems):
+			result.Status = http.StatusUnprocessableEntity
+			result.Transaction = &tr
+			res.NFailed++
+		default:
+			p := ProblemFromError(err)
+			if p.Status >= http.StatusInternalServerError {
+				app.Logger.Error("error in batch line", zap.Int("line", i), zap.Error(err))
 			}
-			// FIXME: send the reason in json response
-			return echo.NewHTTPError(http.StatusBadRequest, msg)
+			result.Status = p.Status
+			result.Error = &p
+			res.NFailed++
 		}
-		err := q.SetItemQuantity(ctx, database.SetItemQuantityParams{
-			Uuid:     itemUUID,
-			Quantity: qty.Quantity + int32(req.Amount),
-		})
+	}
+
+	if err := commitTx(ctx, tx); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, res)
+}
+
+// resolveTransactionItem finds the item of the transaction, either by its
+// uuid or by a scanned barcode.
+func resolveTransactionItem(ctx context.Context, q *database.Queries, req schemas.CreateTransactionRequest) (pgtype.UUID, error) {
+	if req.ItemUUID != "" {
+		itemUUID, err := UUIDFromString(req.ItemUUID)
 		if err != nil {
-			tx.Rollback(ctx)
-			app.Logger.Error("error setting quantity", zap.Error(err))
-			return err
+			return pgtype.UUID{}, echo.ErrBadRequest
 		}
-		tr, err = q.CreateNewTransaction(ctx, database.CreateNewTransactionParams{
-			UserID: uuid,
-			ItemID: itemUUID,
-			Type:   string(req.Type),
-			Amount: int32(req.Amount),
-			Status: string(schemas.TransactionStatusSucceeded),
-		})
-		if err != nil {
-			tx.Rollback(ctx)
-			app.Logger.Error("error creating transaction", zap.Error(err))
-			return err
+		return itemUUID, nil
+	}
+
+	// scanned shelf label
+	itemUUID, err := q.GetItemUUIDByBarcode(ctx, BarcodeLookupCodes(req.Barcode))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, echo.ErrNotFound
 		}
+		return pgtype.UUID{}, err
 	}
+	return itemUUID, nil
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return err // what do I do here?
+// applyTransaction changes the stock and records the transaction. When
+// there's not enough items the failed attempt is recorded instead and
+// returned along with ErrNotEnoughItems.
+func (app App) applyTransaction(ctx context.Context, q *database.Queries, userUUID, itemUUID pgtype.UUID, req schemas.CreateTransactionRequest) (schemas.Transaction, error) {
+	status := schemas.TransactionStatusSucceeded
+	var reason *string
+	err := app.changeStock(ctx, q, itemUUID, req.Type, req.Amount)
+	if errors.Is(err, ErrNotEnoughItems) {
+		msg := NotEnoughItemsMessage
+		status = schemas.TransactionStatusFailed
+		reason = &msg
+	} else if err != nil {
+		return schemas.Transaction{}, err
 	}
 
-	return c.JSON(http.StatusAccepted, schemas.Transaction{
+	tr, err := q.CreateNewTransaction(ctx, database.CreateNewTransactionParams{
+		UserID: userUUID,
+		ItemID: itemUUID,
+		Type:   string(req.Type),
+		Amount: int32(req.Amount),
+		Status: string(status),
+		Reason: reason,
+	})
+	if err != nil {
+		app.Logger.Error("error creating transaction", zap.Error(err))
+		return schemas.Transaction{}, err
+	}
+	if reason == nil {
+		if err := app.recordMovement(ctx, q, itemUUID, req.Type, req.Amount, tr.ID, userUUID); err != nil {
+			return schemas.Transaction{}, err
+		}
+	}
+
+	res := schemas.Transaction{
 		UUID:      tr.ID.String(),
 		Type:      req.Type,
-		OwnerUUID: uuidStr,
-		ItemUUID:  req.ItemUUID,
+		OwnerUUID: userUUID.String(),
+		ItemUUID:  itemUUID.String(),
 		Amount:    req.Amount,
-		Status:    schemas.TransactionStatusSucceeded,
+		Status:    status,
 		CreatedAt: tr.CreatedAt.Time.Unix(),
+	}
+	if reason != nil {
+		res.Reason = *reason
+		return res, ErrNotEnoughItems
+	}
+	return res, nil
+}
+
+// changeStock restocks or withdraws in a single conditional update: the
+// row stays locked until commit and concurrent withdrawals can't take the
+// same items twice.
+func (app App) changeStock(ctx context.Context, q *database.Queries, itemUUID pgtype.UUID, typ schemas.TransactionType, amount int) error {
+	var err error
+	switch typ {
+	case schemas.TransactionTypeRestock:
+		_, err = q.RestockItem(ctx, database.RestockItemParams{
+			Amount: int32(amount),
+			Uuid:   itemUUID,
+		})
+	case schemas.TransactionTypeWithdraw:
+		_, err = q.WithdrawItem(ctx, database.WithdrawItemParams{
+			Amount: int32(amount),
+			Uuid:   itemUUID,
+		})
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// nothing was updated, finding out why
+		qty, err := q.GetItemQuantity(ctx, itemUUID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return echo.ErrNotFound
+			}
+			app.Logger.Error("error getting quantity", zap.Error(err))
+			return err
+		}
+		if qty.ArchivedAt.Valid {
+			return ErrItemArchived
+		}
+		return ErrNotEnoughItems
+	}
+	if err != nil {
+		app.Logger.Error("error changing quantity", zap.Error(err))
+	}
+	return err
+}
+
+// recordMovement appends the change to the stock ledger, items.quantity is
+// only a projection of it and has to be changed in the same transaction.
+func (app App) recordMovement(ctx context.Context, q *database.Queries, itemUUID pgtype.UUID, typ schemas.TransactionType, amount int, trUUID, userUUID pgtype.UUID) error {
+	delta := int32(amount)
+	if typ == schemas.TransactionTypeWithdraw {
+		delta = -delta
+	}
+	err := q.AppendStockMovement(ctx, database.AppendStockMovementParams{
+		ItemID:        itemUUID,
+		Delta:         delta,
+		Kind:          string(typ),
+		TransactionID: trUUID,
+		UserID:        userUUID,
 	})
+	if err != nil {
+		app.Logger.Error("error recording stock movement", zap.Error(err))
+	}
+	return err
+}
+
+// applyTransactionSavepoint is applyTransaction that can fail without
+// aborting the surrounding transaction.
+func (app App) applyTransactionSavepoint(ctx context.Context, tx pgx.Tx, userUUID, itemUUID pgtype.UUID, req schemas.CreateTransactionRequest) (schemas.Transaction, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return schemas.Transaction{}, err
+	}
+	defer sp.Rollback(ctx)
+
+	tr, err := app.applyTransaction(ctx, app.DB.Queries.WithTx(sp), userUUID, itemUUID, req)
+	if err != nil && !errors.Is(err, ErrNotEnoughItems) {
+		return tr, err
+	}
+	if cErr := sp.Commit(ctx); cErr != nil {
+		return schemas.Transaction{}, cErr
+	}
+	return tr, err
+}
+
+// batchLineError points at the line that failed the atomic batch.
+func batchLineError(i int, err error) error {
+	p := ProblemFromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		return err
+	}
+	detail := p.Detail
+	if detail == "" {
+		detail = strings.ToLower(p.Title)
+	}
+	return echo.NewHTTPError(p.Status, fmt.Sprintf("lines[%d]: %s", i, detail))
 }
 
 func (app App) HandleGetAllTransactions(c echo.Context) error {
@@ -146,38 +367,297 @@ func (app App) HandleGetAllTransactions(c echo.Context) error {
 		return echo.ErrForbidden
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
-	defer cancel()
-	result, err := app.DB.Queries.GetAllTransactions(ctx, database.GetAllTransactionsParams{
-		Limit:  50,
-		Offset: 0,
-	})
+	return app.listTransactions(c, pgtype.UUID{}, pgtype.UUID{})
+}
+
+func (app App) HandleGetItemTransactions(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	itemUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return app.listTransactions(c, itemUUID, pgtype.UUID{})
+}
+
+func (app App) HandleGetUserTransactions(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleUser) {
+		return echo.ErrForbidden
+	}
+
+	userUUID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return app.listTransactions(c, pgtype.UUID{}, userUUID)
+}
+
+// listTransactions serves a page of the transaction history, itemUUID and
+// userUUID come from the path and take precedence over the query filters.
+func (app App) listTransactions(c echo.Context, itemUUID, userUUID pgtype.UUID) error {
+	var req schemas.GetAllTransactionsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	res, err := app.GetTransactions(c.Request().Context(), req, itemUUID, userUUID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return echo.ErrNotFound
-		}
 		return err
 	}
+	setPageLinks(c, res.NextCursor, res.PrevCursor)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetTransactions finds a page of the transaction history. The history of
+// an item or a user, when itemUUID or userUUID is valid, is ErrNotFound
+// rather than empty if they don't exist.
+func (app App) GetTransactions(ctx context.Context, req schemas.GetAllTransactionsRequest, itemUUID, userUUID pgtype.UUID) (schemas.GetAllTransactionsResponse, error) {
+	var none schemas.GetAllTransactionsResponse
+	if req.Limit < 0 || req.Offset < 0 {
+		return none, echo.ErrBadRequest
+	}
+	if req.Limit == 0 {
+		req.Limit = schemas.GetAllTransactionsRequestDefaultLimit
+	}
+
+	filters, err := transactionFilters(req)
+	if err != nil {
+		return none, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if itemUUID.Valid {
+		filters.ItemID = itemUUID
+	}
+	if userUUID.Valid {
+		filters.UserID = userUUID
+	}
 
+	var cur transactionsCursor
+	if req.Cursor != "" {
+		if req.Offset != 0 {
+			return none, echo.NewHTTPError(http.StatusBadRequest, "cursor can't be combined with offset")
+		}
+		if err := DecodeCursor(req.Cursor, &cur); err != nil {
+			return none, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		curID, err := UUIDFromString(cur.ID)
+		if err != nil {
+			return none, echo.NewHTTPError(http.StatusBadRequest, ErrInvalidCursor.Error())
+		}
+		filters.Keyset = &database.TransactionsKeyset{
+			CreatedAt: pgtype.Timestamptz{Time: time.UnixMicro(cur.CreatedAt), Valid: true},
+			ID:        curID,
+			Backward:  cur.Backward,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	// one more to know if there is a next page
+	filters.Limit = int32(req.Limit + 1)
+	filters.Offset = int32(req.Offset)
+	result, err := app.DB.Queries.SearchTransactions(ctx, filters)
+	if err != nil {
+		return none, err
+	}
+
+	result, hasMore := trimPage(result, req.Limit, cur.Backward)
 	nResult := len(result)
-	trs := make([]schemas.Transaction, nResult)
+	if nResult == 0 {
+		// telling an unknown item or user apart from one without history
+		if err := app.checkHistoryOwner(ctx, itemUUID, userUUID); err != nil {
+			return none, err
+		}
+	}
 
+	trs := make([]schemas.Transaction, nResult)
 	for i := range nResult {
-		trs[i] = schemas.Transaction{
-			UUID:      result[i].ID.String(),
-			Type:      schemas.TransactionType(result[i].Type),
-			OwnerUUID: result[i].UserID.String(),
-			ItemUUID:  result[i].ItemID.String(),
-			Amount:    int(result[i].Amount),
-			Status:    schemas.TransactionStatus(result[i].Status),
-			CreatedAt: result[i].CreatedAt.Time.Unix(),
+		trs[i] = transactionFromRow(result[i])
+	}
+
+	// there is always a page in the direction we came from
+	var next, prev string
+	if nResult > 0 {
+		if hasMore || cur.Backward {
+			next, err = EncodeCursor(transactionsCursor{
+				CreatedAt: result[nResult-1].CreatedAt.Time.UnixMicro(),
+				ID:        result[nResult-1].ID.String(),
+			})
+			if err != nil {
+				return none, err
+			}
+		}
+		if (hasMore && cur.Backward) || (!cur.Backward && (req.Cursor != "" || req.Offset > 0)) {
+			prev, err = EncodeCursor(transactionsCursor{
+				CreatedAt: result[0].CreatedAt.Time.UnixMicro(),
+				ID:        result[0].ID.String(),
+				Backward:  true,
+			})
+			if err != nil {
+				return none, err
+			}
 		}
 	}
 
-	return c.JSON(http.StatusOK, schemas.GetAllTransactionsResponse{
+	return schemas.GetAllTransactionsResponse{
 		NResult:      nResult,
 		Transactions: trs,
-	})
+		NextCursor:   next,
+		PrevCursor:   prev,
+	}, nil
+}
+
+// StreamTransactions calls fn with the history matching req, oldest first.
+// With follow it then keeps calling fn with the new transactions, as the
+// event hub gets them, until ctx is done. The pagination fields of req are
+// ignored.
+func (app App) StreamTransactions(ctx context.Context, req schemas.GetAllTransactionsRequest, follow bool, fn func(schemas.Transaction) error) error {
+	filters, err := transactionFilters(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	send := func(tr database.Transaction) error {
+		return fn(transactionFromRow(tr))
+	}
+	if !follow {
+		return app.DB.Queries.ForEachTransaction(ctx, filters, send)
+	}
+	if app.Events == nil {
+		return echo.ErrServiceUnavailable
+	}
+
+	// subscribed first, so the history has whatever was sent before
+	ch := app.Events.Subscribe()
+	defer app.Events.Unsubscribe(ch)
+
+	// the snapshot of the history tells which of the events it has
+	tx, err := app.DB.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	queries := app.DB.Queries.WithTx(tx)
+	snapshot, err := queries.GetSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := queries.ForEachTransaction(ctx, filters, send); err != nil {
+		return err
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-ch:
+			if !ok {
+				return ErrEventsTooSlow
+			}
+			if event.Transaction == nil || snapshot.Sees(event.Pos.TxID) || !matchTransaction(req, *event.Transaction) {
+				continue
+			}
+			if err := fn(*event.Transaction); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// matchTransaction checks the filters of req the way the history query does.
+func matchTransaction(req schemas.GetAllTransactionsRequest, tr schemas.Transaction) bool {
+	switch {
+	case req.Type != "" && tr.Type != req.Type,
+		req.Status != "" && tr.Status != req.Status,
+		req.UserUUID != "" && !strings.EqualFold(tr.OwnerUUID, req.UserUUID),
+		req.ItemUUID != "" && !strings.EqualFold(tr.ItemUUID, req.ItemUUID),
+		req.CreatedAfter != 0 && tr.CreatedAt < req.CreatedAfter,
+		req.CreatedBefore != 0 && tr.CreatedAt >= req.CreatedBefore,
+		req.MinAmount != nil && tr.Amount < *req.MinAmount,
+		req.MaxAmount != nil && tr.Amount > *req.MaxAmount:
+		return false
+	}
+	return true
+}
+
+func (app App) checkHistoryOwner(ctx context.Context, itemUUID, userUUID pgtype.UUID) error {
+	var err error
+	switch {
+	case itemUUID.Valid:
+		_, err = app.DB.Queries.GetItemQuantity(ctx, itemUUID)
+	case userUUID.Valid:
+		_, err = app.DB.Queries.GetUserRole(ctx, userUUID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return echo.ErrNotFound
+	}
+	return err
+}
+
+// transactionFilters checks the filters of the request and converts them for the query.
+func transactionFilters(req schemas.GetAllTransactionsRequest) (database.SearchTransactionsParams, error) {
+	var params database.SearchTransactionsParams
+
+	switch req.Type {
+	case "":
+	case schemas.TransactionTypeRestock, schemas.TransactionTypeWithdraw:
+		params.Type = PtrFromString(string(req.Type))
+	default:
+		return params, errors.New("unknown transaction type")
+	}
+	switch req.Status {
+	case "":
+	case schemas.TransactionStatusSucceeded, schemas.TransactionStatusFailed, schemas.TransactionStatusReversed:
+		params.Status = PtrFromString(string(req.Status))
+	default:
+		return params, errors.New("unknown transaction status")
+	}
+
+	var err error
+	if req.UserUUID != "" {
+		if params.UserID, err = UUIDFromString(req.UserUUID); err != nil {
+			return params, errors.New("invalid user_uuid")
+		}
+	}
+	if req.ItemUUID != "" {
+		if params.ItemID, err = UUIDFromString(req.ItemUUID); err != nil {
+			return params, errors.New("invalid item_uuid")
+		}
+	}
+
+	if req.CreatedAfter != 0 && req.CreatedBefore != 0 && req.CreatedAfter >= req.CreatedBefore {
+		return params, errors.New("created_after has to be earlier than created_before")
+	}
+	params.CreatedAfter = TimestampFromUnix(req.CreatedAfter)
+	params.CreatedBefore = TimestampFromUnix(req.CreatedBefore)
+
+	if req.MinAmount != nil {
+		if *req.MinAmount < 0 || *req.MinAmount > math.MaxInt32 {
+			return params, errors.New("min_amount is out of range")
+		}
+		v := int32(*req.MinAmount)
+		params.MinAmount = &v
+	}
+	if req.MaxAmount != nil {
+		if *req.MaxAmount < 0 || *req.MaxAmount > math.MaxInt32 {
+			return params, errors.New("max_amount is out of range")
+		}
+		v := int32(*req.MaxAmount)
+		params.MaxAmount = &v
+	}
+	if params.MinAmount != nil && params.MaxAmount != nil && *params.MinAmount > *params.MaxAmount {
+		return params, errors.New("min_amount is greater than max_amount")
+	}
+
+	return params, nil
 }
 
 func (app App) HandleGetTransaction(c echo.Context) error {
@@ -191,23 +671,185 @@ func (app App) HandleGetTransaction(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	tr, err := app.GetTransaction(c.Request().Context(), uuid)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, tr)
+}
+
+func (app App) GetTransaction(ctx context.Context, uuid pgtype.UUID) (schemas.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
 	defer cancel()
 	tr, err := app.DB.Queries.GetTransaction(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return schemas.Transaction{}, echo.ErrNotFound
+		}
+		return schemas.Transaction{}, err
+	}
+	return transactionFromRow(tr), nil
+}
+
+// GetRecentTransactions returns the last n transactions of each item, newest
+// first.
+func (app App) GetRecentTransactions(ctx context.Context, itemUUIDs []pgtype.UUID, n int) ([]schemas.Transaction, error) {
+	if n <= 0 || n > math.MaxInt32 {
+		return nil, echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase)
+	defer cancel()
+	rows, err := app.DB.Queries.GetRecentTransactionsByItems(ctx, database.GetRecentTransactionsByItemsParams{
+		ItemIds: itemUUIDs,
+		N:       int32(n),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]schemas.Transaction, len(rows))
+	for i, row := range rows {
+		res[i] = transactionFromRow(database.Transaction(row))
+	}
+	return res, nil
+}
+
+// HandleReverseTransaction undoes a mistyped transaction with a compensating
+// one, the original is kept and marked as reversed.
+func (app App) HandleReverseTransaction(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	uuidStr, ok := c.Get("userID").(string)
+	if uuidStr == "" || !ok {
+		return echo.ErrForbidden
+	}
+
+	userUUID, err := UUIDFromString(uuidStr)
+	if err != nil {
+		return echo.ErrForbidden
+	}
+
+	uuid, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.ReverseTransactionRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err = c.Validate(&req); err != nil {
+		return err
+	}
+
+	res, err := app.ReverseTransaction(c.Request().Context(), userUUID, uuid, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, res)
+}
+
+// ReverseTransaction records the compensating transaction made by the user.
+func (app App) ReverseTransaction(ctx context.Context, userUUID, uuid pgtype.UUID, req schemas.ReverseTransactionRequest) (schemas.ReverseTransactionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutDatabase*4)
+	defer cancel()
+	tx, err := app.DB.Pool.Begin(ctx)
+	if err != nil {
+		app.Logger.Error("error starting transaction", zap.Error(err))
+		return schemas.ReverseTransactionResponse{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := app.DB.Queries.WithTx(tx)
+
+	// the row stays locked, a concurrent reversal waits and then finds it
+	// reversed already
+	orig, err := q.MarkTransactionReversed(ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return schemas.ReverseTransactionResponse{}, notReversible(ctx, q, uuid)
+	}
+	if err != nil {
+		return schemas.ReverseTransactionResponse{}, err
+	}
+
+	var typ schemas.TransactionType
+	switch schemas.TransactionType(orig.Type) {
+	case schemas.TransactionTypeRestock:
+		typ = schemas.TransactionTypeWithdraw
+	case schemas.TransactionTypeWithdraw:
+		typ = schemas.TransactionTypeRestock
+	default:
+		return schemas.ReverseTransactionResponse{}, fmt.Errorf("%w: it is of type %s", ErrNotReversible, orig.Type)
+	}
+
+	err = app.changeStock(ctx, q, orig.ItemID, typ, int(orig.Amount))
+	if errors.Is(err, ErrNotEnoughItems) {
+		return schemas.ReverseTransactionResponse{}, fmt.Errorf("%w: not enough items left to undo the restock", ErrNotReversible)
+	}
+	if err != nil {
+		return schemas.ReverseTransactionResponse{}, err
+	}
+
+	reversal, err := q.CreateReversalTransaction(ctx, database.CreateReversalTransactionParams{
+		UserID:     userUUID,
+		ItemID:     orig.ItemID,
+		Type:       string(typ),
+		Amount:     orig.Amount,
+		Reason:     &req.Reason,
+		ReversalOf: orig.ID,
+	})
+	if err != nil {
+		app.Logger.Error("error creating reversal", zap.Error(err))
+		return schemas.ReverseTransactionResponse{}, err
+	}
+	if err := app.recordMovement(ctx, q, orig.ItemID, typ, int(orig.Amount), reversal.ID, userUUID); err != nil {
+		return schemas.ReverseTransactionResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return schemas.ReverseTransactionResponse{}, err
+	}
+
+	return schemas.ReverseTransactionResponse{
+		Original: transactionFromRow(orig),
+		Reversal: transactionFromRow(reversal),
+	}, nil
+}
+
+// notReversible tells why MarkTransactionReversed matched nothing.
+func notReversible(ctx context.Context, q *database.Queries, uuid pgtype.UUID) error {
+	tr, err := q.GetTransaction(ctx, uuid)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return echo.ErrNotFound
 		}
 		return err
 	}
+	switch {
+	case tr.ReversalOf.Valid:
+		return fmt.Errorf("%w: it is a reversal itself", ErrNotReversible)
+	case tr.Status == string(schemas.TransactionStatusReversed):
+		return fmt.Errorf("%w: it is already reversed", ErrNotReversible)
+	default:
+		return fmt.Errorf("%w: failed transactions have nothing to undo", ErrNotReversible)
+	}
+}
 
-	return c.JSON(http.StatusOK, schemas.Transaction{
+func transactionFromRow(tr database.Transaction) schemas.Transaction {
+	res := schemas.Transaction{
 		UUID:      tr.ID.String(),
 		Type:      schemas.TransactionType(tr.Type),
 		OwnerUUID: tr.UserID.String(),
 		ItemUUID:  tr.ItemID.String(),
 		Amount:    int(tr.Amount),
 		Status:    schemas.TransactionStatus(tr.Status),
+		Reason:    StringFromPtr(tr.Reason),
 		CreatedAt: tr.CreatedAt.Time.Unix(),
-	})
+	}
+	if tr.ReversalOf.Valid {
+		res.ReversalOf = tr.ReversalOf.String()
+	}
+	return res
 }
@@ -0,0 +1,222 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentWithdrawals(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleStocker)
+	item := newTestItem(t, app, "hammered")
+
+	e := newTestEcho(app, usr)
+	e.POST("/transactions", app.HandleCreateTransaction)
+	send := func(typ schemas.TransactionType, amount int) int {
+		body := fmt.Sprintf(`{"type": %q, "item_uuid": %q, "amount": %d}`, typ, item.Uuid.String(), amount)
+		return serve(e, http.MethodPost, "/transactions", body).Code
+	}
+
+	const stock, workers = 20, 50
+	require.Equal(t, http.StatusAccepted, send(schemas.TransactionTypeRestock, stock))
+	// would wrap around to -1 as an integer column
+	require.Equal(t, http.StatusBadRequest, send(schemas.TransactionTypeRestock, 4294967295))
+
+	codes := make(chan int, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- send(schemas.TransactionTypeWithdraw, 1)
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	succeeded, failed := 0, 0
+	for code := range codes {
+		switch code {
+		case http.StatusAccepted:
+			succeeded++
+		case http.StatusUnprocessableEntity:
+			failed++
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	require.Equal(t, stock, succeeded)
+	require.Equal(t, workers-stock, failed)
+
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, qty.Quantity)
+
+	// every attempt is in the history
+	var nFailed int
+	err = app.DB.Pool.QueryRow(ctx,
+		"SELECT count(*) FROM transactions WHERE item_id = $1 AND status = 'failed'", item.Uuid,
+	).Scan(&nFailed)
+	require.NoError(t, err)
+	require.Equal(t, workers-stock, nFailed)
+}
+
+func TestTransactionBatch(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleStocker)
+	nails := newTestItem(t, app, "nails")
+	screws := newTestItem(t, app, "screws")
+
+	e := newTestEcho(app, usr)
+	e.POST("/transactions/batch", app.HandleCreateTransactionBatch)
+	send := func(mode schemas.BatchMode, lines ...string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"mode": %q, "lines": [%s]}`, mode, strings.Join(lines, ","))
+		return serve(e, http.MethodPost, "/transactions/batch", body)
+	}
+	line := func(typ schemas.TransactionType, item string, amount int) string {
+		return fmt.Sprintf(`{"type": %q, "item_uuid": %q, "amount": %d}`, typ, item, amount)
+	}
+	quantity := func(item database.CreateItemRow) int32 {
+		qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+		require.NoError(t, err)
+		return qty.Quantity
+	}
+
+	rec := send(schemas.BatchModeAtomic,
+		line(schemas.TransactionTypeRestock, nails.Uuid.String(), 10),
+		line(schemas.TransactionTypeRestock, screws.Uuid.String(), 5),
+	)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.EqualValues(t, 10, quantity(nails))
+	require.EqualValues(t, 5, quantity(screws))
+
+	// the second line fails, the first one is rolled back
+	rec = send(schemas.BatchModeAtomic,
+		line(schemas.TransactionTypeWithdraw, nails.Uuid.String(), 3),
+		line(schemas.TransactionTypeWithdraw, screws.Uuid.String(), 6),
+	)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "lines[1]")
+	require.EqualValues(t, 10, quantity(nails))
+	require.EqualValues(t, 5, quantity(screws))
+
+	rec = send(schemas.BatchModeBestEffort,
+		line(schemas.TransactionTypeWithdraw, nails.Uuid.String(), 3),
+		line(schemas.TransactionTypeWithdraw, screws.Uuid.String(), 6),
+		line(schemas.TransactionTypeRestock, "00000000-0000-0000-0000-000000000000", 1),
+	)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var res schemas.CreateTransactionBatchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, 1, res.NSucceeded)
+	require.Equal(t, 2, res.NFailed)
+	require.Equal(t, http.StatusAccepted, res.Lines[0].Status)
+	require.Equal(t, http.StatusUnprocessableEntity, res.Lines[1].Status)
+	require.Equal(t, schemas.TransactionStatusFailed, res.Lines[1].Transaction.Status)
+	require.Equal(t, http.StatusNotFound, res.Lines[2].Status)
+	require.EqualValues(t, 7, quantity(nails))
+	require.EqualValues(t, 5, quantity(screws))
+}
+
+func TestReverseTransaction(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	item := newTestItem(t, app, "mistyped")
+
+	e := newTestEcho(app, usr)
+	e.POST("/transactions", app.HandleCreateTransaction)
+	e.POST("/transactions/:uuid/reverse", app.HandleReverseTransaction)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		return serve(e, http.MethodPost, path, body)
+	}
+
+	rec := post("/transactions", fmt.Sprintf(`{"type": "restock", "item_uuid": %q, "amount": 50}`, item.Uuid.String()))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var tr schemas.Transaction
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tr))
+
+	// a reason is required
+	require.Equal(t, http.StatusBadRequest, post("/transactions/"+tr.UUID+"/reverse", `{}`).Code)
+
+	rec = post("/transactions/"+tr.UUID+"/reverse", `{"reason": "meant 5"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var res schemas.ReverseTransactionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, schemas.TransactionStatusReversed, res.Original.Status)
+	require.Equal(t, schemas.TransactionTypeWithdraw, res.Reversal.Type)
+	require.Equal(t, tr.UUID, res.Reversal.ReversalOf)
+	require.Equal(t, "meant 5", res.Reversal.Reason)
+
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, qty.Quantity)
+
+	require.Equal(t, http.StatusConflict, post("/transactions/"+tr.UUID+"/reverse", `{"reason": "again"}`).Code)
+	require.Equal(t, http.StatusConflict, post("/transactions/"+res.Reversal.UUID+"/reverse", `{"reason": "undo"}`).Code)
+}
+
+func TestItemStockLedger(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	item := newTestItem(t, app, "ledgered")
+
+	e := newTestEcho(app, usr)
+	e.POST("/transactions", app.HandleCreateTransaction)
+	e.PATCH("/items/:uuid", app.HandlePatchItem)
+	e.GET("/items/:uuid/stock", app.HandleGetItemStock)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(e, method, path, body, handlers.HeaderIfMatch, "*")
+	}
+	stock := func(asOf int64) schemas.ItemStock {
+		rec := do(http.MethodGet, fmt.Sprintf("/items/%s/stock?as_of=%d", item.Uuid.String(), asOf), "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var s schemas.ItemStock
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+		return s
+	}
+
+	body := fmt.Sprintf(`{"type": "restock", "item_uuid": %q, "amount": 10}`, item.Uuid.String())
+	require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/transactions", body).Code)
+	// as_of has a precision of a second
+	time.Sleep(time.Second)
+	afterRestock := time.Now().Unix()
+	time.Sleep(time.Second)
+
+	body = fmt.Sprintf(`{"type": "withdraw", "item_uuid": %q, "amount": 4}`, item.Uuid.String())
+	require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/transactions", body).Code)
+	// a direct PATCH is an adjustment in the ledger
+	require.Equal(t, http.StatusOK, do(http.MethodPatch, "/items/"+item.Uuid.String(), `{"quantity": 5}`).Code)
+
+	require.Equal(t, 10, stock(afterRestock).Quantity)
+	now := stock(0)
+	require.Equal(t, 5, now.Quantity)
+	require.Equal(t, 3, now.NMovements)
+
+	qty, err := app.DB.Queries.GetItemQuantity(ctx, item.Uuid)
+	require.NoError(t, err)
+	require.EqualValues(t, now.Quantity, qty.Quantity)
+
+	// the ledger can't be rewritten
+	_, err = app.DB.Pool.Exec(ctx, "DELETE FROM stock_movements WHERE item_id = $1", item.Uuid)
+	require.Error(t, err)
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/bigelle/warehouse/internal/schemas"
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -31,6 +36,21 @@ func GenerateAccessJWT(id string, role string, secret []byte, expires time.Durat
 	return token.SignedString(secret)
 }
 
+// GenerateEventsTicket signs a ticket for the event streams with the secret
+// of the access tokens, its audience keeps it from being one.
+func GenerateEventsTicket(id string, role string, secret []byte, expires time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  id,
+		"role": role,
+		"aud":  eventsTicketAudience,
+		"exp":  time.Now().Add(expires).Unix(),
+		"iat":  time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(secret)
+}
+
 func GenerateRefreshJWT(id string, secret []byte, expires time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": id,
@@ -64,3 +84,257 @@ func IsAppropriateRole(v any, expected schemas.Role) bool {
 	got, ok := v.(schemas.Role)
 	return ok && expected <= got
 }
+
+// UnixOrZero is for nullable timestamps, NULL gives 0.
+func UnixOrZero(ts pgtype.Timestamptz) int64 {
+	if !ts.Valid {
+		return 0
+	}
+	return ts.Time.Unix()
+}
+
+func StringFromPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// PtrFromString returns nil for empty strings, so they end up as NULLs.
+func PtrFromString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// VariantOptionsFromJSON decodes items.variant_options, NULL gives nil.
+func VariantOptionsFromJSON(b []byte) map[string]string {
+	if len(b) == 0 {
+		return nil
+	}
+	var opts map[string]string
+	if err := json.Unmarshal(b, &opts); err != nil {
+		return nil
+	}
+	return opts
+}
+
+var (
+	ErrTooManyVariants      = errors.New("too many variants")
+	ErrDuplicateOption      = errors.New("duplicate option name")
+	ErrDuplicateOptionValue = errors.New("duplicate option value")
+)
+
+// CheckProductOptions counts the variants before VariantMatrix builds them,
+// and rejects options and values that would give the same SKU twice.
+func CheckProductOptions(options []schemas.ProductOption) error {
+	n := 1
+	names := make(map[string]bool, len(options))
+	for _, opt := range options {
+		n *= len(opt.Values)
+		if n > schemas.ProductMaxVariants {
+			return ErrTooManyVariants
+		}
+
+		name := skuPart(opt.Name)
+		if names[name] {
+			return fmt.Errorf("%w: %s", ErrDuplicateOption, opt.Name)
+		}
+		names[name] = true
+		values := make(map[string]bool, len(opt.Values))
+		for _, v := range opt.Values {
+			if values[skuPart(v)] {
+				return fmt.Errorf("%w: %s", ErrDuplicateOptionValue, v)
+			}
+			values[skuPart(v)] = true
+		}
+	}
+	return nil
+}
+
+// VariantMatrix expands product options into every combination of their
+// values, keeping the order of the options in each combination.
+func VariantMatrix(options []schemas.ProductOption) [][]string {
+	matrix := [][]string{{}}
+	for _, opt := range options {
+		next := make([][]string, 0, len(matrix)*len(opt.Values))
+		for _, combo := range matrix {
+			for _, v := range opt.Values {
+				c := make([]string, len(combo), len(combo)+1)
+				copy(c, combo)
+				next = append(next, append(c, v))
+			}
+		}
+		matrix = next
+	}
+	return matrix
+}
+
+// VariantName gives names like "Glove (L, Black)".
+func VariantName(product string, values []string) string {
+	return product + " (" + strings.Join(values, ", ") + ")"
+}
+
+// VariantSKU gives SKUs like "GLV-L-BLACK".
+func VariantSKU(prefix string, values []string) string {
+	parts := make([]string, 0, len(values)+1)
+	parts = append(parts, prefix)
+	parts = append(parts, values...)
+	return skuPart(strings.Join(parts, "-"))
+}
+
+func skuPart(s string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(s)), "-")
+}
+
+var (
+	ErrInvalidBarcode       = errors.New("invalid barcode")
+	ErrInvalidBarcodeFormat = errors.New("code does not match the barcode format")
+	ErrInvalidCheckDigit    = errors.New("invalid GTIN check digit")
+)
+
+const MaxCode128Length = 80
+
+// DetectBarcodeFormat guesses the format of a scanned code: all-digit codes
+// of a GTIN length are treated as GTINs, anything else as Code128.
+func DetectBarcodeFormat(code string) schemas.BarcodeFormat {
+	if !isDigits(code) {
+		return schemas.BarcodeFormatCode128
+	}
+	switch len(code) {
+	case 8:
+		return schemas.BarcodeFormatEAN8
+	case 12:
+		return schemas.BarcodeFormatUPCA
+	case 13:
+		return schemas.BarcodeFormatEAN13
+	case 14:
+		return schemas.BarcodeFormatGTIN14
+	default:
+		return schemas.BarcodeFormatCode128
+	}
+}
+
+func ValidateBarcode(code string, format schemas.BarcodeFormat) error {
+	if code == "" {
+		return ErrInvalidBarcode
+	}
+
+	if format.IsGTIN() {
+		if DetectBarcodeFormat(code) != format {
+			return ErrInvalidBarcodeFormat
+		}
+		if !IsValidGTIN(code) {
+			return ErrInvalidCheckDigit
+		}
+		return nil
+	}
+	switch format {
+	case schemas.BarcodeFormatCode128:
+		if len(code) > MaxCode128Length {
+			return ErrInvalidBarcode
+		}
+		for i := range len(code) {
+			// set B, the one handheld scanners emit
+			if code[i] < 32 || code[i] > 126 {
+				return ErrInvalidBarcode
+			}
+		}
+		return nil
+	default:
+		return ErrInvalidBarcodeFormat
+	}
+}
+
+// NormalizeBarcode pads GTINs to GTIN-14, so that a UPC-A and the EAN-13
+// with its leading zero are the same code.
+func NormalizeBarcode(code string, format schemas.BarcodeFormat) string {
+	if format.IsGTIN() && len(code) < 14 {
+		return strings.Repeat("0", 14-len(code)) + code
+	}
+	return code
+}
+
+// BarcodeLookupCodes is what a scanned code may have been stored as: the
+// code itself, and the GTIN-14 if it reads as a GTIN.
+func BarcodeLookupCodes(code string) []string {
+	code = strings.TrimSpace(code)
+	format := DetectBarcodeFormat(code)
+	if format.IsGTIN() && IsValidGTIN(code) {
+		if gtin := NormalizeBarcode(code, format); gtin != code {
+			return []string{gtin, code}
+		}
+	}
+	return []string{code}
+}
+
+// IsValidGTIN checks the mod-10 check digit shared by EAN-8, UPC-A, EAN-13
+// and GTIN-14.
+func IsValidGTIN(code string) bool {
+	if len(code) < 2 || !isDigits(code) {
+		return false
+	}
+
+	sum := 0
+	weight := 3
+	for i := len(code) - 2; i >= 0; i-- {
+		sum += int(code[i]-'0') * weight
+		weight = 4 - weight // 3, 1, 3, 1...
+	}
+	check := (10 - sum%10) % 10
+
+	return check == int(code[len(code)-1]-'0')
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxSortFields limits how many keys can be chained in a single sort.
+const MaxSortFields = 3
+
+// ParseItemSort reads sorts like "-quantity,name", where "-" means descending.
+func ParseItemSort(s string) ([]database.ItemSort, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(s, ",")
+	if len(fields) > MaxSortFields {
+		return nil, fmt.Errorf("can't sort by more than %d fields", MaxSortFields)
+	}
+
+	sort := make([]database.ItemSort, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		desc := strings.HasPrefix(f, "-")
+		f = strings.TrimPrefix(f, "-")
+		if _, ok := database.ItemSortColumns[f]; !ok {
+			return nil, fmt.Errorf("can't sort by %q", f)
+		}
+		for _, prev := range sort {
+			if prev.Column == f {
+				return nil, fmt.Errorf("%q is used twice", f)
+			}
+		}
+		sort = append(sort, database.ItemSort{Column: f, Desc: desc})
+	}
+	return sort, nil
+}
+
+// TimestampFromUnix turns zero into NULL.
+func TimestampFromUnix(sec int64) pgtype.Timestamptz {
+	if sec == 0 {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: time.Unix(sec, 0), Valid: true}
+}
@@ -1,10 +1,12 @@
 package handlers_test
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/bigelle/warehouse/internal/database"
 	"github.com/bigelle/warehouse/internal/handlers"
-	"github.com/bigelle/warehouse/internal/schemas"
+	"github.com/bigelle/warehouse/pkg/schemas"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,3 +21,118 @@ func TestIsAppropriateRole(t *testing.T) {
 	ok = handlers.IsAppropriateRole(role, schemas.RoleAdmin)
 	require.False(t, ok)
 }
+
+func TestIsValidGTIN(t *testing.T) {
+	// expect true:
+	require.True(t, handlers.IsValidGTIN("96385074"))       // EAN-8
+	require.True(t, handlers.IsValidGTIN("036000291452"))   // UPC-A
+	require.True(t, handlers.IsValidGTIN("4006381333931"))  // EAN-13
+	require.True(t, handlers.IsValidGTIN("10614141000415")) // GTIN-14
+
+	// expect false:
+	require.False(t, handlers.IsValidGTIN("4006381333932"))
+	require.False(t, handlers.IsValidGTIN("40063813339a1"))
+	require.False(t, handlers.IsValidGTIN(""))
+}
+
+func TestValidateBarcode(t *testing.T) {
+	format := handlers.DetectBarcodeFormat("4006381333931")
+	require.Equal(t, schemas.BarcodeFormatEAN13, format)
+	require.NoError(t, handlers.ValidateBarcode("4006381333931", format))
+
+	format = handlers.DetectBarcodeFormat("BIN-A12-03")
+	require.Equal(t, schemas.BarcodeFormatCode128, format)
+	require.NoError(t, handlers.ValidateBarcode("BIN-A12-03", format))
+
+	err := handlers.ValidateBarcode("4006381333931", schemas.BarcodeFormatUPCA)
+	require.ErrorIs(t, err, handlers.ErrInvalidBarcodeFormat)
+
+	err = handlers.ValidateBarcode("4006381333932", schemas.BarcodeFormatEAN13)
+	require.ErrorIs(t, err, handlers.ErrInvalidCheckDigit)
+
+	err = handlers.ValidateBarcode("bad\tcode", schemas.BarcodeFormatCode128)
+	require.ErrorIs(t, err, handlers.ErrInvalidBarcode)
+}
+
+func TestBarcodeLookupCodes(t *testing.T) {
+	require.Equal(t, "00036000291452", handlers.NormalizeBarcode("036000291452", schemas.BarcodeFormatUPCA))
+	require.Equal(t, "00036000291452", handlers.NormalizeBarcode("0036000291452", schemas.BarcodeFormatEAN13))
+	require.Equal(t, "00000096385074", handlers.NormalizeBarcode("96385074", schemas.BarcodeFormatEAN8))
+	require.Equal(t, "036000291452", handlers.NormalizeBarcode("036000291452", schemas.BarcodeFormatCode128))
+
+	// a UPC-A and its EAN-13 scan to the same stored code
+	require.Equal(t, []string{"00036000291452", "036000291452"}, handlers.BarcodeLookupCodes("036000291452"))
+	require.Equal(t, []string{"00036000291452", "0036000291452"}, handlers.BarcodeLookupCodes(" 0036000291452 "))
+	require.Equal(t, []string{"10614141000415"}, handlers.BarcodeLookupCodes("10614141000415"))
+	// not a GTIN
+	require.Equal(t, []string{"036000291453"}, handlers.BarcodeLookupCodes("036000291453"))
+	require.Equal(t, []string{"BIN-A12-03"}, handlers.BarcodeLookupCodes("BIN-A12-03"))
+}
+
+func TestVariantMatrix(t *testing.T) {
+	matrix := handlers.VariantMatrix([]schemas.ProductOption{
+		{Name: "size", Values: []string{"S", "M", "L"}},
+		{Name: "colour", Values: []string{"black", "light blue"}},
+	})
+	require.Len(t, matrix, 6)
+	require.Equal(t, []string{"S", "black"}, matrix[0])
+	require.Equal(t, []string{"L", "light blue"}, matrix[5])
+
+	require.Equal(t, "Glove (L, light blue)", handlers.VariantName("Glove", matrix[5]))
+	require.Equal(t, "GLV-L-LIGHT-BLUE", handlers.VariantSKU("glv", matrix[5]))
+}
+
+func TestCheckProductOptions(t *testing.T) {
+	values := func(n int) []string {
+		res := make([]string, n)
+		for i := range n {
+			res[i] = fmt.Sprint(i)
+		}
+		return res
+	}
+
+	require.NoError(t, handlers.CheckProductOptions([]schemas.ProductOption{
+		{Name: "size", Values: values(10)},
+		{Name: "colour", Values: values(10)},
+	}))
+	// counted, never expanded
+	options := make([]schemas.ProductOption, 10)
+	for i := range options {
+		options[i] = schemas.ProductOption{Name: fmt.Sprint("option", i), Values: values(10)}
+	}
+	require.ErrorIs(t, handlers.CheckProductOptions(options), handlers.ErrTooManyVariants)
+
+	// the same SKU twice
+	err := handlers.CheckProductOptions([]schemas.ProductOption{
+		{Name: "size", Values: []string{"S"}},
+		{Name: "Size", Values: []string{"M"}},
+	})
+	require.ErrorIs(t, err, handlers.ErrDuplicateOption)
+	err = handlers.CheckProductOptions([]schemas.ProductOption{
+		{Name: "colour", Values: []string{"light blue", "Light  Blue"}},
+	})
+	require.ErrorIs(t, err, handlers.ErrDuplicateOptionValue)
+}
+
+func TestParseItemSort(t *testing.T) {
+	sort, err := handlers.ParseItemSort("-quantity, name")
+	require.NoError(t, err)
+	require.Equal(t, []database.ItemSort{
+		{Column: "quantity", Desc: true},
+		{Column: "name"},
+	}, sort)
+
+	sort, err = handlers.ParseItemSort("")
+	require.NoError(t, err)
+	require.Empty(t, sort)
+
+	// expect errors:
+	_, err = handlers.ParseItemSort("id; DROP TABLE items")
+	require.Error(t, err)
+	_, err = handlers.ParseItemSort("name,-name")
+	require.Error(t, err)
+	_, err = handlers.ParseItemSort("name,")
+	require.Error(t, err)
+	_, err = handlers.ParseItemSort("name,sku,quantity,created_at")
+	require.Error(t, err)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// Validator checks requests against their `validate` tags, plug it into
+// echo.Echo.Validator and call c.Validate after c.Bind.
+type Validator struct {
+	v *validator.Validate
+}
+
+func NewValidator() *Validator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// reporting fields the way clients send them
+	v.RegisterTagNameFunc(fieldTagName)
+
+	// roles are numbers, unknown names are unmarshalled as RoleUndefined
+	v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		r, ok := fl.Field().Interface().(schemas.Role)
+		return ok && r > schemas.RoleUndefined && r <= schemas.RoleAdmin
+	})
+
+	return &Validator{v: v}
+}
+
+// Validate returns a 400 listing every failing field.
+func (v *Validator) Validate(i any) error {
+	err := v.v.Struct(i)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	root := reflect.TypeOf(i)
+	fields := make([]schemas.FieldError, len(verrs))
+	for i, fe := range verrs {
+		param := fieldParam(root, fe)
+		fields[i] = schemas.FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   param,
+			Message: fieldErrorMessage(fe, param),
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, schemas.ValidationErrorResponse{
+		Message: "invalid request",
+		Errors:  fields,
+	})
+}
+
+func fieldTagName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// fieldParamTags are the rules whose param names other fields.
+var fieldParamTags = map[string]bool{
+	"required_with":        true,
+	"required_with_all":    true,
+	"required_without":     true,
+	"required_without_all": true,
+	"excluded_with":        true,
+	"excluded_with_all":    true,
+	"excluded_without":     true,
+	"excluded_without_all": true,
+	"eqfield":              true,
+	"nefield":              true,
+	"gtfield":              true,
+	"gtefield":             true,
+	"ltfield":              true,
+	"ltefield":             true,
+}
+
+// fieldParam names the fields in the param of the rule the way clients
+// send them, like the fields themselves: "ItemUUID" becomes "item_uuid".
+func fieldParam(root reflect.Type, fe validator.FieldError) string {
+	if !fieldParamTags[fe.Tag()] {
+		return fe.Param()
+	}
+	parent := parentStruct(root, fe.StructNamespace())
+	if parent == nil {
+		return fe.Param()
+	}
+	names := strings.Fields(fe.Param())
+	for i, name := range names {
+		if f, ok := parent.FieldByName(name); ok {
+			if tagName := fieldTagName(f); tagName != "" {
+				names[i] = tagName
+			}
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// parentStruct finds the struct holding the field of the namespace,
+// "CreateTransactionBatchRequest.Lines[0].Barcode" is in a
+// CreateTransactionRequest.
+func parentStruct(t reflect.Type, ns string) reflect.Type {
+	parts := strings.Split(ns, ".")
+	for _, part := range parts[1 : len(parts)-1] {
+		t = derefType(t)
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		name, _, _ := strings.Cut(part, "[")
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return nil
+		}
+		t = f.Type
+		// an element of a slice or a map
+		for range strings.Count(part, "[") {
+			t = derefType(t).Elem()
+		}
+	}
+	if t = derefType(t); t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldPath drops the name of the request struct: "items[2]" rather than
+// "LabelSheetRequest.items[2]".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+// fieldErrorMessage describes the error, param as fieldParam gives it.
+func fieldErrorMessage(fe validator.FieldError, param string) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required when " + param + " is not set"
+	case "excluded_with":
+		return "must not be set along with " + param
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "role":
+		return "must be one of: user, stocker, admin"
+	case "uuid":
+		return "must be a UUID"
+	case "min", "gte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return "must have at least " + fe.Param() + " " + unit
+		}
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return "must have at most " + fe.Param() + " " + unit
+		}
+		return "must be at most " + fe.Param()
+	default:
+		return "failed on " + fe.Tag()
+	}
+}
+
+// lengthUnit is what min and max count for the kind, empty for numbers.
+func lengthUnit(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "elements"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator(t *testing.T) {
+	v := handlers.NewValidator()
+
+	// expect no errors:
+	require.NoError(t, v.Validate(&schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: "0199f0b6-6a1e-7d1c-9a43-3c1f1e0c5a10",
+		Amount:   5,
+	}))
+	require.NoError(t, v.Validate(&schemas.CreateTransactionRequest{
+		Type:    schemas.TransactionTypeWithdraw,
+		Barcode: "4006381333931",
+		Amount:  1,
+	}))
+	require.NoError(t, v.Validate(&schemas.RegisterRequest{Username: "bob", Password: "secret"}))
+
+	err := v.Validate(&schemas.CreateTransactionRequest{
+		Type:   "steal",
+		Amount: -3,
+	})
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	require.Equal(t, http.StatusBadRequest, he.Code)
+
+	resp, ok := he.Message.(schemas.ValidationErrorResponse)
+	require.True(t, ok)
+	require.ElementsMatch(t, []schemas.FieldError{
+		{Field: "type", Rule: "oneof", Param: "restock withdraw", Message: "must be one of: restock, withdraw"},
+		{Field: "barcode", Rule: "required_without", Param: "item_uuid", Message: "is required when item_uuid is not set"},
+		{Field: "amount", Rule: "min", Param: "1", Message: "must be at least 1"},
+	}, resp.Errors)
+
+	// either one, not both
+	err = v.Validate(&schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: "0199f0b6-6a1e-7d1c-9a43-3c1f1e0c5a10",
+		Barcode:  "4006381333931",
+		Amount:   5,
+	})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Equal(t, []schemas.FieldError{
+		{Field: "barcode", Rule: "excluded_with", Param: "item_uuid", Message: "must not be set along with item_uuid"},
+	}, resp.Errors)
+
+	// the params name fields the same way, in nested requests too
+	err = v.Validate(&schemas.CreateTransactionBatchRequest{Lines: []schemas.CreateTransactionRequest{{
+		Type:   schemas.TransactionTypeRestock,
+		Amount: 1,
+	}}})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Equal(t, []schemas.FieldError{
+		{Field: "lines[0].barcode", Rule: "required_without", Param: "item_uuid", Message: "is required when item_uuid is not set"},
+	}, resp.Errors)
+
+	// amounts are stored as integers, a bigger one would wrap around
+	err = v.Validate(&schemas.CreateTransactionBatchRequest{Lines: []schemas.CreateTransactionRequest{{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: "0199f0b6-6a1e-7d1c-9a43-3c1f1e0c5a10",
+		Amount:   4294967295,
+	}}})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Equal(t, []schemas.FieldError{
+		{Field: "lines[0].amount", Rule: "max", Param: "2147483647", Message: "must be at most 2147483647"},
+	}, resp.Errors)
+
+	err = v.Validate(&schemas.LabelSheetRequest{Items: []string{"not-a-uuid"}})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "items[0]", resp.Errors[0].Field)
+
+	err = v.Validate(&schemas.RegisterRequest{Username: "bob", Password: "secret", Role: schemas.RoleUndefined})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Equal(t, "role", resp.Errors[0].Field)
+
+	err = v.Validate(&schemas.GetItemsRequest{Limit: 1000})
+	require.ErrorAs(t, err, &he)
+	resp = he.Message.(schemas.ValidationErrorResponse)
+	require.Equal(t, "limit", resp.Errors[0].Field)
+}
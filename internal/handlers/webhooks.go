@@ -0,0 +1,539 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	// sha256= and the hex HMAC-SHA256 of the timestamp, a dot and the body,
+	// keyed with the secret of the webhook
+	HeaderWebhookSignature = "X-Webhook-Signature"
+	WebhookTimeout         = 10 * time.Second
+	// a delivery is given up on after this many attempts, and its webhook
+	// disabled: the ones after it would wait forever
+	WebhookMaxAttempts = 10
+	webhookBackoff     = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+	webhookBatch       = 20
+	// how long a server has to attempt the deliveries it claimed before
+	// another one may
+	webhookLease = time.Minute
+)
+
+var (
+	ErrWebhookAddress = errors.New("the webhook resolves to a private address")
+
+	webhookClient        = NewWebhookClient(false)
+	webhookPrivateClient = NewWebhookClient(true)
+
+	// not global unicast but not caught by netip either
+	webhookReservedPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+	}
+)
+
+// NewWebhookClient returns the client the deliveries are sent with. It
+// doesn't follow redirects, a receiver that moved has to be updated, and
+// unless allowPrivate it refuses to connect to the loopback, private and
+// reserved addresses, whatever the url resolves to.
+func NewWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: WebhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkWebhookAddress(address)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect for us, past the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   WebhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func checkWebhookAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() {
+		return ErrWebhookAddress
+	}
+	for _, prefix := range webhookReservedPrefixes {
+		if prefix.Contains(addr) {
+			return ErrWebhookAddress
+		}
+	}
+	return nil
+}
+
+func (app App) HandleCreateWebhook(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	uuidStr, ok := c.Get("userID").(string)
+	if uuidStr == "" || !ok {
+		return echo.ErrForbidden
+	}
+	userUUID, err := UUIDFromString(uuidStr)
+	if err != nil {
+		return echo.ErrForbidden
+	}
+
+	var req schemas.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		req.Secret = hex.EncodeToString(b)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	hook, err := app.DB.Queries.CreateWebhook(ctx, database.CreateWebhookParams{
+		UserID:     userUUID,
+		Url:        req.URL,
+		EventTypes: eventTypeStrings(req.EventTypes),
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	res := webhookFromDB(hook)
+	// shown once, the receiver needs it to check the signatures
+	res.Secret = hook.Secret
+	return c.JSON(http.StatusOK, res)
+}
+
+func (app App) HandleGetWebhooks(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	hooks, err := app.DB.Queries.GetWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	res := schemas.GetWebhooksResponse{Webhooks: make([]schemas.Webhook, len(hooks))}
+	for i, hook := range hooks {
+		res.Webhooks[i] = webhookFromDB(hook)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (app App) HandleGetWebhook(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	hook, err := app.DB.Queries.GetWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	return c.JSON(http.StatusOK, webhookFromDB(hook))
+}
+
+func (app App) HandlePatchWebhook(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.PatchWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	params := database.UpdateWebhookParams{
+		ID:      id,
+		Url:     req.URL,
+		Secret:  req.Secret,
+		Enabled: req.Enabled,
+	}
+	if req.EventTypes != nil {
+		// not nil even if empty, NULL would keep the types
+		params.EventTypes = eventTypeStrings(*req.EventTypes)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	hook, err := app.DB.Queries.UpdateWebhook(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	return c.JSON(http.StatusOK, webhookFromDB(hook))
+}
+
+func (app App) HandleDeleteWebhook(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	n, err := app.DB.Queries.DeleteWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return echo.ErrNotFound
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleGetWebhookDeliveries lists the deliveries of a webhook, the newest
+// first.
+func (app App) HandleGetWebhookDeliveries(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	id, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var req schemas.GetWebhookDeliveriesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if req.Limit == 0 {
+		req.Limit = schemas.GetWebhookDeliveriesRequestDefaultLimit
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase*2)
+	defer cancel()
+	if _, err := app.DB.Queries.GetWebhook(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	params := database.GetWebhookDeliveriesParams{
+		WebhookID: id,
+		Limit:     int32(req.Limit),
+		Offset:    int32(req.Offset),
+	}
+	if req.Status != "" {
+		params.Status = PtrFromString(string(req.Status))
+	}
+	found, err := app.DB.Queries.GetWebhookDeliveries(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	res := schemas.GetWebhookDeliveriesResponse{
+		NResults:   len(found),
+		Deliveries: make([]schemas.WebhookDelivery, len(found)),
+	}
+	for i, d := range found {
+		if res.Deliveries[i], err = webhookDeliveryFromDB(d); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// HandleReplayWebhookDelivery sends a delivery again as a new one, whatever
+// became of it.
+func (app App) HandleReplayWebhookDelivery(c echo.Context) error {
+	if !IsAppropriateRole(c.Get("userRole"), schemas.RoleAdmin) {
+		return echo.ErrForbidden
+	}
+	hookID, err := UUIDFromString(c.Param("uuid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	id, err := UUIDFromString(c.Param("delivery"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), TimeoutDatabase)
+	defer cancel()
+	d, err := app.DB.Queries.ReplayWebhookDelivery(ctx, database.ReplayWebhookDeliveryParams{ID: id, WebhookID: hookID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	res, err := webhookDeliveryFromDB(d)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, res)
+}
+
+// DeliverWebhooks attempts the due deliveries every so often until ctx is
+// done.
+func (app App) DeliverWebhooks(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := app.DeliverDueWebhooks(ctx); err != nil && ctx.Err() == nil {
+				app.Logger.Error("error delivering webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// DeliverDueWebhooks attempts the deliveries that are due, a batch at a
+// time, and returns how many it attempted. The webhooks are attempted
+// concurrently, the deliveries of each one by one.
+func (app App) DeliverDueWebhooks(ctx context.Context) (int, error) {
+	client := webhookClient
+	if app.Config.WebhookAllowPrivate {
+		client = webhookPrivateClient
+	}
+
+	n := 0
+	for {
+		dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase*2)
+		due, err := app.DB.Queries.ClaimWebhookDeliveries(dbCtx, database.ClaimWebhookDeliveriesParams{
+			LeaseUntil: pgtype.Timestamptz{Time: time.Now().Add(webhookLease), Valid: true},
+			N:          webhookBatch,
+		})
+		cancel()
+		if err != nil {
+			return n, err
+		}
+		// the next delivery of a webhook is claimed once the one before is
+		// done, until none is due
+		if len(due) == 0 {
+			return n, nil
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range due {
+			wg.Go(func() { app.attemptWebhookDelivery(ctx, client, d) })
+		}
+		wg.Wait()
+		n += len(due)
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+	}
+}
+
+func (app App) attemptWebhookDelivery(ctx context.Context, client *http.Client, d database.ClaimWebhookDeliveriesRow) {
+	status, sendErr := SendWebhook(ctx, client, d.Url, d.Secret, d.ID.String(), d.EventType, d.Payload)
+
+	now := time.Now()
+	params := database.FinishWebhookAttemptParams{
+		ID:            d.ID,
+		Status:        string(schemas.WebhookDeliverySucceeded),
+		NextAttemptAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	if status != 0 {
+		code := int32(status)
+		params.ResponseStatus = &code
+	}
+	if sendErr != nil {
+		params.Error = PtrFromString(sendErr.Error())
+		attempts := int(d.Attempts) + 1
+		if attempts >= WebhookMaxAttempts {
+			params.Status = string(schemas.WebhookDeliveryFailed)
+		} else {
+			params.Status = string(schemas.WebhookDeliveryPending)
+			params.NextAttemptAt.Time = now.Add(WebhookBackoff(attempts))
+		}
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, TimeoutDatabase*2)
+	defer cancel()
+	if err := app.DB.Queries.FinishWebhookAttempt(dbCtx, params); err != nil {
+		// the lease runs out and it's attempted again
+		app.Logger.Error("error recording webhook attempt", zap.Error(err), zap.String("delivery", d.ID.String()))
+		return
+	}
+
+	if sendErr == nil {
+		if err := app.DB.Queries.RecordWebhookSuccess(dbCtx, d.WebhookID); err != nil {
+			app.Logger.Error("error resetting webhook failures", zap.Error(err), zap.String("webhook", d.WebhookID.String()))
+		}
+		return
+	}
+	app.Logger.Warn("webhook delivery failed",
+		zap.Error(sendErr),
+		zap.String("webhook", d.WebhookID.String()),
+		zap.String("delivery", d.ID.String()),
+		zap.String("status", params.Status),
+	)
+	givenUp := params.Status == string(schemas.WebhookDeliveryFailed)
+	if _, err := app.DB.Queries.RecordWebhookFailure(dbCtx, database.RecordWebhookFailureParams{
+		ID:      d.WebhookID,
+		Disable: givenUp,
+	}); err != nil {
+		app.Logger.Error("error counting webhook failures", zap.Error(err), zap.String("webhook", d.WebhookID.String()))
+		return
+	}
+	if givenUp {
+		app.Logger.Warn("disabled webhook after a delivery was given up on", zap.String("webhook", d.WebhookID.String()))
+	}
+}
+
+// WebhookBackoff is how long to wait after the nth failed attempt.
+func WebhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return webhookBackoff
+	}
+	d := webhookBackoff << min(attempts-1, 20)
+	return min(d, webhookMaxBackoff)
+}
+
+// SignWebhook is what HeaderWebhookSignature is set to.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendWebhook posts a signed delivery and returns the status the receiver
+// answered with, an error unless it's 2xx.
+func SendWebhook(ctx context.Context, client *http.Client, url, secret, deliveryID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("User-Agent", "warehouse-webhooks/1.0")
+	req.Header.Set(HeaderWebhookDelivery, deliveryID)
+	req.Header.Set(HeaderWebhookEvent, eventType)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(secret, timestamp, body))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// the answer isn't kept, whatever the receiver put in it; read so the
+	// connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("the receiver answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+func webhookFromDB(hook database.Webhook) schemas.Webhook {
+	types := make([]schemas.EventType, len(hook.EventTypes))
+	for i, t := range hook.EventTypes {
+		types[i] = schemas.EventType(t)
+	}
+	return schemas.Webhook{
+		UUID:       hook.ID.String(),
+		URL:        hook.Url,
+		EventTypes: types,
+		Enabled:    !hook.DisabledAt.Valid,
+		Failures:   int(hook.Failures),
+		DisabledAt: UnixOrZero(hook.DisabledAt),
+		CreatedAt:  hook.CreatedAt.Time.Unix(),
+	}
+}
+
+func webhookDeliveryFromDB(d database.WebhookDelivery) (schemas.WebhookDelivery, error) {
+	res := schemas.WebhookDelivery{
+		UUID:        d.ID.String(),
+		WebhookUUID: d.WebhookID.String(),
+		Status:      schemas.WebhookDeliveryStatus(d.Status),
+		Attempts:    int(d.Attempts),
+		Error:       StringFromPtr(d.Error),
+		ReplayOf:    d.ReplayOf.String(),
+		CreatedAt:   d.CreatedAt.Time.Unix(),
+		DeliveredAt: UnixOrZero(d.DeliveredAt),
+	}
+	if res.Status == schemas.WebhookDeliveryPending {
+		res.NextAttemptAt = d.NextAttemptAt.Time.Unix()
+	}
+	if d.ResponseStatus != nil {
+		res.ResponseStatus = int(*d.ResponseStatus)
+	}
+	err := json.Unmarshal(d.Payload, &res.Event)
+	return res, err
+}
+
+func eventTypeStrings(types []schemas.EventType) []string {
+	res := make([]string, len(types))
+	for i, t := range types {
+		res[i] = string(t)
+	}
+	return res
+}
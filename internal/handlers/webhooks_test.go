@@ -0,0 +1,301 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a webhook endpoint that checks the signatures and answers
+// with status.
+type receiver struct {
+	*httptest.Server
+	secret string
+	status atomic.Int32
+
+	mu     sync.Mutex
+	events []schemas.Event
+}
+
+func newReceiver(t *testing.T, secret string) *receiver {
+	t.Helper()
+
+	r := &receiver{secret: secret}
+	r.status.Store(http.StatusNoContent)
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(r.secret))
+		mac.Write([]byte(req.Header.Get(handlers.HeaderWebhookTimestamp) + "." + string(body)))
+		if req.Header.Get(handlers.HeaderWebhookSignature) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var e schemas.Event
+		if err := json.Unmarshal(body, &e); err != nil || string(e.Type) != req.Header.Get(handlers.HeaderWebhookEvent) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		status := int(r.status.Load())
+		if status < 300 {
+			r.mu.Lock()
+			r.events = append(r.events, e)
+			r.mu.Unlock()
+		}
+		http.Error(w, "answered "+strconv.Itoa(status), status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// received returns the events of the item that were accepted.
+func (r *receiver) received(itemUUID string) []schemas.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []schemas.Event
+	for _, e := range r.events {
+		if e.ItemUUID == itemUUID {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func TestSendWebhook(t *testing.T) {
+	r := newReceiver(t, "0123456789abcdef")
+	body := []byte(`{"id": 1, "type": "item.created", "item_uuid": "0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b"}`)
+
+	status, err := handlers.SendWebhook(context.Background(), http.DefaultClient, r.URL, r.secret, "d1", "item.created", body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+	require.Len(t, r.received("0198f5a8-7c5e-7d43-9b8e-0a4c9f2d1e6b"), 1)
+
+	status, err = handlers.SendWebhook(context.Background(), http.DefaultClient, r.URL, "the wrong secret", "d2", "item.created", body)
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.ErrorContains(t, err, "answered 401")
+	require.NotContains(t, err.Error(), "bad signature")
+
+	r.status.Store(http.StatusServiceUnavailable)
+	status, err = handlers.SendWebhook(context.Background(), http.DefaultClient, r.URL, r.secret, "d3", "item.created", body)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.ErrorContains(t, err, "answered 503")
+
+	// the receiver listens on the loopback
+	r.status.Store(http.StatusNoContent)
+	status, err = handlers.SendWebhook(context.Background(), handlers.NewWebhookClient(false), r.URL, r.secret, "d4", "item.created", body)
+	require.Zero(t, status)
+	require.ErrorIs(t, err, handlers.ErrWebhookAddress)
+	status, err = handlers.SendWebhook(context.Background(), handlers.NewWebhookClient(true), r.URL, r.secret, "d5", "item.created", body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+
+	r.Close()
+	status, err = handlers.SendWebhook(context.Background(), http.DefaultClient, r.URL, r.secret, "d6", "item.created", body)
+	require.Zero(t, status)
+	require.Error(t, err)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, handlers.WebhookBackoff(1))
+	require.Equal(t, time.Minute, handlers.WebhookBackoff(2))
+	require.Equal(t, 8*time.Minute, handlers.WebhookBackoff(5))
+	require.Equal(t, 256*time.Minute, handlers.WebhookBackoff(10))
+	require.Equal(t, 6*time.Hour, handlers.WebhookBackoff(100))
+}
+
+func TestWebhooks(t *testing.T) {
+	app := testApp(t)
+	app.Config.JWTAccessSecret = []byte("access")
+	// the receiver listens on the loopback
+	app.Config.WebhookAllowPrivate = true
+	ctx := context.Background()
+
+	usr := newTestUser(t, app, schemas.RoleAdmin)
+	token, err := handlers.GenerateAccessJWT(usr.ID.String(), "admin", app.Config.JWTAccessSecret, time.Minute)
+	require.NoError(t, err)
+
+	r := router.New(app, handlers.RateLimiter{}, handlers.RateLimiter{})
+	call := func(method, target, body string, res any) int {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if res != nil && rec.Code < 300 {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+		}
+		return rec.Code
+	}
+	deliver := func() {
+		t.Helper()
+		_, err := app.DeliverDueWebhooks(ctx)
+		require.NoError(t, err)
+	}
+
+	rcv := newReceiver(t, "")
+	var hook schemas.Webhook
+	body := fmt.Sprintf(`{"url": %q, "event_types": ["item.created", "item.updated", "transaction.created"]}`, rcv.URL)
+	require.Equal(t, http.StatusOK, call(http.MethodPost, "/webhooks", body, &hook))
+	require.Len(t, hook.Secret, 64)
+	require.True(t, hook.Enabled)
+	rcv.secret = hook.Secret
+	hookID, err := handlers.UUIDFromString(hook.UUID)
+	require.NoError(t, err)
+
+	item := newTestItem(t, app, "hooked")
+
+	deliver()
+	got := rcv.received(item.Uuid.String())
+	require.Len(t, got, 1)
+	require.Equal(t, schemas.EventItemCreated, got[0].Type)
+	require.Equal(t, "hooked", got[0].Item.Name)
+
+	var deliveries schemas.GetWebhookDeliveriesResponse
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/webhooks/"+hook.UUID+"/deliveries?status=succeeded", "", &deliveries))
+	require.Equal(t, 1, deliveries.NResults)
+	delivered := deliveries.Deliveries[0]
+	require.Equal(t, 1, delivered.Attempts)
+	require.Equal(t, http.StatusNoContent, delivered.ResponseStatus)
+	require.Equal(t, got[0], delivered.Event)
+
+	// the receiver is down, the first of the transaction and the new
+	// quantity is retried, the other one waits for it
+	rcv.status.Store(http.StatusInternalServerError)
+	_, err = app.CreateTransaction(ctx, usr.ID, schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: item.Uuid.String(),
+		Amount:   2,
+	})
+	require.NoError(t, err)
+	deliver()
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/webhooks/"+hook.UUID+"/deliveries?status=pending", "", &deliveries))
+	require.Equal(t, 2, deliveries.NResults)
+	attempts := map[int]int{}
+	for _, d := range deliveries.Deliveries {
+		attempts[d.Attempts]++
+		if d.Attempts == 1 {
+			require.Equal(t, http.StatusInternalServerError, d.ResponseStatus)
+			require.Equal(t, "the receiver answered 500 Internal Server Error", d.Error)
+			require.Greater(t, d.NextAttemptAt, time.Now().Add(20*time.Second).Unix())
+		}
+	}
+	require.Equal(t, map[int]int{0: 1, 1: 1}, attempts)
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/webhooks/"+hook.UUID, "", &hook))
+	require.Equal(t, 1, hook.Failures)
+
+	// a delivery given up on disables it
+	_, err = app.DB.Pool.Exec(ctx, "UPDATE webhook_deliveries SET attempts = $1, next_attempt_at = now() WHERE webhook_id = $2 AND status = 'pending'", handlers.WebhookMaxAttempts-1, hookID)
+	require.NoError(t, err)
+	deliver()
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/webhooks/"+hook.UUID, "", &hook))
+	require.False(t, hook.Enabled)
+	require.NotZero(t, hook.DisabledAt)
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/webhooks/"+hook.UUID+"/deliveries?status=failed", "", &deliveries))
+	require.Equal(t, 1, deliveries.NResults)
+	failed := deliveries.Deliveries[0]
+	require.Equal(t, handlers.WebhookMaxAttempts, failed.Attempts)
+
+	// nothing is queued or sent while it's disabled
+	var replayed schemas.WebhookDelivery
+	require.Equal(t, http.StatusAccepted, call(http.MethodPost, "/webhooks/"+hook.UUID+"/deliveries/"+delivered.UUID+"/replay", "", &replayed))
+	require.Equal(t, delivered.UUID, replayed.ReplayOf)
+	require.Equal(t, schemas.WebhookDeliveryPending, replayed.Status)
+	rcv.status.Store(http.StatusOK)
+	_, err = app.DB.Pool.Exec(ctx, "UPDATE webhook_deliveries SET next_attempt_at = now() WHERE webhook_id = $1 AND status = 'pending'", hookID)
+	require.NoError(t, err)
+	deliver()
+	require.Len(t, rcv.received(item.Uuid.String()), 1)
+
+	// enabled again, the replay and the one left go through, in order
+	require.Equal(t, http.StatusOK, call(http.MethodPatch, "/webhooks/"+hook.UUID, `{"enabled": true}`, &hook))
+	require.True(t, hook.Enabled)
+	require.Zero(t, hook.Failures)
+	deliver()
+	got = rcv.received(item.Uuid.String())
+	require.Len(t, got, 3)
+	for i := 1; i < len(got); i++ {
+		require.LessOrEqual(t, got[i-1].ID, got[i].ID)
+	}
+
+	// and the one given up on once it's replayed
+	require.Equal(t, http.StatusAccepted, call(http.MethodPost, "/webhooks/"+hook.UUID+"/deliveries/"+failed.UUID+"/replay", "", &replayed))
+	deliver()
+	got = rcv.received(item.Uuid.String())
+	require.Len(t, got, 4)
+	types := map[schemas.EventType]int{}
+	for _, e := range got {
+		types[e.Type]++
+	}
+	require.Equal(t, map[schemas.EventType]int{
+		schemas.EventItemCreated:        2,
+		schemas.EventItemUpdated:        1,
+		schemas.EventTransactionCreated: 1,
+	}, types)
+
+	require.Equal(t, http.StatusNotFound, call(http.MethodPost, "/webhooks/"+hook.UUID+"/deliveries/"+hook.UUID+"/replay", "", nil))
+	require.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/webhooks", `{"url": "ftp://example.com"}`, nil))
+}
+
+func TestWebhooksOrder(t *testing.T) {
+	app := testApp(t)
+	app.Config.WebhookAllowPrivate = true
+	ctx := context.Background()
+
+	rcv := newReceiver(t, "0123456789abcdef")
+	_, err := app.DB.Queries.CreateWebhook(ctx, database.CreateWebhookParams{
+		UserID:     newTestUser(t, app, schemas.RoleAdmin).ID,
+		Url:        rcv.URL,
+		EventTypes: []string{string(schemas.EventItemCreated)},
+		Secret:     rcv.secret,
+	})
+	require.NoError(t, err)
+
+	// takes its event id first, but commits after the next one
+	tx, err := app.DB.Pool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+	slow, err := app.DB.Queries.WithTx(tx).CreateItem(ctx, database.CreateItemParams{Name: "slow"})
+	require.NoError(t, err)
+	fast := newTestItem(t, app, "fast")
+
+	// sending the fast one would put it before the slow one
+	_, err = app.DeliverDueWebhooks(ctx)
+	require.NoError(t, err)
+	require.Empty(t, rcv.received(fast.Uuid.String()))
+
+	require.NoError(t, tx.Commit(ctx))
+	// other transactions of the cluster hold them back too
+	require.Eventually(t, func() bool {
+		_, err := app.DeliverDueWebhooks(ctx)
+		require.NoError(t, err)
+		return len(rcv.received(fast.Uuid.String())) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	require.Len(t, rcv.events, 2)
+	require.Equal(t, slow.Uuid.String(), rcv.events[0].ItemUUID)
+	require.Equal(t, fast.Uuid.String(), rcv.events[1].ItemUUID)
+}
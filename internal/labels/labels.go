@@ -0,0 +1,181 @@
+// Package labels renders shelf, bin and item labels: single Code128 or QR
+// symbols as PNG or SVG, and printable sheets of them as PDF. Everything is
+// drawn locally, no external services are involved.
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+type Symbology string
+
+const (
+	SymbologyCode128 Symbology = "code128"
+	SymbologyQR      Symbology = "qr"
+)
+
+var (
+	ErrUnknownSymbology = errors.New("unknown symbology")
+	ErrEmptyContent     = errors.New("nothing to encode")
+)
+
+const (
+	// quiet zones, in modules, as required by the specs
+	quietZone1D = 10
+	quietZone2D = 4
+
+	// height of 1D bars, in modules
+	barHeight = 40
+
+	DefaultScale = 4
+)
+
+// Encode turns content into a symbol of the given kind.
+func Encode(sym Symbology, content string) (barcode.Barcode, error) {
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+
+	switch sym {
+	case SymbologyCode128:
+		return code128.Encode(content)
+	case SymbologyQR:
+		return qr.Encode(content, qr.M, qr.Auto)
+	default:
+		return nil, ErrUnknownSymbology
+	}
+}
+
+// grid is a symbol unpacked into rows of dark (true) and light modules,
+// quiet zone included.
+type grid struct {
+	rows [][]bool
+	// whether the single row of a 1D symbol should be stretched into bars
+	linear bool
+}
+
+func newGrid(bc barcode.Barcode) grid {
+	b := bc.Bounds()
+	linear := bc.Metadata().Dimensions == 1
+
+	quiet := quietZone2D
+	nRows := b.Dy() + 2*quiet
+	if linear {
+		quiet = quietZone1D
+		nRows = 1
+	}
+	nCols := b.Dx() + 2*quiet
+
+	rows := make([][]bool, nRows)
+	for y := range nRows {
+		rows[y] = make([]bool, nCols)
+	}
+	for y := range nRows {
+		srcY := b.Min.Y
+		if !linear {
+			srcY += y - quiet
+			if srcY < b.Min.Y || srcY >= b.Max.Y {
+				continue
+			}
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rows[y][x-b.Min.X+quiet] = isDark(bc.At(x, srcY))
+		}
+	}
+
+	return grid{rows: rows, linear: linear}
+}
+
+func (g grid) width() int {
+	return len(g.rows[0])
+}
+
+// height returns the height in modules once 1D bars are stretched.
+func (g grid) height() int {
+	if g.linear {
+		return barHeight
+	}
+	return len(g.rows)
+}
+
+// runs calls fn for every horizontal run of dark modules.
+func (g grid) runs(fn func(x, y, w, h int)) {
+	h := 1
+	if g.linear {
+		h = barHeight
+	}
+	for y, row := range g.rows {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fn(start, y, x-start, h)
+		}
+	}
+}
+
+func isDark(c color.Color) bool {
+	gray := color.GrayModel.Convert(c).(color.Gray)
+	return gray.Y < 128
+}
+
+// WritePNG writes the symbol as a PNG where every module is scale pixels wide.
+func WritePNG(w io.Writer, bc barcode.Barcode, scale int) error {
+	if scale <= 0 {
+		scale = DefaultScale
+	}
+	g := newGrid(bc)
+
+	img := image.NewGray(image.Rect(0, 0, g.width()*scale, g.height()*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	g.runs(func(x, y, w, h int) {
+		for py := y * scale; py < (y+h)*scale; py++ {
+			for px := x * scale; px < (x+w)*scale; px++ {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			}
+		}
+	})
+
+	return png.Encode(w, img)
+}
+
+// WriteSVG writes the symbol as an SVG document. The drawing is done in
+// module units, scale only sets the default rendered size.
+func WriteSVG(w io.Writer, bc barcode.Barcode, scale int) error {
+	if scale <= 0 {
+		scale = DefaultScale
+	}
+	g := newGrid(bc)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		g.width()*scale, g.height()*scale, g.width(), g.height(),
+	)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/>`, g.width(), g.height())
+	sb.WriteString(`<path fill="#000" d="`)
+	g.runs(func(x, y, w, h int) {
+		fmt.Fprintf(&sb, "M%d %dh%dv%dh-%dz", x, y, w, h, w)
+	})
+	sb.WriteString(`"/></svg>`)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
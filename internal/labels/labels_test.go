@@ -0,0 +1,45 @@
+package labels_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	for _, sym := range []labels.Symbology{labels.SymbologyCode128, labels.SymbologyQR} {
+		bc, err := labels.Encode(sym, "4006381333931")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, labels.WritePNG(&buf, bc, 2))
+		require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG")))
+
+		buf.Reset()
+		require.NoError(t, labels.WriteSVG(&buf, bc, 2))
+		require.Contains(t, buf.String(), "<svg")
+	}
+
+	_, err := labels.Encode("ean99", "123")
+	require.ErrorIs(t, err, labels.ErrUnknownSymbology)
+}
+
+func TestWriteSheet(t *testing.T) {
+	ls := make([]labels.Label, 30) // more than one A4 page
+	for i := range ls {
+		ls[i] = labels.Label{Title: "Glove", Content: "BIN-A12-03"}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, labels.WriteSheet(&buf, labels.LayoutA4, labels.SymbologyCode128, ls))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF")))
+
+	buf.Reset()
+	require.NoError(t, labels.WriteSheet(&buf, labels.LayoutThermal4x6, labels.SymbologyQR, ls[:2]))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF")))
+
+	err := labels.WriteSheet(&buf, "letter", labels.SymbologyQR, ls)
+	require.ErrorIs(t, err, labels.ErrUnknownLayout)
+}
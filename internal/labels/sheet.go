@@ -0,0 +1,140 @@
+package labels
+
+import (
+	"errors"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+type Layout string
+
+const (
+	// 3x8 grid of 70x37mm labels on A4
+	LayoutA4 Layout = "a4"
+	// one 4x6in label per page, for thermal printers
+	LayoutThermal4x6 Layout = "thermal_4x6"
+)
+
+var ErrUnknownLayout = errors.New("unknown layout")
+
+type Label struct {
+	// printed in bold above the caption, e.g. the item name
+	Title string
+	// encoded into the symbol and printed under it
+	Content string
+}
+
+type pageLayout struct {
+	pageW, pageH     float64
+	cols, rows       int
+	labelW, labelH   float64
+	marginX, marginY float64
+	padding          float64
+	titleSize        float64
+	captionSize      float64
+}
+
+// all sizes are in millimeters, fonts in points
+var layouts = map[Layout]pageLayout{
+	LayoutA4: {
+		pageW: 210, pageH: 297,
+		cols: 3, rows: 8,
+		labelW: 70, labelH: 37,
+		marginX: 0, marginY: 0.5,
+		padding:   3,
+		titleSize: 9, captionSize: 7,
+	},
+	LayoutThermal4x6: {
+		pageW: 101.6, pageH: 152.4,
+		cols: 1, rows: 1,
+		labelW: 101.6, labelH: 152.4,
+		padding:   6,
+		titleSize: 20, captionSize: 12,
+	},
+}
+
+// WriteSheet lays the labels out on as many pages as needed and writes the
+// resulting PDF.
+func WriteSheet(w io.Writer, layout Layout, sym Symbology, labels []Label) error {
+	pl, ok := layouts[layout]
+	if !ok {
+		return ErrUnknownLayout
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "mm",
+		Size:    gofpdf.SizeType{Wd: pl.pageW, Ht: pl.pageH},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFillColor(0, 0, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // cp1252 for the core fonts
+
+	perPage := pl.cols * pl.rows
+	for i, l := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		n := i % perPage
+		x := pl.marginX + float64(n%pl.cols)*pl.labelW
+		y := pl.marginY + float64(n/pl.cols)*pl.labelH
+
+		bc, err := Encode(sym, l.Content)
+		if err != nil {
+			return err
+		}
+		drawLabel(pdf, pl, x, y, newGrid(bc), tr(l.Title), tr(l.Content))
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	return pdf.Output(w)
+}
+
+func drawLabel(pdf *gofpdf.Fpdf, pl pageLayout, x, y float64, g grid, title, caption string) {
+	pad := pl.padding
+	innerW := pl.labelW - 2*pad
+	innerH := pl.labelH - 2*pad
+	titleH := pl.titleSize * 0.4 // pt to mm, with some leading
+	captionH := pl.captionSize * 0.4
+
+	// symbol box, text goes under 1D symbols and next to 2D ones
+	symX, symY := x+pad, y+pad+titleH
+	symW, symH := innerW, innerH-titleH-captionH
+	textX, textW := x+pad, innerW
+	if !g.linear {
+		side := min(innerH-titleH, innerW/2)
+		symW, symH = side, side
+		textX, textW = symX+side+pad/2, innerW-side-pad/2
+	}
+
+	module := symW / float64(g.width())
+	barH := symH
+	if !g.linear {
+		module = min(module, symH/float64(g.height()))
+		barH = module
+	}
+	g.runs(func(mx, my, mw, _ int) {
+		pdf.Rect(symX+float64(mx)*module, symY+float64(my)*module, float64(mw)*module, barH, "F")
+	})
+
+	pdf.SetFont("Helvetica", "B", pl.titleSize)
+	pdf.Text(x+pad, y+pad+titleH*0.8, fit(pdf, title, innerW))
+
+	pdf.SetFont("Helvetica", "", pl.captionSize)
+	captionY := symY + symH + captionH*0.8
+	if !g.linear {
+		captionY = symY + captionH
+	}
+	pdf.Text(textX, captionY, fit(pdf, caption, textW))
+}
+
+// fit cuts s until it fits into w with the current font.
+func fit(pdf *gofpdf.Fpdf, s string, w float64) string {
+	for s != "" && pdf.GetStringWidth(s) > w {
+		s = s[:len(s)-1]
+	}
+	return s
+}
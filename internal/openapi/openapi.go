@@ -0,0 +1,219 @@
+// Package openapi builds an OpenAPI 3.1 document, with the schemas derived
+// from Go structs by their json and validate tags.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+// Operation returns the operation of the method, nil if there is none.
+func (p *PathItem) Operation(method string) *Operation {
+	if op := p.slot(method); op != nil {
+		return *op
+	}
+	return nil
+}
+
+func (p *PathItem) slot(method string) **Operation {
+	switch method {
+	case http.MethodGet:
+		return &p.Get
+	case http.MethodPost:
+		return &p.Post
+	case http.MethodPut:
+		return &p.Put
+	case http.MethodPatch:
+		return &p.Patch
+	case http.MethodDelete:
+		return &p.Delete
+	}
+	return nil
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query, header or cookie
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// SecurityRequirement maps a scheme to its scopes, empty for non-OAuth ones.
+type SecurityRequirement map[string][]string
+
+// Schema is the subset of JSON Schema 2020-12 the API needs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+}
+
+// Generator collects the operations and the schemas they refer to.
+type Generator struct {
+	doc       *Document
+	overrides map[reflect.Type]*Schema
+}
+
+func New(info Info) *Generator {
+	return &Generator{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   map[string]*PathItem{},
+			Components: Components{
+				Schemas:         map[string]*Schema{},
+				SecuritySchemes: map[string]SecurityScheme{},
+			},
+		},
+		overrides: map[reflect.Type]*Schema{},
+	}
+}
+
+// Override sets the schema of the type of v, for the types with their own
+// JSON encoding.
+func (g *Generator) Override(v any, s *Schema) {
+	g.overrides[reflect.TypeOf(v)] = s
+}
+
+func (g *Generator) SecurityScheme(name string, s SecurityScheme) {
+	g.doc.Components.SecuritySchemes[name] = s
+}
+
+var echoParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Add registers the operation at an echo route path, the path parameters
+// are added to it unless it has them already.
+func (g *Generator) Add(method, path string, op *Operation) {
+	for _, m := range echoParam.FindAllStringSubmatch(path, -1) {
+		if !hasParameter(op.Parameters, m[1], "path") {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     m[1],
+				In:       "path",
+				Required: true,
+				Schema:   pathParameterSchema(m[1]),
+			})
+		}
+	}
+
+	p := Path(path)
+	item, ok := g.doc.Paths[p]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[p] = item
+	}
+	slot := item.slot(method)
+	if slot == nil {
+		panic("openapi: unsupported method " + method)
+	}
+	*slot = op
+}
+
+func (g *Generator) Document() *Document {
+	return g.doc
+}
+
+// Path converts an echo route path to an OpenAPI one.
+func Path(echoPath string) string {
+	return echoParam.ReplaceAllString(echoPath, "{$1}")
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func pathParameterSchema(name string) *Schema {
+	if name == "uuid" || strings.HasSuffix(name, "_uuid") {
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+	return &Schema{Type: "string"}
+}
@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema returns the schema of the type of v. Named structs are added to
+// the components and referred to.
+func (g *Generator) Schema(v any) *Schema {
+	return g.schemaOf(reflect.TypeOf(v))
+}
+
+// QueryParameters describes the fields of the struct v with a query tag.
+func (g *Generator) QueryParameters(v any) []Parameter {
+	var params []Parameter
+	g.eachField(reflect.TypeOf(v), "query", func(name string, f reflect.StructField) {
+		s := g.schemaOf(f.Type)
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: applyValidate(s, f.Type, f.Tag.Get("validate")),
+			Schema:   s,
+		})
+	})
+	return params
+}
+
+func (g *Generator) schemaOf(t reflect.Type) *Schema {
+	if s, ok := g.overrides[t]; ok {
+		c := *s
+		return &c
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := g.doc.Components.Schemas[name]; !ok {
+			// registered first, the struct may refer to itself
+			s := &Schema{}
+			g.doc.Components.Schemas[name] = s
+			*s = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		// any value
+		return &Schema{}
+	}
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.eachField(t, "json", func(name string, f reflect.StructField) {
+		prop := g.schemaOf(f.Type)
+		if applyValidate(prop, f.Type, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	})
+	return s
+}
+
+// eachField calls fn for the fields of the struct as encoding/json sees
+// them, named by the tag. Fields without the tag are skipped for the query
+// tag and keep their Go name for json.
+func (g *Generator) eachField(t reflect.Type, tag string, fn func(name string, f reflect.StructField)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.eachField(ft, tag, fn)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			if tag != "json" {
+				continue
+			}
+			name = f.Name
+		}
+		fn(name, f)
+	}
+}
+
+// applyValidate adds the constraints of the validate tag to s and reports
+// whether the field is required. Rules after dive apply to the elements.
+func applyValidate(s *Schema, t reflect.Type, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	target, top := s, true
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			var next *Schema
+			switch {
+			case target.Items != nil:
+				next = target.Items
+			case target.AdditionalProperties != nil:
+				next = target.AdditionalProperties
+			}
+			if next == nil {
+				return required
+			}
+			target, top, t = next, false, t.Elem()
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+		case "required":
+			if top {
+				required = true
+			} else if t.Kind() == reflect.String {
+				target.MinLength = intPtr(1)
+			}
+		case "min", "gte":
+			setBound(target, t, param, true)
+		case "max", "lte":
+			setBound(target, t, param, false)
+		case "len":
+			setBound(target, t, param, true)
+			setBound(target, t, param, false)
+		case "oneof":
+			target.Enum = nil
+			for _, v := range strings.Fields(param) {
+				if n, err := strconv.ParseInt(v, 10, 64); err == nil && target.Type == "integer" {
+					target.Enum = append(target.Enum, n)
+				} else {
+					target.Enum = append(target.Enum, v)
+				}
+			}
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "email":
+			target.Format = "email"
+		case "url", "http_url":
+			target.Format = "uri"
+		}
+	}
+	return required
+}
+
+// setBound sets the lower or upper bound, which is a length for strings and
+// lists and a value for numbers.
+func setBound(s *Schema, t reflect.Type, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if lower {
+			s.MinLength = intPtr(int(n))
+		} else {
+			s.MaxLength = intPtr(int(n))
+		}
+	case reflect.Map:
+		// minProperties and maxProperties, nothing uses them
+	case reflect.Slice, reflect.Array:
+		if lower {
+			s.MinItems = intPtr(int(n))
+		} else {
+			s.MaxItems = intPtr(int(n))
+		}
+	default:
+		if lower {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,87 @@
+package openapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+type testLine struct {
+	SKU    string   `validate:"required,max=32" json:"sku"`
+	Amount int      `validate:"required,min=1" json:"amount"`
+	Tags   []string `validate:"omitempty,max=5,dive,required" json:"tags,omitempty"`
+	Note   *string  `json:"note"`
+	secret string
+}
+
+type testOrder struct {
+	ID     string            `validate:"required,uuid" json:"id"`
+	Mode   string            `validate:"omitempty,oneof=fast slow" json:"mode"`
+	Lines  []testLine        `validate:"required,min=1,dive" json:"lines"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Parent *testOrder        `json:"parent,omitempty"`
+	Legacy string
+	Hidden string `json:"-"`
+}
+
+type testQuery struct {
+	Limit   int    `validate:"min=0,max=100" query:"limit"`
+	Cursor  string `query:"cursor"`
+	Ignored string
+}
+
+func TestSchema(t *testing.T) {
+	g := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	ref := g.Schema(testOrder{})
+	require.Equal(t, "#/components/schemas/testOrder", ref.Ref)
+
+	schemas := g.Document().Components.Schemas
+	order := schemas["testOrder"]
+	require.Equal(t, "object", order.Type)
+	require.ElementsMatch(t, []string{"id", "lines"}, order.Required)
+	require.Equal(t, "uuid", order.Properties["id"].Format)
+	require.Equal(t, []any{"fast", "slow"}, order.Properties["mode"].Enum)
+	require.Equal(t, 1, *order.Properties["lines"].MinItems)
+	require.Equal(t, "#/components/schemas/testLine", order.Properties["lines"].Items.Ref)
+	require.Equal(t, "string", order.Properties["labels"].AdditionalProperties.Type)
+	require.Equal(t, "#/components/schemas/testOrder", order.Properties["parent"].Ref)
+	require.Contains(t, order.Properties, "Legacy")
+	require.NotContains(t, order.Properties, "Hidden")
+
+	line := schemas["testLine"]
+	require.ElementsMatch(t, []string{"sku", "amount"}, line.Required)
+	require.Equal(t, 32, *line.Properties["sku"].MaxLength)
+	require.Equal(t, 1.0, *line.Properties["amount"].Minimum)
+	require.Equal(t, "integer", line.Properties["amount"].Type)
+	require.Equal(t, 5, *line.Properties["tags"].MaxItems)
+	require.Equal(t, 1, *line.Properties["tags"].Items.MinLength)
+	require.Equal(t, "string", line.Properties["note"].Type)
+	require.NotContains(t, line.Properties, "secret")
+}
+
+func TestQueryParameters(t *testing.T) {
+	g := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	params := g.QueryParameters(testQuery{})
+	require.Len(t, params, 2)
+	require.Equal(t, "limit", params[0].Name)
+	require.Equal(t, "query", params[0].In)
+	require.Equal(t, 100.0, *params[0].Schema.Maximum)
+	require.Equal(t, "cursor", params[1].Name)
+}
+
+func TestAdd(t *testing.T) {
+	g := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	g.Add(http.MethodDelete, "/items/:uuid/barcodes/:code", &openapi.Operation{})
+
+	item := g.Document().Paths["/items/{uuid}/barcodes/{code}"]
+	require.NotNil(t, item)
+	op := item.Operation(http.MethodDelete)
+	require.NotNil(t, op)
+	require.Nil(t, item.Operation(http.MethodGet))
+	require.Len(t, op.Parameters, 2)
+	require.Equal(t, "uuid", op.Parameters[0].Schema.Format)
+	require.Equal(t, "code", op.Parameters[1].Name)
+	require.True(t, op.Parameters[1].Required)
+}
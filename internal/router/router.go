@@ -0,0 +1,122 @@
+// Package router puts the handlers together, it's shared by the server and
+// the tests that need the real routes.
+package router
+
+import (
+	"github.com/bigelle/warehouse/internal/graphqlapi"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// New registers the routes, TestOpenAPICoversRoutes checks that
+// handlers.OpenAPI describes all of them.
+func New(app handlers.App, authRL, RL handlers.RateLimiter) *echo.Echo {
+	r := echo.New()
+	r.Validator = handlers.NewValidator()
+	r.HTTPErrorHandler = app.HTTPErrorHandler
+	r.Use(
+		middleware.RequestID(),
+		middleware.Recover(),
+		app.LoggingMiddleware,
+	)
+	r.Pre(middleware.RemoveTrailingSlash())
+
+	// Docs:
+	r.GET("/openapi.json", app.HandleOpenAPI)
+	r.GET("/docs", app.HandleSwaggerUI)
+	r.GET("/docs/*", app.HandleSwaggerUIAssets)
+
+	// Unprotected routes:
+	auth := r.Group("/auth", authRL.Middleware)
+	auth.POST("/register", app.HandleRegister)
+	auth.POST("/login", app.HandleLogin)
+	auth.POST("/refresh", app.HandleRefresh)
+
+	// Protected routes:
+
+	items := r.Group("/items", RL.Middleware, app.JWTMiddleware)
+	// user or higher:
+	items.GET("", app.HandleGetItems)
+	items.GET("/export", app.HandleExportItems)
+	items.GET("/:uuid", app.HandleGetSingleItem)
+	items.GET("/by-barcode/:code", app.HandleGetItemByBarcode)
+	items.GET("/:uuid/barcodes", app.HandleGetItemBarcodes)
+	items.GET("/:uuid/attachments", app.HandleGetItemAttachments)
+	items.GET("/:uuid/transactions", app.HandleGetItemTransactions)
+	items.GET("/:uuid/stock", app.HandleGetItemStock)
+	// stocker or higher:
+	items.POST("/:uuid/attachments", app.HandleUploadItemAttachment)
+	// admin only:
+	items.POST("", app.HandleCreateItem)
+	items.POST("/import", app.HandleImportItems)
+	items.GET("/import/:uuid", app.HandleGetImportJob)
+	items.PATCH("/:uuid", app.HandlePatchItem)
+	items.DELETE("/:uuid", app.HandleDeleteItem)
+	items.POST("/:uuid/archive", app.HandleArchiveItem)
+	items.POST("/:uuid/unarchive", app.HandleUnarchiveItem)
+	items.DELETE("/:uuid/purge", app.HandlePurgeItem)
+	items.POST("/:uuid/barcodes", app.HandleAddItemBarcode)
+	items.DELETE("/:uuid/barcodes/:code", app.HandleDeleteItemBarcode)
+
+	products := r.Group("/products", RL.Middleware, app.JWTMiddleware)
+	// user or higher:
+	products.GET("", app.HandleGetProducts)
+	products.GET("/:uuid", app.HandleGetSingleProduct)
+	// admin only:
+	products.POST("", app.HandleCreateProduct)
+
+	transactions := r.Group("/transactions", app.JWTMiddleware)
+	// user or higher
+	transactions.GET("", app.HandleGetAllTransactions)
+	transactions.GET("/export", app.HandleExportTransactions)
+	transactions.GET("/:uuid", app.HandleGetTransaction)
+	transactions.GET("/:uuid/attachments", app.HandleGetTransactionAttachments)
+	// stocker or higher
+	transactions.POST("", app.HandleCreateTransaction, app.IdempotencyMiddleware)
+	transactions.POST("/batch", app.HandleCreateTransactionBatch, app.IdempotencyMiddleware)
+	transactions.POST("/:uuid/attachments", app.HandleUploadTransactionAttachment)
+	// admin only
+	transactions.POST("/:uuid/reverse", app.HandleReverseTransaction)
+
+	users := r.Group("/users", RL.Middleware, app.JWTMiddleware)
+	// user or higher:
+	users.GET("/:uuid/transactions", app.HandleGetUserTransactions)
+
+	attachments := r.Group("/attachments", RL.Middleware, app.JWTMiddleware)
+	// user or higher:
+	attachments.GET("/:uuid", app.HandleDownloadAttachment)
+	attachments.GET("/:uuid/thumbnail", app.HandleDownloadAttachmentThumbnail)
+	// admin only:
+	attachments.DELETE("/:uuid", app.HandleDeleteAttachment)
+
+	// user or higher, each field checks its own role:
+	r.POST("/graphql", graphqlapi.New(app).Handle, RL.Middleware, app.JWTMiddleware)
+
+	events := r.Group("/events", RL.Middleware)
+	// user or higher:
+	events.POST("/ticket", app.HandleCreateEventsTicket, app.JWTMiddleware)
+	// user or higher, with a ticket instead of the token for browsers:
+	events.GET("", app.HandleEvents, app.EventsAuthMiddleware)
+	events.GET("/ws", app.HandleEventsWebSocket, app.EventsAuthMiddleware)
+
+	webhooks := r.Group("/webhooks", RL.Middleware, app.JWTMiddleware)
+	// admin only:
+	webhooks.POST("", app.HandleCreateWebhook)
+	webhooks.GET("", app.HandleGetWebhooks)
+	webhooks.GET("/:uuid", app.HandleGetWebhook)
+	webhooks.PATCH("/:uuid", app.HandlePatchWebhook)
+	webhooks.DELETE("/:uuid", app.HandleDeleteWebhook)
+	webhooks.GET("/:uuid/deliveries", app.HandleGetWebhookDeliveries)
+	webhooks.POST("/:uuid/deliveries/:delivery/replay", app.HandleReplayWebhookDelivery)
+
+	labels := r.Group("/labels", RL.Middleware, app.JWTMiddleware)
+	// user or higher:
+	labels.GET("/items/:uuid", app.HandleGetItemLabel)
+	labels.GET("/items/by-sku/:sku", app.HandleGetItemLabelBySKU)
+	labels.GET("/locations/:code", app.HandleGetLocationLabel)
+	// stocker or higher:
+	labels.POST("/sheet", app.HandleCreateLabelSheet)
+
+	return r
+}
@@ -0,0 +1,72 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/openapi"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	r := router.New(handlers.App{Logger: zap.NewNop()}, handlers.RateLimiter{}, handlers.RateLimiter{})
+	doc := handlers.OpenAPI()
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		switch route.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			continue // the not found routes of the groups
+		}
+		if route.Path == "/docs" || strings.HasPrefix(route.Path, "/docs/") {
+			continue
+		}
+		path := openapi.Path(route.Path)
+		registered[route.Method+" "+path] = true
+
+		item, ok := doc.Paths[path]
+		require.True(t, ok, "%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		require.NotNil(t, item.Operation(route.Method), "%s %s is missing from the OpenAPI document", route.Method, route.Path)
+	}
+
+	// and nothing that is gone
+	for path, item := range doc.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if item.Operation(method) != nil {
+				require.True(t, registered[method+" "+path], "%s %s is documented but not registered", method, path)
+			}
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	r := router.New(handlers.App{Logger: zap.NewNop()}, handlers.RateLimiter{}, handlers.RateLimiter{})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/openapi.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+
+	rec = get("/docs")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "/openapi.json")
+
+	// served from the binary
+	rec = get("/docs/swagger-ui-bundle.js")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotZero(t, rec.Body.Len())
+	require.Equal(t, http.StatusMovedPermanently, get("/docs/index.html").Code)
+}
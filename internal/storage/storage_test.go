@@ -0,0 +1,34 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/warehouse/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, st.Put(ctx, "items/abc", strings.NewReader("hello")))
+
+	r, err := st.Get(ctx, "items/abc")
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, "hello", string(b))
+
+	require.NoError(t, st.Delete(ctx, "items/abc"))
+	require.NoError(t, st.Delete(ctx, "items/abc"))
+	_, err = st.Get(ctx, "items/abc")
+	require.ErrorIs(t, err, storage.ErrNotExist)
+
+	_, err = st.Get(ctx, "../etc/passwd")
+	require.ErrorIs(t, err, storage.ErrInvalidKey)
+}
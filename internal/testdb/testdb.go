@@ -0,0 +1,68 @@
+// Package testdb gives each test a database of its own, created from
+// db/schema.sql on the server of TEST_DATABASE_URL and dropped when the test
+// ends, so tests don't have to clean up after themselves.
+package testdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// URL creates a database with the schema and returns its URL. The test is
+// skipped when TEST_DATABASE_URL isn't set.
+func URL(t testing.TB) string {
+	t.Helper()
+
+	base := os.Getenv("TEST_DATABASE_URL")
+	if base == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	_, file, _, _ := runtime.Caller(0)
+	schema, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "..", "db", "schema.sql"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, base)
+	require.NoError(t, err)
+	name := fmt.Sprintf("warehouse_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, "CREATE DATABASE "+name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// the pools of the test are closed by then, FORCE is for the
+		// connections a failed test leaked
+		admin.Exec(context.Background(), "DROP DATABASE "+name+" WITH (FORCE)")
+		admin.Close(context.Background())
+	})
+
+	u, err := url.Parse(base)
+	require.NoError(t, err)
+	u.Path = "/" + name
+	// a connection of its own, the dump empties the search_path
+	conn, err := pgx.Connect(ctx, u.String())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, string(schema))
+	require.NoError(t, err)
+
+	return u.String()
+}
+
+// Pool connects to a database created by URL.
+func Pool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), URL(t))
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
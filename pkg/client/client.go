@@ -0,0 +1,334 @@
+// Package client is a Go client of the warehouse API.
+//
+//	c, err := client.New(client.Config{BaseURL: "https://warehouse.example.com"})
+//	if err != nil { ... }
+//	if err := c.Login(ctx, "alice", "secret"); err != nil { ... }
+//	for item, err := range c.Items(ctx, schemas.GetItemsRequest{Query: "drill"}) { ... }
+//
+// The access token is renewed with the refresh cookie of the login when it
+// is about to expire or gets rejected. Requests that are safe to repeat are
+// retried on network errors, 429 and 5xx gateway errors.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigelle/warehouse/pkg/schemas"
+)
+
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 200 * time.Millisecond
+	// the access token is renewed this long before it expires
+	tokenRenewBefore = 30 * time.Second
+	maxRetryAfter    = 30 * time.Second
+)
+
+type Config struct {
+	// e.g. https://warehouse.example.com
+	BaseURL string
+	// http.DefaultClient if nil. A cookie jar is added for the refresh
+	// token when it has none, the client isn't modified.
+	HTTPClient *http.Client
+	// DefaultMaxRetries if zero, negative to disable retries
+	MaxRetries int
+	// the first delay between retries, doubled every time. DefaultRetryBackoff
+	// if zero
+	RetryBackoff time.Duration
+}
+
+type Client struct {
+	base       *url.URL
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	// one refresh at a time, the others wait for its token
+	refreshMu sync.Mutex
+}
+
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, errors.New("base URL has to be http or https")
+	}
+
+	hc := http.DefaultClient
+	if cfg.HTTPClient != nil {
+		hc = cfg.HTTPClient
+	}
+	if hc.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		withJar := *hc
+		withJar.Jar = jar
+		hc = &withJar
+	}
+
+	c := &Client{
+		base:       base,
+		http:       hc,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	}
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.backoff == 0 {
+		c.backoff = DefaultRetryBackoff
+	}
+	return c, nil
+}
+
+// Register creates a user, it doesn't log in.
+func (c *Client) Register(ctx context.Context, req schemas.RegisterRequest) (schemas.RegisterResponse, error) {
+	var res schemas.RegisterResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/auth/register", body: req, noAuth: true}, &res)
+	return res, err
+}
+
+// Login gets an access token, and the refresh cookie which keeps renewing
+// it for as long as it's valid.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	var res schemas.LoginResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/login",
+		body:   schemas.LoginRequest{Username: username, Password: password},
+		noAuth: true,
+	}, &res)
+	if err != nil {
+		return err
+	}
+	c.SetAccessToken(res.AccessToken, time.Unix(res.Expires, 0))
+	return nil
+}
+
+// Refresh gets a new access token with the refresh cookie.
+func (c *Client) Refresh(ctx context.Context) error {
+	var res schemas.LoginResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/auth/refresh", noAuth: true}, &res)
+	if err != nil {
+		return err
+	}
+	c.SetAccessToken(res.AccessToken, time.Unix(res.Expires, 0))
+	return nil
+}
+
+// SetAccessToken uses a token obtained elsewhere, expires may be zero when
+// it isn't known.
+func (c *Client) SetAccessToken(token string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.expires = token, expires
+}
+
+func (c *Client) AccessToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// accessToken renews the token first if it's about to expire. Failures are
+// left for the request to run into.
+func (c *Client) accessToken(ctx context.Context) string {
+	c.mu.Lock()
+	token, expires := c.token, c.expires
+	c.mu.Unlock()
+
+	if token != "" && !expires.IsZero() && time.Until(expires) < tokenRenewBefore {
+		if c.renew(ctx, token) {
+			return c.AccessToken()
+		}
+	}
+	return token
+}
+
+// renew refreshes the token unless another request did it already since
+// stale was handed out, and reports whether there is a new one.
+func (c *Client) renew(ctx context.Context, stale string) bool {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.AccessToken() != stale {
+		return true
+	}
+	return c.Refresh(ctx) == nil
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	header http.Header
+	// for the auth routes themselves
+	noAuth bool
+	// POSTs are only retried with an Idempotency-Key
+	idempotent bool
+}
+
+// do sends the request, decoding the response into out unless it's nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+	retryable := req.idempotent || req.method == http.MethodGet || req.method == http.MethodHead ||
+		req.method == http.MethodPut || req.method == http.MethodDelete
+
+	var token string
+	if !req.noAuth {
+		token = c.accessToken(ctx)
+	}
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, req, body, token)
+		if err != nil {
+			if ctx.Err() != nil || !retryable || attempt >= c.maxRetries {
+				return err
+			}
+			if err := c.sleep(ctx, c.retryDelay(attempt, nil)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the token expired early or was revoked, one refresh per request
+		if res.StatusCode == http.StatusUnauthorized && !req.noAuth && !refreshed {
+			drain(res)
+			refreshed = true
+			if c.renew(ctx, token) {
+				token = c.AccessToken()
+				attempt--
+				continue
+			}
+			return &Error{Problem: schemas.Problem{
+				Type:   schemas.ProblemTypeBlank,
+				Title:  http.StatusText(http.StatusUnauthorized),
+				Status: http.StatusUnauthorized,
+			}}
+		}
+
+		if retryable && attempt < c.maxRetries && shouldRetry(res, req.idempotent) {
+			delay := c.retryDelay(attempt, res)
+			drain(res)
+			if err := c.sleep(ctx, delay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		defer res.Body.Close()
+		if res.StatusCode >= 300 {
+			return errorFromResponse(res)
+		}
+		if out != nil && res.StatusCode != http.StatusNoContent {
+			if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+				return fmt.Errorf("decoding %s %s: %w", req.method, req.path, err)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	u := c.base.JoinPath(req.path)
+	u.RawQuery = req.query.Encode()
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(httpReq)
+}
+
+func shouldRetry(res *http.Response, idempotent bool) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// a request with the same Idempotency-Key is still running
+		return idempotent && res.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// retryDelay doubles the backoff every attempt, a longer Retry-After of the
+// response wins.
+func (c *Client) retryDelay(attempt int, res *http.Response) time.Duration {
+	delay := c.backoff << attempt
+	if res != nil {
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			if after := time.Duration(s) * time.Second; after > delay {
+				delay = min(after, maxRetryAfter)
+			}
+		}
+	}
+	return delay
+}
+
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// drain lets the connection be reused.
+func drain(res *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+}
+
+// newIdempotencyKey makes the retries of a POST safe.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
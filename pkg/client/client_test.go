@@ -0,0 +1,168 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigelle/ratebucket"
+	"github.com/bigelle/warehouse/internal/database"
+	"github.com/bigelle/warehouse/internal/handlers"
+	"github.com/bigelle/warehouse/internal/router"
+	"github.com/bigelle/warehouse/internal/testdb"
+	"github.com/bigelle/warehouse/pkg/client"
+	"github.com/bigelle/warehouse/pkg/schemas"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func rateLimiter() handlers.RateLimiter {
+	return handlers.RateLimiter{Pool: ratebucket.NewPoolConfig(ratebucket.PoolConfig{
+		InitialTokens: 1000,
+		Capacity:      1000,
+		RefillRate:    1000,
+	})}
+}
+
+// testServer serves the real routes over TLS, the refresh cookie is Secure.
+func testServer(t *testing.T, app handlers.App, wrap func(http.Handler) http.Handler) *client.Client {
+	t.Helper()
+
+	var h http.Handler = router.New(app, rateLimiter(), rateLimiter())
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewTLSServer(h)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(client.Config{
+		BaseURL:      srv.URL,
+		HTTPClient:   srv.Client(),
+		RetryBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestErrorsAndRetries(t *testing.T) {
+	app := handlers.App{Logger: zap.NewNop()}
+	var requests, unavailable atomic.Int32
+	unavailable.Store(2)
+	c := testServer(t, app, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			if unavailable.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+
+	// twice unavailable, then rejected without a token, then no refresh cookie
+	_, err := c.ListItems(ctx, schemas.GetItemsRequest{})
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+	require.EqualValues(t, 4, requests.Load())
+
+	// POSTs without an Idempotency-Key are never retried
+	unavailable.Store(1)
+	requests.Store(0)
+	err = c.Login(ctx, "", "")
+	require.ErrorIs(t, err, client.ErrServiceUnavailable)
+	require.EqualValues(t, 1, requests.Load())
+
+	err = c.Login(ctx, "", "")
+	require.ErrorIs(t, err, client.ErrValidation)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.NotEmpty(t, apiErr.Errors)
+	require.Equal(t, "/auth/login", apiErr.Instance)
+}
+
+func TestClient(t *testing.T) {
+	pool := testdb.Pool(t)
+	app := handlers.App{
+		DB: handlers.Database{
+			Pool:    pool,
+			Queries: database.New(pool),
+		},
+		Logger: zap.NewNop(),
+		Config: handlers.Config{
+			JWTAccessSecret:  []byte("access"),
+			JWTRefreshSecret: []byte("refresh"),
+		},
+	}
+	c := testServer(t, app, nil)
+	ctx := context.Background()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	_, err := c.Register(ctx, schemas.RegisterRequest{
+		Username: "client-" + suffix,
+		Password: "secret",
+		Role:     schemas.RoleAdmin,
+	})
+	require.NoError(t, err)
+	var uuids []string
+
+	require.ErrorIs(t, c.Login(ctx, "client-"+suffix, "wrong"), client.ErrUnauthorized)
+	require.NoError(t, c.Login(ctx, "client-"+suffix, "secret"))
+
+	for _, name := range []string{"a", "b", "c"} {
+		item, err := c.CreateItem(ctx, schemas.CreateItemRequest{Name: "client-" + name + "-" + suffix})
+		require.NoError(t, err)
+		uuids = append(uuids, item.UUID)
+	}
+
+	var names []string
+	for item, err := range c.Items(ctx, schemas.GetItemsRequest{Name: suffix, Limit: 2, Sort: "created_at"}) {
+		require.NoError(t, err)
+		names = append(names, item.Name)
+	}
+	require.Equal(t, []string{"client-a-" + suffix, "client-b-" + suffix, "client-c-" + suffix}, names)
+
+	page, err := c.ListItems(ctx, schemas.GetItemsRequest{Name: "nothing-" + suffix})
+	require.NoError(t, err)
+	require.Empty(t, page.Items)
+
+	// a rejected access token is renewed with the refresh cookie
+	c.SetAccessToken("expired", time.Time{})
+	item, err := c.GetItem(ctx, uuids[0])
+	require.NoError(t, err)
+	require.NotEqual(t, "expired", c.AccessToken())
+
+	_, err = c.CreateTransaction(ctx, schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeWithdraw,
+		ItemUUID: item.UUID,
+		Amount:   1,
+	})
+	var failed *client.FailedTransactionError
+	require.ErrorAs(t, err, &failed)
+	require.ErrorIs(t, err, client.ErrUnprocessable)
+	require.Equal(t, schemas.TransactionStatusFailed, failed.Transaction.Status)
+
+	tr, err := c.CreateTransaction(ctx, schemas.CreateTransactionRequest{
+		Type:     schemas.TransactionTypeRestock,
+		ItemUUID: item.UUID,
+		Amount:   5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, schemas.TransactionStatusSucceeded, tr.Status)
+
+	// the restock bumped the version
+	name := "renamed-" + suffix
+	_, err = c.UpdateItem(ctx, item.UUID, item.Version, schemas.PatchRequest{Name: &name})
+	require.ErrorIs(t, err, client.ErrPreconditionFailed)
+	item, err = c.GetItem(ctx, item.UUID)
+	require.NoError(t, err)
+	item, err = c.UpdateItem(ctx, item.UUID, item.Version, schemas.PatchRequest{Name: &name})
+	require.NoError(t, err)
+	require.Equal(t, name, item.Name)
+
+	_, err = c.GetItem(ctx, "00000000-0000-0000-0000-000000000000")
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bigelle/warehouse/pkg/schemas"
+)
+
+// Error is an error response of the API. Compare it to the sentinels with
+// errors.Is, or get the details with errors.As.
+type Error struct {
+	schemas.Problem
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Title)
+}
+
+// Is matches errors of the same status, and of the same type unless target
+// has none.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Status == e.Status && (t.Type == "" || t.Type == e.Type)
+}
+
+var (
+	ErrBadRequest           = &Error{Problem: schemas.Problem{Status: http.StatusBadRequest}}
+	ErrUnauthorized         = &Error{Problem: schemas.Problem{Status: http.StatusUnauthorized}}
+	ErrForbidden            = &Error{Problem: schemas.Problem{Status: http.StatusForbidden}}
+	ErrNotFound             = &Error{Problem: schemas.Problem{Status: http.StatusNotFound}}
+	ErrConflict             = &Error{Problem: schemas.Problem{Status: http.StatusConflict}}
+	ErrPreconditionFailed   = &Error{Problem: schemas.Problem{Status: http.StatusPreconditionFailed}}
+	ErrPreconditionRequired = &Error{Problem: schemas.Problem{Status: http.StatusPreconditionRequired}}
+	ErrUnprocessable        = &Error{Problem: schemas.Problem{Status: http.StatusUnprocessableEntity}}
+	ErrTooManyRequests      = &Error{Problem: schemas.Problem{Status: http.StatusTooManyRequests}}
+	ErrServiceUnavailable   = &Error{Problem: schemas.Problem{Status: http.StatusServiceUnavailable}}
+
+	// failed validation of the request, the fields are in Errors
+	ErrValidation = &Error{Problem: schemas.Problem{Status: http.StatusBadRequest, Type: schemas.ProblemTypeValidation}}
+
+	ErrItemArchived             = &Error{Problem: schemas.Problem{Status: http.StatusConflict, Type: schemas.ProblemTypeItemArchived}}
+	ErrItemModified             = &Error{Problem: schemas.Problem{Status: http.StatusPreconditionFailed, Type: schemas.ProblemTypeItemModified}}
+	ErrNotReversible            = &Error{Problem: schemas.Problem{Status: http.StatusConflict, Type: schemas.ProblemTypeNotReversible}}
+	ErrIdempotencyKeyMismatch   = &Error{Problem: schemas.Problem{Status: http.StatusUnprocessableEntity, Type: schemas.ProblemTypeIdempotencyKeyMismatch}}
+	ErrIdempotencyKeyInProgress = &Error{Problem: schemas.Problem{Status: http.StatusConflict, Type: schemas.ProblemTypeIdempotencyKeyInProgress}}
+)
+
+// FailedTransactionError is returned for a withdrawal of more than there is
+// in stock. The attempt is still recorded, as Transaction.
+type FailedTransactionError struct {
+	Transaction schemas.Transaction
+}
+
+func (e *FailedTransactionError) Error() string {
+	return fmt.Sprintf("transaction %s failed: %s", e.Transaction.UUID, e.Transaction.Reason)
+}
+
+func (e *FailedTransactionError) Is(target error) bool {
+	return target == ErrUnprocessable
+}
+
+// errorFromResponse reads the problem of an error response. Bodies of
+// something in between, like a proxy, fall back to the status text.
+func errorFromResponse(res *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+
+	var p schemas.Problem
+	if json.Unmarshal(data, &p) == nil && p.Status != 0 {
+		return &Error{Problem: p}
+	}
+	if res.StatusCode == http.StatusUnprocessableEntity {
+		var tr schemas.Transaction
+		if json.Unmarshal(data, &tr) == nil && tr.UUID != "" {
+			return &FailedTransactionError{Transaction: tr}
+		}
+	}
+	return &Error{Problem: schemas.Problem{
+		Type:   schemas.ProblemTypeBlank,
+		Title:  http.StatusText(res.StatusCode),
+		Status: res.StatusCode,
+	}}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bigelle/warehouse/pkg/schemas"
+)
+
+// ListItems gets a page of items. No matches is an empty page, not an error.
+func (c *Client) ListItems(ctx context.Context, req schemas.GetItemsRequest) (schemas.GetItemsResponse, error) {
+	var res schemas.GetItemsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/items", query: queryValues(req)}, &res)
+	if errors.Is(err, ErrNotFound) {
+		return schemas.GetItemsResponse{}, nil
+	}
+	return res, err
+}
+
+// Items goes over all the items matching req, page by page. The iteration
+// stops after the first error.
+func (c *Client) Items(ctx context.Context, req schemas.GetItemsRequest) iter.Seq2[schemas.Item, error] {
+	return func(yield func(schemas.Item, error) bool) {
+		for {
+			page, err := c.ListItems(ctx, req)
+			if err != nil {
+				yield(schemas.Item{}, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			req.Cursor, req.Offset = page.NextCursor, 0
+		}
+	}
+}
+
+func (c *Client) GetItem(ctx context.Context, uuid string) (schemas.Item, error) {
+	var res schemas.Item
+	err := c.do(ctx, request{method: http.MethodGet, path: "/items/" + url.PathEscape(uuid)}, &res)
+	return res, err
+}
+
+func (c *Client) CreateItem(ctx context.Context, req schemas.CreateItemRequest) (schemas.CreateItemResponse, error) {
+	var res schemas.CreateItemResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/items", body: req}, &res)
+	return res, err
+}
+
+// UpdateItem changes the item if it's still at version, the Version of the
+// fetched item. ErrPreconditionFailed means someone else changed it first.
+func (c *Client) UpdateItem(ctx context.Context, uuid string, version int, req schemas.PatchRequest) (schemas.Item, error) {
+	var res schemas.Item
+	err := c.do(ctx, request{
+		method: http.MethodPatch,
+		path:   "/items/" + url.PathEscape(uuid),
+		body:   req,
+		header: http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}},
+	}, &res)
+	return res, err
+}
+
+func (c *Client) DeleteItem(ctx context.Context, uuid string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/items/" + url.PathEscape(uuid)}, nil)
+}
+
+func (c *Client) ArchiveItem(ctx context.Context, uuid string) (schemas.Item, error) {
+	var res schemas.Item
+	err := c.do(ctx, request{method: http.MethodPost, path: "/items/" + url.PathEscape(uuid) + "/archive"}, &res)
+	return res, err
+}
+
+func (c *Client) UnarchiveItem(ctx context.Context, uuid string) (schemas.Item, error) {
+	var res schemas.Item
+	err := c.do(ctx, request{method: http.MethodPost, path: "/items/" + url.PathEscape(uuid) + "/unarchive"}, &res)
+	return res, err
+}
+
+// ItemStock sums up the stock ledger of the item, as of a unix timestamp or
+// now if zero.
+func (c *Client) ItemStock(ctx context.Context, uuid string, asOf int64) (schemas.ItemStock, error) {
+	var res schemas.ItemStock
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/items/" + url.PathEscape(uuid) + "/stock",
+		query:  queryValues(schemas.GetItemStockRequest{AsOf: asOf}),
+	}, &res)
+	return res, err
+}
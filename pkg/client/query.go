@@ -0,0 +1,37 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// queryValues encodes the fields of a request struct with a query tag, the
+// way the server binds them. Zero values are left out, nil pointers too.
+func queryValues(v any) url.Values {
+	q := url.Values{}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return q
+		}
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("query"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		f := rv.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Pointer {
+			f = f.Elem()
+		}
+		q.Set(name, fmt.Sprint(f.Interface()))
+	}
+	return q
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/bigelle/warehouse/pkg/schemas"
+)
+
+func (c *Client) ListTransactions(ctx context.Context, req schemas.GetAllTransactionsRequest) (schemas.GetAllTransactionsResponse, error) {
+	var res schemas.GetAllTransactionsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/transactions", query: queryValues(req)}, &res)
+	return res, err
+}
+
+// Transactions goes over all the transactions matching req, page by page.
+// The iteration stops after the first error.
+func (c *Client) Transactions(ctx context.Context, req schemas.GetAllTransactionsRequest) iter.Seq2[schemas.Transaction, error] {
+	return func(yield func(schemas.Transaction, error) bool) {
+		for {
+			page, err := c.ListTransactions(ctx, req)
+			if err != nil {
+				yield(schemas.Transaction{}, err)
+				return
+			}
+			for _, tr := range page.Transactions {
+				if !yield(tr, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			req.Cursor, req.Offset = page.NextCursor, 0
+		}
+	}
+}
+
+func (c *Client) GetTransaction(ctx context.Context, uuid string) (schemas.Transaction, error) {
+	var res schemas.Transaction
+	err := c.do(ctx, request{method: http.MethodGet, path: "/transactions/" + url.PathEscape(uuid)}, &res)
+	return res, err
+}
+
+// CreateTransaction is sent with an Idempotency-Key, so that retrying it
+// can't move the stock twice. A withdrawal of more than there is returns
+// a *FailedTransactionError.
+func (c *Client) CreateTransaction(ctx context.Context, req schemas.CreateTransactionRequest) (schemas.Transaction, error) {
+	var res schemas.Transaction
+	err := c.do(ctx, idempotentPost("/transactions", req), &res)
+	return res, err
+}
+
+// CreateTransactionBatch is retried like CreateTransaction. Failed lines
+// are reported in the response, not as an error.
+func (c *Client) CreateTransactionBatch(ctx context.Context, req schemas.CreateTransactionBatchRequest) (schemas.CreateTransactionBatchResponse, error) {
+	var res schemas.CreateTransactionBatchResponse
+	err := c.do(ctx, idempotentPost("/transactions/batch", req), &res)
+	return res, err
+}
+
+func (c *Client) ReverseTransaction(ctx context.Context, uuid, reason string) (schemas.ReverseTransactionResponse, error) {
+	var res schemas.ReverseTransactionResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/transactions/" + url.PathEscape(uuid) + "/reverse",
+		body:   schemas.ReverseTransactionRequest{Reason: reason},
+	}, &res)
+	return res, err
+}
+
+func idempotentPost(path string, body any) request {
+	return request{
+		method:     http.MethodPost,
+		path:       path,
+		body:       body,
+		header:     http.Header{"Idempotency-Key": {newIdempotencyKey()}},
+		idempotent: true,
+	}
+}
@@ -0,0 +1,1010 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: warehouse/v1/items.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Item struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Uuid     string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name     string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Sku      string                 `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	Quantity int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// set for variants only
+	ProductUuid string            `protobuf:"bytes,5,opt,name=product_uuid,json=productUuid,proto3" json:"product_uuid,omitempty"`
+	Options     map[string]string `protobuf:"bytes,6,rep,name=options,proto3" json:"options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ArchivedAt  int64             `protobuf:"varint,7,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	// what UpdateItem and ArchiveItem expect
+	Version       int64 `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Item) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Item) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Item) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *Item) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Item) GetProductUuid() string {
+	if x != nil {
+		return x.ProductUuid
+	}
+	return ""
+}
+
+func (x *Item) GetOptions() map[string]string {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Item) GetArchivedAt() int64 {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return 0
+}
+
+func (x *Item) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ProductStock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	NVariants     int64                  `protobuf:"varint,3,opt,name=n_variants,json=nVariants,proto3" json:"n_variants,omitempty"`
+	Quantity      int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProductStock) Reset() {
+	*x = ProductStock{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProductStock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProductStock) ProtoMessage() {}
+
+func (x *ProductStock) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProductStock.ProtoReflect.Descriptor instead.
+func (*ProductStock) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ProductStock) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *ProductStock) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProductStock) GetNVariants() int64 {
+	if x != nil {
+		return x.NVariants
+	}
+	return 0
+}
+
+func (x *ProductStock) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// The same filters as the query of GET /items.
+type ListItemsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Limit  int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset int32                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// next_cursor or prev_cursor of a previous response, replaces offset
+	Cursor string `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// exclude (default), include or only
+	Archived string `protobuf:"bytes,4,opt,name=archived,proto3" json:"archived,omitempty"`
+	Name     string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Sku      string `protobuf:"bytes,6,opt,name=sku,proto3" json:"sku,omitempty"`
+	// full-text search over name and SKU
+	Query       string `protobuf:"bytes,7,opt,name=query,proto3" json:"query,omitempty"`
+	MinQuantity *int64 `protobuf:"varint,8,opt,name=min_quantity,json=minQuantity,proto3,oneof" json:"min_quantity,omitempty"`
+	MaxQuantity *int64 `protobuf:"varint,9,opt,name=max_quantity,json=maxQuantity,proto3,oneof" json:"max_quantity,omitempty"`
+	// unix timestamps, after is inclusive and before is exclusive
+	CreatedAfter  int64 `protobuf:"varint,10,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore int64 `protobuf:"varint,11,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	UpdatedAfter  int64 `protobuf:"varint,12,opt,name=updated_after,json=updatedAfter,proto3" json:"updated_after,omitempty"`
+	UpdatedBefore int64 `protobuf:"varint,13,opt,name=updated_before,json=updatedBefore,proto3" json:"updated_before,omitempty"`
+	// e.g. "-quantity,name"
+	Sort          string `protobuf:"bytes,14,opt,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsRequest) Reset() {
+	*x = ListItemsRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsRequest) ProtoMessage() {}
+
+func (x *ListItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsRequest.ProtoReflect.Descriptor instead.
+func (*ListItemsRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListItemsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetArchived() string {
+	if x != nil {
+		return x.Archived
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetMinQuantity() int64 {
+	if x != nil && x.MinQuantity != nil {
+		return *x.MinQuantity
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetMaxQuantity() int64 {
+	if x != nil && x.MaxQuantity != nil {
+		return *x.MaxQuantity
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetCreatedAfter() int64 {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetCreatedBefore() int64 {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetUpdatedAfter() int64 {
+	if x != nil {
+		return x.UpdatedAfter
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetUpdatedBefore() int64 {
+	if x != nil {
+		return x.UpdatedBefore
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+type ListItemsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// parents of the variants found in items
+	Products      []*ProductStock `protobuf:"bytes,2,rep,name=products,proto3" json:"products,omitempty"`
+	NextCursor    string          `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	PrevCursor    string          `protobuf:"bytes,4,opt,name=prev_cursor,json=prevCursor,proto3" json:"prev_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsResponse) Reset() {
+	*x = ListItemsResponse{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsResponse) ProtoMessage() {}
+
+func (x *ListItemsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsResponse.ProtoReflect.Descriptor instead.
+func (*ListItemsResponse) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListItemsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ListItemsResponse) GetProducts() []*ProductStock {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+func (x *ListItemsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *ListItemsResponse) GetPrevCursor() string {
+	if x != nil {
+		return x.PrevCursor
+	}
+	return ""
+}
+
+type GetItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemRequest) Reset() {
+	*x = GetItemRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemRequest) ProtoMessage() {}
+
+func (x *GetItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemRequest.ProtoReflect.Descriptor instead.
+func (*GetItemRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetItemRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type CreateItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Sku           string                 `protobuf:"bytes,2,opt,name=sku,proto3" json:"sku,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateItemRequest) Reset() {
+	*x = CreateItemRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateItemRequest) ProtoMessage() {}
+
+func (x *CreateItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateItemRequest.ProtoReflect.Descriptor instead.
+func (*CreateItemRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateItemRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateItemRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+type CreateItemResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Sku           string                 `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateItemResponse) Reset() {
+	*x = CreateItemResponse{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateItemResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateItemResponse) ProtoMessage() {}
+
+func (x *CreateItemResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateItemResponse.ProtoReflect.Descriptor instead.
+func (*CreateItemResponse) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateItemResponse) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *CreateItemResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateItemResponse) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *CreateItemResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type UpdateItemRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Uuid  string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// required, the version the changes were made to, like If-Match of PATCH
+	// /items/:uuid
+	Version       int64   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Name          *string `protobuf:"bytes,3,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Sku           *string `protobuf:"bytes,4,opt,name=sku,proto3,oneof" json:"sku,omitempty"`
+	Quantity      *int32  `protobuf:"varint,5,opt,name=quantity,proto3,oneof" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateItemRequest) Reset() {
+	*x = UpdateItemRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateItemRequest) ProtoMessage() {}
+
+func (x *UpdateItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateItemRequest.ProtoReflect.Descriptor instead.
+func (*UpdateItemRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateItemRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *UpdateItemRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetSku() string {
+	if x != nil && x.Sku != nil {
+		return *x.Sku
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetQuantity() int32 {
+	if x != nil && x.Quantity != nil {
+		return *x.Quantity
+	}
+	return 0
+}
+
+type ArchiveItemRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Uuid  string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// any version if zero
+	Version       int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArchiveItemRequest) Reset() {
+	*x = ArchiveItemRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArchiveItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArchiveItemRequest) ProtoMessage() {}
+
+func (x *ArchiveItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArchiveItemRequest.ProtoReflect.Descriptor instead.
+func (*ArchiveItemRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ArchiveItemRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *ArchiveItemRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GetItemStockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Uuid  string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// unix timestamp, now if zero
+	AsOf          int64 `protobuf:"varint,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemStockRequest) Reset() {
+	*x = GetItemStockRequest{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemStockRequest) ProtoMessage() {}
+
+func (x *GetItemStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemStockRequest.ProtoReflect.Descriptor instead.
+func (*GetItemStockRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetItemStockRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *GetItemStockRequest) GetAsOf() int64 {
+	if x != nil {
+		return x.AsOf
+	}
+	return 0
+}
+
+type ItemStock struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ItemUuid       string                 `protobuf:"bytes,1,opt,name=item_uuid,json=itemUuid,proto3" json:"item_uuid,omitempty"`
+	AsOf           int64                  `protobuf:"varint,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	Quantity       int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	NMovements     int64                  `protobuf:"varint,4,opt,name=n_movements,json=nMovements,proto3" json:"n_movements,omitempty"`
+	LastMovementAt int64                  `protobuf:"varint,5,opt,name=last_movement_at,json=lastMovementAt,proto3" json:"last_movement_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ItemStock) Reset() {
+	*x = ItemStock{}
+	mi := &file_warehouse_v1_items_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemStock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemStock) ProtoMessage() {}
+
+func (x *ItemStock) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_items_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemStock.ProtoReflect.Descriptor instead.
+func (*ItemStock) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_items_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ItemStock) GetItemUuid() string {
+	if x != nil {
+		return x.ItemUuid
+	}
+	return ""
+}
+
+func (x *ItemStock) GetAsOf() int64 {
+	if x != nil {
+		return x.AsOf
+	}
+	return 0
+}
+
+func (x *ItemStock) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *ItemStock) GetNMovements() int64 {
+	if x != nil {
+		return x.NMovements
+	}
+	return 0
+}
+
+func (x *ItemStock) GetLastMovementAt() int64 {
+	if x != nil {
+		return x.LastMovementAt
+	}
+	return 0
+}
+
+var File_warehouse_v1_items_proto protoreflect.FileDescriptor
+
+const file_warehouse_v1_items_proto_rawDesc = "" +
+	"\n" +
+	"\x18warehouse/v1/items.proto\x12\fwarehouse.v1\"\xb1\x02\n" +
+	"\x04Item\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x10\n" +
+	"\x03sku\x18\x03 \x01(\tR\x03sku\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\x12!\n" +
+	"\fproduct_uuid\x18\x05 \x01(\tR\vproductUuid\x129\n" +
+	"\aoptions\x18\x06 \x03(\v2\x1f.warehouse.v1.Item.OptionsEntryR\aoptions\x12\x1f\n" +
+	"\varchived_at\x18\a \x01(\x03R\n" +
+	"archivedAt\x12\x18\n" +
+	"\aversion\x18\b \x01(\x03R\aversion\x1a:\n" +
+	"\fOptionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"q\n" +
+	"\fProductStock\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"n_variants\x18\x03 \x01(\x03R\tnVariants\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\"\xce\x03\n" +
+	"\x10ListItemsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x05R\x06offset\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\tR\x06cursor\x12\x1a\n" +
+	"\barchived\x18\x04 \x01(\tR\barchived\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x10\n" +
+	"\x03sku\x18\x06 \x01(\tR\x03sku\x12\x14\n" +
+	"\x05query\x18\a \x01(\tR\x05query\x12&\n" +
+	"\fmin_quantity\x18\b \x01(\x03H\x00R\vminQuantity\x88\x01\x01\x12&\n" +
+	"\fmax_quantity\x18\t \x01(\x03H\x01R\vmaxQuantity\x88\x01\x01\x12#\n" +
+	"\rcreated_after\x18\n" +
+	" \x01(\x03R\fcreatedAfter\x12%\n" +
+	"\x0ecreated_before\x18\v \x01(\x03R\rcreatedBefore\x12#\n" +
+	"\rupdated_after\x18\f \x01(\x03R\fupdatedAfter\x12%\n" +
+	"\x0eupdated_before\x18\r \x01(\x03R\rupdatedBefore\x12\x12\n" +
+	"\x04sort\x18\x0e \x01(\tR\x04sortB\x0f\n" +
+	"\r_min_quantityB\x0f\n" +
+	"\r_max_quantity\"\xb7\x01\n" +
+	"\x11ListItemsResponse\x12(\n" +
+	"\x05items\x18\x01 \x03(\v2\x12.warehouse.v1.ItemR\x05items\x126\n" +
+	"\bproducts\x18\x02 \x03(\v2\x1a.warehouse.v1.ProductStockR\bproducts\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\tR\n" +
+	"nextCursor\x12\x1f\n" +
+	"\vprev_cursor\x18\x04 \x01(\tR\n" +
+	"prevCursor\"$\n" +
+	"\x0eGetItemRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"9\n" +
+	"\x11CreateItemRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03sku\x18\x02 \x01(\tR\x03sku\"m\n" +
+	"\x12CreateItemResponse\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x10\n" +
+	"\x03sku\x18\x03 \x01(\tR\x03sku\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\"\xb0\x01\n" +
+	"\x11UpdateItemRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12\x17\n" +
+	"\x04name\x18\x03 \x01(\tH\x00R\x04name\x88\x01\x01\x12\x15\n" +
+	"\x03sku\x18\x04 \x01(\tH\x01R\x03sku\x88\x01\x01\x12\x1f\n" +
+	"\bquantity\x18\x05 \x01(\x05H\x02R\bquantity\x88\x01\x01B\a\n" +
+	"\x05_nameB\x06\n" +
+	"\x04_skuB\v\n" +
+	"\t_quantity\"B\n" +
+	"\x12ArchiveItemRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\">\n" +
+	"\x13GetItemStockRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x13\n" +
+	"\x05as_of\x18\x02 \x01(\x03R\x04asOf\"\xa4\x01\n" +
+	"\tItemStock\x12\x1b\n" +
+	"\titem_uuid\x18\x01 \x01(\tR\bitemUuid\x12\x13\n" +
+	"\x05as_of\x18\x02 \x01(\x03R\x04asOf\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x03R\bquantity\x12\x1f\n" +
+	"\vn_movements\x18\x04 \x01(\x03R\n" +
+	"nMovements\x12(\n" +
+	"\x10last_movement_at\x18\x05 \x01(\x03R\x0elastMovementAt2\x84\x04\n" +
+	"\vItemService\x12L\n" +
+	"\tListItems\x12\x1e.warehouse.v1.ListItemsRequest\x1a\x1f.warehouse.v1.ListItemsResponse\x12;\n" +
+	"\aGetItem\x12\x1c.warehouse.v1.GetItemRequest\x1a\x12.warehouse.v1.Item\x12O\n" +
+	"\n" +
+	"CreateItem\x12\x1f.warehouse.v1.CreateItemRequest\x1a .warehouse.v1.CreateItemResponse\x12A\n" +
+	"\n" +
+	"UpdateItem\x12\x1f.warehouse.v1.UpdateItemRequest\x1a\x12.warehouse.v1.Item\x12C\n" +
+	"\vArchiveItem\x12 .warehouse.v1.ArchiveItemRequest\x1a\x12.warehouse.v1.Item\x12E\n" +
+	"\rUnarchiveItem\x12 .warehouse.v1.ArchiveItemRequest\x1a\x12.warehouse.v1.Item\x12J\n" +
+	"\fGetItemStock\x12!.warehouse.v1.GetItemStockRequest\x1a\x17.warehouse.v1.ItemStockB%Z#github.com/bigelle/warehouse/pkg/pbb\x06proto3"
+
+var (
+	file_warehouse_v1_items_proto_rawDescOnce sync.Once
+	file_warehouse_v1_items_proto_rawDescData []byte
+)
+
+func file_warehouse_v1_items_proto_rawDescGZIP() []byte {
+	file_warehouse_v1_items_proto_rawDescOnce.Do(func() {
+		file_warehouse_v1_items_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_warehouse_v1_items_proto_rawDesc), len(file_warehouse_v1_items_proto_rawDesc)))
+	})
+	return file_warehouse_v1_items_proto_rawDescData
+}
+
+var file_warehouse_v1_items_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_warehouse_v1_items_proto_goTypes = []any{
+	(*Item)(nil),                // 0: warehouse.v1.Item
+	(*ProductStock)(nil),        // 1: warehouse.v1.ProductStock
+	(*ListItemsRequest)(nil),    // 2: warehouse.v1.ListItemsRequest
+	(*ListItemsResponse)(nil),   // 3: warehouse.v1.ListItemsResponse
+	(*GetItemRequest)(nil),      // 4: warehouse.v1.GetItemRequest
+	(*CreateItemRequest)(nil),   // 5: warehouse.v1.CreateItemRequest
+	(*CreateItemResponse)(nil),  // 6: warehouse.v1.CreateItemResponse
+	(*UpdateItemRequest)(nil),   // 7: warehouse.v1.UpdateItemRequest
+	(*ArchiveItemRequest)(nil),  // 8: warehouse.v1.ArchiveItemRequest
+	(*GetItemStockRequest)(nil), // 9: warehouse.v1.GetItemStockRequest
+	(*ItemStock)(nil),           // 10: warehouse.v1.ItemStock
+	nil,                         // 11: warehouse.v1.Item.OptionsEntry
+}
+var file_warehouse_v1_items_proto_depIdxs = []int32{
+	11, // 0: warehouse.v1.Item.options:type_name -> warehouse.v1.Item.OptionsEntry
+	0,  // 1: warehouse.v1.ListItemsResponse.items:type_name -> warehouse.v1.Item
+	1,  // 2: warehouse.v1.ListItemsResponse.products:type_name -> warehouse.v1.ProductStock
+	2,  // 3: warehouse.v1.ItemService.ListItems:input_type -> warehouse.v1.ListItemsRequest
+	4,  // 4: warehouse.v1.ItemService.GetItem:input_type -> warehouse.v1.GetItemRequest
+	5,  // 5: warehouse.v1.ItemService.CreateItem:input_type -> warehouse.v1.CreateItemRequest
+	7,  // 6: warehouse.v1.ItemService.UpdateItem:input_type -> warehouse.v1.UpdateItemRequest
+	8,  // 7: warehouse.v1.ItemService.ArchiveItem:input_type -> warehouse.v1.ArchiveItemRequest
+	8,  // 8: warehouse.v1.ItemService.UnarchiveItem:input_type -> warehouse.v1.ArchiveItemRequest
+	9,  // 9: warehouse.v1.ItemService.GetItemStock:input_type -> warehouse.v1.GetItemStockRequest
+	3,  // 10: warehouse.v1.ItemService.ListItems:output_type -> warehouse.v1.ListItemsResponse
+	0,  // 11: warehouse.v1.ItemService.GetItem:output_type -> warehouse.v1.Item
+	6,  // 12: warehouse.v1.ItemService.CreateItem:output_type -> warehouse.v1.CreateItemResponse
+	0,  // 13: warehouse.v1.ItemService.UpdateItem:output_type -> warehouse.v1.Item
+	0,  // 14: warehouse.v1.ItemService.ArchiveItem:output_type -> warehouse.v1.Item
+	0,  // 15: warehouse.v1.ItemService.UnarchiveItem:output_type -> warehouse.v1.Item
+	10, // 16: warehouse.v1.ItemService.GetItemStock:output_type -> warehouse.v1.ItemStock
+	10, // [10:17] is the sub-list for method output_type
+	3,  // [3:10] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_warehouse_v1_items_proto_init() }
+func file_warehouse_v1_items_proto_init() {
+	if File_warehouse_v1_items_proto != nil {
+		return
+	}
+	file_warehouse_v1_items_proto_msgTypes[2].OneofWrappers = []any{}
+	file_warehouse_v1_items_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_warehouse_v1_items_proto_rawDesc), len(file_warehouse_v1_items_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_warehouse_v1_items_proto_goTypes,
+		DependencyIndexes: file_warehouse_v1_items_proto_depIdxs,
+		MessageInfos:      file_warehouse_v1_items_proto_msgTypes,
+	}.Build()
+	File_warehouse_v1_items_proto = out.File
+	file_warehouse_v1_items_proto_goTypes = nil
+	file_warehouse_v1_items_proto_depIdxs = nil
+}
@@ -0,0 +1,890 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: warehouse/v1/transactions.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TransactionType int32
+
+const (
+	TransactionType_TRANSACTION_TYPE_UNSPECIFIED TransactionType = 0
+	TransactionType_TRANSACTION_TYPE_RESTOCK     TransactionType = 1
+	TransactionType_TRANSACTION_TYPE_WITHDRAW    TransactionType = 2
+)
+
+// Enum value maps for TransactionType.
+var (
+	TransactionType_name = map[int32]string{
+		0: "TRANSACTION_TYPE_UNSPECIFIED",
+		1: "TRANSACTION_TYPE_RESTOCK",
+		2: "TRANSACTION_TYPE_WITHDRAW",
+	}
+	TransactionType_value = map[string]int32{
+		"TRANSACTION_TYPE_UNSPECIFIED": 0,
+		"TRANSACTION_TYPE_RESTOCK":     1,
+		"TRANSACTION_TYPE_WITHDRAW":    2,
+	}
+)
+
+func (x TransactionType) Enum() *TransactionType {
+	p := new(TransactionType)
+	*p = x
+	return p
+}
+
+func (x TransactionType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionType) Descriptor() protoreflect.EnumDescriptor {
+	return file_warehouse_v1_transactions_proto_enumTypes[0].Descriptor()
+}
+
+func (TransactionType) Type() protoreflect.EnumType {
+	return &file_warehouse_v1_transactions_proto_enumTypes[0]
+}
+
+func (x TransactionType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionType.Descriptor instead.
+func (TransactionType) EnumDescriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{0}
+}
+
+type TransactionStatus int32
+
+const (
+	TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED TransactionStatus = 0
+	TransactionStatus_TRANSACTION_STATUS_SUCCEEDED   TransactionStatus = 1
+	TransactionStatus_TRANSACTION_STATUS_FAILED      TransactionStatus = 2
+	// undone by another transaction
+	TransactionStatus_TRANSACTION_STATUS_REVERSED TransactionStatus = 3
+)
+
+// Enum value maps for TransactionStatus.
+var (
+	TransactionStatus_name = map[int32]string{
+		0: "TRANSACTION_STATUS_UNSPECIFIED",
+		1: "TRANSACTION_STATUS_SUCCEEDED",
+		2: "TRANSACTION_STATUS_FAILED",
+		3: "TRANSACTION_STATUS_REVERSED",
+	}
+	TransactionStatus_value = map[string]int32{
+		"TRANSACTION_STATUS_UNSPECIFIED": 0,
+		"TRANSACTION_STATUS_SUCCEEDED":   1,
+		"TRANSACTION_STATUS_FAILED":      2,
+		"TRANSACTION_STATUS_REVERSED":    3,
+	}
+)
+
+func (x TransactionStatus) Enum() *TransactionStatus {
+	p := new(TransactionStatus)
+	*p = x
+	return p
+}
+
+func (x TransactionStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_warehouse_v1_transactions_proto_enumTypes[1].Descriptor()
+}
+
+func (TransactionStatus) Type() protoreflect.EnumType {
+	return &file_warehouse_v1_transactions_proto_enumTypes[1]
+}
+
+func (x TransactionStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionStatus.Descriptor instead.
+func (TransactionStatus) EnumDescriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{1}
+}
+
+type Transaction struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Uuid      string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Type      TransactionType        `protobuf:"varint,2,opt,name=type,proto3,enum=warehouse.v1.TransactionType" json:"type,omitempty"`
+	OwnerUuid string                 `protobuf:"bytes,3,opt,name=owner_uuid,json=ownerUuid,proto3" json:"owner_uuid,omitempty"`
+	ItemUuid  string                 `protobuf:"bytes,4,opt,name=item_uuid,json=itemUuid,proto3" json:"item_uuid,omitempty"`
+	Amount    int64                  `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Status    TransactionStatus      `protobuf:"varint,6,opt,name=status,proto3,enum=warehouse.v1.TransactionStatus" json:"status,omitempty"`
+	// why it failed or was reversed
+	Reason    string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	CreatedAt int64  `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// set for the compensating entries
+	ReversalOf    string `protobuf:"bytes,9,opt,name=reversal_of,json=reversalOf,proto3" json:"reversal_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Transaction) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetType() TransactionType {
+	if x != nil {
+		return x.Type
+	}
+	return TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+}
+
+func (x *Transaction) GetOwnerUuid() string {
+	if x != nil {
+		return x.OwnerUuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetItemUuid() string {
+	if x != nil {
+		return x.ItemUuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Transaction) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *Transaction) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Transaction) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Transaction) GetReversalOf() string {
+	if x != nil {
+		return x.ReversalOf
+	}
+	return ""
+}
+
+// The same filters as the query of GET /transactions.
+type TransactionFilter struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Type     TransactionType        `protobuf:"varint,1,opt,name=type,proto3,enum=warehouse.v1.TransactionType" json:"type,omitempty"`
+	Status   TransactionStatus      `protobuf:"varint,2,opt,name=status,proto3,enum=warehouse.v1.TransactionStatus" json:"status,omitempty"`
+	UserUuid string                 `protobuf:"bytes,3,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	ItemUuid string                 `protobuf:"bytes,4,opt,name=item_uuid,json=itemUuid,proto3" json:"item_uuid,omitempty"`
+	// unix timestamps, after is inclusive and before is exclusive
+	CreatedAfter  int64  `protobuf:"varint,5,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore int64  `protobuf:"varint,6,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	MinAmount     *int64 `protobuf:"varint,7,opt,name=min_amount,json=minAmount,proto3,oneof" json:"min_amount,omitempty"`
+	MaxAmount     *int64 `protobuf:"varint,8,opt,name=max_amount,json=maxAmount,proto3,oneof" json:"max_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionFilter) Reset() {
+	*x = TransactionFilter{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionFilter) ProtoMessage() {}
+
+func (x *TransactionFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionFilter.ProtoReflect.Descriptor instead.
+func (*TransactionFilter) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TransactionFilter) GetType() TransactionType {
+	if x != nil {
+		return x.Type
+	}
+	return TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+}
+
+func (x *TransactionFilter) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *TransactionFilter) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *TransactionFilter) GetItemUuid() string {
+	if x != nil {
+		return x.ItemUuid
+	}
+	return ""
+}
+
+func (x *TransactionFilter) GetCreatedAfter() int64 {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return 0
+}
+
+func (x *TransactionFilter) GetCreatedBefore() int64 {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return 0
+}
+
+func (x *TransactionFilter) GetMinAmount() int64 {
+	if x != nil && x.MinAmount != nil {
+		return *x.MinAmount
+	}
+	return 0
+}
+
+func (x *TransactionFilter) GetMaxAmount() int64 {
+	if x != nil && x.MaxAmount != nil {
+		return *x.MaxAmount
+	}
+	return 0
+}
+
+type ListTransactionsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *TransactionFilter     `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	Limit  int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// next_cursor or prev_cursor of a previous response, replaces offset
+	Cursor        string `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsRequest) Reset() {
+	*x = ListTransactionsRequest{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsRequest) ProtoMessage() {}
+
+func (x *ListTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListTransactionsRequest) GetFilter() *TransactionFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListTransactionsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListTransactionsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ListTransactionsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	PrevCursor    string                 `protobuf:"bytes,3,opt,name=prev_cursor,json=prevCursor,proto3" json:"prev_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsResponse) Reset() {
+	*x = ListTransactionsResponse{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsResponse) ProtoMessage() {}
+
+func (x *ListTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *ListTransactionsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *ListTransactionsResponse) GetPrevCursor() string {
+	if x != nil {
+		return x.PrevCursor
+	}
+	return ""
+}
+
+type GetTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionRequest) Reset() {
+	*x = GetTransactionRequest{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionRequest) ProtoMessage() {}
+
+func (x *GetTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetTransactionRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+type CreateTransactionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  TransactionType        `protobuf:"varint,1,opt,name=type,proto3,enum=warehouse.v1.TransactionType" json:"type,omitempty"`
+	// either the item or one of its barcodes
+	ItemUuid      string `protobuf:"bytes,2,opt,name=item_uuid,json=itemUuid,proto3" json:"item_uuid,omitempty"`
+	Barcode       string `protobuf:"bytes,3,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	Amount        int64  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTransactionRequest) Reset() {
+	*x = CreateTransactionRequest{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTransactionRequest) ProtoMessage() {}
+
+func (x *CreateTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTransactionRequest.ProtoReflect.Descriptor instead.
+func (*CreateTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateTransactionRequest) GetType() TransactionType {
+	if x != nil {
+		return x.Type
+	}
+	return TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+}
+
+func (x *CreateTransactionRequest) GetItemUuid() string {
+	if x != nil {
+		return x.ItemUuid
+	}
+	return ""
+}
+
+func (x *CreateTransactionRequest) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+func (x *CreateTransactionRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type ReverseTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReverseTransactionRequest) Reset() {
+	*x = ReverseTransactionRequest{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReverseTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReverseTransactionRequest) ProtoMessage() {}
+
+func (x *ReverseTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReverseTransactionRequest.ProtoReflect.Descriptor instead.
+func (*ReverseTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReverseTransactionRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *ReverseTransactionRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ReverseTransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Original      *Transaction           `protobuf:"bytes,1,opt,name=original,proto3" json:"original,omitempty"`
+	Reversal      *Transaction           `protobuf:"bytes,2,opt,name=reversal,proto3" json:"reversal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReverseTransactionResponse) Reset() {
+	*x = ReverseTransactionResponse{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReverseTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReverseTransactionResponse) ProtoMessage() {}
+
+func (x *ReverseTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReverseTransactionResponse.ProtoReflect.Descriptor instead.
+func (*ReverseTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReverseTransactionResponse) GetOriginal() *Transaction {
+	if x != nil {
+		return x.Original
+	}
+	return nil
+}
+
+func (x *ReverseTransactionResponse) GetReversal() *Transaction {
+	if x != nil {
+		return x.Reversal
+	}
+	return nil
+}
+
+type StreamTransactionsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *TransactionFilter     `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// keep the stream open for new transactions
+	Follow        bool `protobuf:"varint,2,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamTransactionsRequest) Reset() {
+	*x = StreamTransactionsRequest{}
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamTransactionsRequest) ProtoMessage() {}
+
+func (x *StreamTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_warehouse_v1_transactions_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*StreamTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_warehouse_v1_transactions_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamTransactionsRequest) GetFilter() *TransactionFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *StreamTransactionsRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+var File_warehouse_v1_transactions_proto protoreflect.FileDescriptor
+
+const file_warehouse_v1_transactions_proto_rawDesc = "" +
+	"\n" +
+	"\x1fwarehouse/v1/transactions.proto\x12\fwarehouse.v1\"\xb9\x02\n" +
+	"\vTransaction\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x121\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x1d.warehouse.v1.TransactionTypeR\x04type\x12\x1d\n" +
+	"\n" +
+	"owner_uuid\x18\x03 \x01(\tR\townerUuid\x12\x1b\n" +
+	"\titem_uuid\x18\x04 \x01(\tR\bitemUuid\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x127\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x1f.warehouse.v1.TransactionStatusR\x06status\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt\x12\x1f\n" +
+	"\vreversal_of\x18\t \x01(\tR\n" +
+	"reversalOf\"\xeb\x02\n" +
+	"\x11TransactionFilter\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.warehouse.v1.TransactionTypeR\x04type\x127\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x1f.warehouse.v1.TransactionStatusR\x06status\x12\x1b\n" +
+	"\tuser_uuid\x18\x03 \x01(\tR\buserUuid\x12\x1b\n" +
+	"\titem_uuid\x18\x04 \x01(\tR\bitemUuid\x12#\n" +
+	"\rcreated_after\x18\x05 \x01(\x03R\fcreatedAfter\x12%\n" +
+	"\x0ecreated_before\x18\x06 \x01(\x03R\rcreatedBefore\x12\"\n" +
+	"\n" +
+	"min_amount\x18\a \x01(\x03H\x00R\tminAmount\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_amount\x18\b \x01(\x03H\x01R\tmaxAmount\x88\x01\x01B\r\n" +
+	"\v_min_amountB\r\n" +
+	"\v_max_amount\"\x98\x01\n" +
+	"\x17ListTransactionsRequest\x127\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1f.warehouse.v1.TransactionFilterR\x06filter\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12\x16\n" +
+	"\x06cursor\x18\x04 \x01(\tR\x06cursor\"\x9b\x01\n" +
+	"\x18ListTransactionsResponse\x12=\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x19.warehouse.v1.TransactionR\ftransactions\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\x12\x1f\n" +
+	"\vprev_cursor\x18\x03 \x01(\tR\n" +
+	"prevCursor\"+\n" +
+	"\x15GetTransactionRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\x9c\x01\n" +
+	"\x18CreateTransactionRequest\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.warehouse.v1.TransactionTypeR\x04type\x12\x1b\n" +
+	"\titem_uuid\x18\x02 \x01(\tR\bitemUuid\x12\x18\n" +
+	"\abarcode\x18\x03 \x01(\tR\abarcode\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\"G\n" +
+	"\x19ReverseTransactionRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\x8a\x01\n" +
+	"\x1aReverseTransactionResponse\x125\n" +
+	"\boriginal\x18\x01 \x01(\v2\x19.warehouse.v1.TransactionR\boriginal\x125\n" +
+	"\breversal\x18\x02 \x01(\v2\x19.warehouse.v1.TransactionR\breversal\"l\n" +
+	"\x19StreamTransactionsRequest\x127\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1f.warehouse.v1.TransactionFilterR\x06filter\x12\x16\n" +
+	"\x06follow\x18\x02 \x01(\bR\x06follow*p\n" +
+	"\x0fTransactionType\x12 \n" +
+	"\x1cTRANSACTION_TYPE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18TRANSACTION_TYPE_RESTOCK\x10\x01\x12\x1d\n" +
+	"\x19TRANSACTION_TYPE_WITHDRAW\x10\x02*\x99\x01\n" +
+	"\x11TransactionStatus\x12\"\n" +
+	"\x1eTRANSACTION_STATUS_UNSPECIFIED\x10\x00\x12 \n" +
+	"\x1cTRANSACTION_STATUS_SUCCEEDED\x10\x01\x12\x1d\n" +
+	"\x19TRANSACTION_STATUS_FAILED\x10\x02\x12\x1f\n" +
+	"\x1bTRANSACTION_STATUS_REVERSED\x10\x032\xe6\x03\n" +
+	"\x12TransactionService\x12a\n" +
+	"\x10ListTransactions\x12%.warehouse.v1.ListTransactionsRequest\x1a&.warehouse.v1.ListTransactionsResponse\x12P\n" +
+	"\x0eGetTransaction\x12#.warehouse.v1.GetTransactionRequest\x1a\x19.warehouse.v1.Transaction\x12V\n" +
+	"\x11CreateTransaction\x12&.warehouse.v1.CreateTransactionRequest\x1a\x19.warehouse.v1.Transaction\x12g\n" +
+	"\x12ReverseTransaction\x12'.warehouse.v1.ReverseTransactionRequest\x1a(.warehouse.v1.ReverseTransactionResponse\x12Z\n" +
+	"\x12StreamTransactions\x12'.warehouse.v1.StreamTransactionsRequest\x1a\x19.warehouse.v1.Transaction0\x01B%Z#github.com/bigelle/warehouse/pkg/pbb\x06proto3"
+
+var (
+	file_warehouse_v1_transactions_proto_rawDescOnce sync.Once
+	file_warehouse_v1_transactions_proto_rawDescData []byte
+)
+
+func file_warehouse_v1_transactions_proto_rawDescGZIP() []byte {
+	file_warehouse_v1_transactions_proto_rawDescOnce.Do(func() {
+		file_warehouse_v1_transactions_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_warehouse_v1_transactions_proto_rawDesc), len(file_warehouse_v1_transactions_proto_rawDesc)))
+	})
+	return file_warehouse_v1_transactions_proto_rawDescData
+}
+
+var file_warehouse_v1_transactions_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_warehouse_v1_transactions_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_warehouse_v1_transactions_proto_goTypes = []any{
+	(TransactionType)(0),               // 0: warehouse.v1.TransactionType
+	(TransactionStatus)(0),             // 1: warehouse.v1.TransactionStatus
+	(*Transaction)(nil),                // 2: warehouse.v1.Transaction
+	(*TransactionFilter)(nil),          // 3: warehouse.v1.TransactionFilter
+	(*ListTransactionsRequest)(nil),    // 4: warehouse.v1.ListTransactionsRequest
+	(*ListTransactionsResponse)(nil),   // 5: warehouse.v1.ListTransactionsResponse
+	(*GetTransactionRequest)(nil),      // 6: warehouse.v1.GetTransactionRequest
+	(*CreateTransactionRequest)(nil),   // 7: warehouse.v1.CreateTransactionRequest
+	(*ReverseTransactionRequest)(nil),  // 8: warehouse.v1.ReverseTransactionRequest
+	(*ReverseTransactionResponse)(nil), // 9: warehouse.v1.ReverseTransactionResponse
+	(*StreamTransactionsRequest)(nil),  // 10: warehouse.v1.StreamTransactionsRequest
+}
+var file_warehouse_v1_transactions_proto_depIdxs = []int32{
+	0,  // 0: warehouse.v1.Transaction.type:type_name -> warehouse.v1.TransactionType
+	1,  // 1: warehouse.v1.Transaction.status:type_name -> warehouse.v1.TransactionStatus
+	0,  // 2: warehouse.v1.TransactionFilter.type:type_name -> warehouse.v1.TransactionType
+	1,  // 3: warehouse.v1.TransactionFilter.status:type_name -> warehouse.v1.TransactionStatus
+	3,  // 4: warehouse.v1.ListTransactionsRequest.filter:type_name -> warehouse.v1.TransactionFilter
+	2,  // 5: warehouse.v1.ListTransactionsResponse.transactions:type_name -> warehouse.v1.Transaction
+	0,  // 6: warehouse.v1.CreateTransactionRequest.type:type_name -> warehouse.v1.TransactionType
+	2,  // 7: warehouse.v1.ReverseTransactionResponse.original:type_name -> warehouse.v1.Transaction
+	2,  // 8: warehouse.v1.ReverseTransactionResponse.reversal:type_name -> warehouse.v1.Transaction
+	3,  // 9: warehouse.v1.StreamTransactionsRequest.filter:type_name -> warehouse.v1.TransactionFilter
+	4,  // 10: warehouse.v1.TransactionService.ListTransactions:input_type -> warehouse.v1.ListTransactionsRequest
+	6,  // 11: warehouse.v1.TransactionService.GetTransaction:input_type -> warehouse.v1.GetTransactionRequest
+	7,  // 12: warehouse.v1.TransactionService.CreateTransaction:input_type -> warehouse.v1.CreateTransactionRequest
+	8,  // 13: warehouse.v1.TransactionService.ReverseTransaction:input_type -> warehouse.v1.ReverseTransactionRequest
+	10, // 14: warehouse.v1.TransactionService.StreamTransactions:input_type -> warehouse.v1.StreamTransactionsRequest
+	5,  // 15: warehouse.v1.TransactionService.ListTransactions:output_type -> warehouse.v1.ListTransactionsResponse
+	2,  // 16: warehouse.v1.TransactionService.GetTransaction:output_type -> warehouse.v1.Transaction
+	2,  // 17: warehouse.v1.TransactionService.CreateTransaction:output_type -> warehouse.v1.Transaction
+	9,  // 18: warehouse.v1.TransactionService.ReverseTransaction:output_type -> warehouse.v1.ReverseTransactionResponse
+	2,  // 19: warehouse.v1.TransactionService.StreamTransactions:output_type -> warehouse.v1.Transaction
+	15, // [15:20] is the sub-list for method output_type
+	10, // [10:15] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_warehouse_v1_transactions_proto_init() }
+func file_warehouse_v1_transactions_proto_init() {
+	if File_warehouse_v1_transactions_proto != nil {
+		return
+	}
+	file_warehouse_v1_transactions_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_warehouse_v1_transactions_proto_rawDesc), len(file_warehouse_v1_transactions_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_warehouse_v1_transactions_proto_goTypes,
+		DependencyIndexes: file_warehouse_v1_transactions_proto_depIdxs,
+		EnumInfos:         file_warehouse_v1_transactions_proto_enumTypes,
+		MessageInfos:      file_warehouse_v1_transactions_proto_msgTypes,
+	}.Build()
+	File_warehouse_v1_transactions_proto = out.File
+	file_warehouse_v1_transactions_proto_goTypes = nil
+	file_warehouse_v1_transactions_proto_depIdxs = nil
+}
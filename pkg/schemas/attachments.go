@@ -0,0 +1,23 @@
+package schemas
+
+const (
+	// longest side of generated thumbnails, in pixels
+	AttachmentThumbnailSize = 256
+)
+
+type Attachment struct {
+	UUID            string `json:"uuid"`
+	ItemUUID        string `json:"item_uuid,omitempty"`
+	TransactionUUID string `json:"transaction_uuid,omitempty"`
+	OwnerUUID       string `json:"owner_uuid"`
+	Filename        string `json:"filename"`
+	ContentType     string `json:"content_type"`
+	Size            int64  `json:"size"`
+	HasThumbnail    bool   `json:"has_thumbnail"`
+	CreatedAt       int64  `json:"created_at"`
+}
+
+type GetAttachmentsResponse struct {
+	NResults    int          `json:"n_results"`
+	Attachments []Attachment `json:"attachments"`
+}
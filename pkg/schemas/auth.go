@@ -48,7 +48,8 @@ func RoleFromString(str string) Role {
 type RegisterRequest struct {
 	Username string `validate:"required" json:"username"`
 	Password string `validate:"required" json:"password"`
-	Role     Role   `validate:"omitempty,oneof=user stocker admin" json:"role"`
+	// user, stocker or admin, user if omitted
+	Role Role `validate:"role" json:"role"`
 }
 
 type RegisterResponse struct {
@@ -64,5 +65,13 @@ type LoginRequest struct {
 
 type LoginResponse struct {
 	AccessToken string `json:"access_token"`
-	Expires     int64  `json:"expires"`
+	// unix time the access token expires at
+	Expires int64 `json:"expires"`
+}
+
+type User struct {
+	UUID      string `json:"uuid"`
+	Username  string `json:"username"`
+	Role      Role   `json:"role"`
+	CreatedAt int64  `json:"created_at"`
 }
@@ -0,0 +1,41 @@
+package schemas
+
+type BarcodeFormat string
+
+const (
+	BarcodeFormatEAN8    BarcodeFormat = "ean8"
+	BarcodeFormatUPCA    BarcodeFormat = "upca"
+	BarcodeFormatEAN13   BarcodeFormat = "ean13"
+	BarcodeFormatGTIN14  BarcodeFormat = "gtin14"
+	BarcodeFormatCode128 BarcodeFormat = "code128"
+)
+
+// IsGTIN reports whether the format is one of the numeric GTIN family,
+// which carry a mod-10 check digit.
+func (f BarcodeFormat) IsGTIN() bool {
+	switch f {
+	case BarcodeFormatEAN8, BarcodeFormatUPCA, BarcodeFormatEAN13, BarcodeFormatGTIN14:
+		return true
+	default:
+		return false
+	}
+}
+
+type AddBarcodeRequest struct {
+	// GTINs are stored as GTIN-14, padded with zeros
+	Code string `validate:"required" json:"code"`
+	// Format is detected from the code if omitted
+	Format BarcodeFormat `validate:"omitempty,oneof=ean8 upca ean13 gtin14 code128" json:"format"`
+}
+
+type Barcode struct {
+	ItemUUID  string        `json:"item_uuid"`
+	Code      string        `json:"code"`
+	Format    BarcodeFormat `json:"format"`
+	CreatedAt int64         `json:"created_at"`
+}
+
+type GetItemBarcodesResponse struct {
+	NResults int       `json:"n_results"`
+	Barcodes []Barcode `json:"barcodes"`
+}
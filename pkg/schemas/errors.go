@@ -0,0 +1,55 @@
+package schemas
+
+type FieldError struct {
+	// JSON or query name, with the index for list elements, e.g. "items[2]"
+	Field string `json:"field"`
+	// the failed `validate` rule
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type ValidationErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// Problem is an RFC 7807 error response, served as application/problem+json.
+type Problem struct {
+	// one of the ProblemType constants, clients should branch on it
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// path of the request
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+const (
+	// nothing more specific than the status code
+	ProblemTypeBlank      = "about:blank"
+	ProblemTypeValidation = "/problems/validation"
+	// unique constraint, e.g. a taken username or SKU
+	ProblemTypeConflict = "/problems/conflict"
+	// a referenced record does not exist
+	ProblemTypeMissingReference = "/problems/missing-reference"
+	// the record is still referenced by others
+	ProblemTypeStillReferenced = "/problems/still-referenced"
+	// check and not-null constraints
+	ProblemTypeConstraint = "/problems/constraint-violation"
+	ProblemTypeTimeout    = "/problems/timeout"
+
+	ProblemTypeItemArchived = "/problems/item-archived"
+	// a withdrawal of more than there is in stock
+	ProblemTypeNotEnoughStock = "/problems/not-enough-stock"
+	// the version in If-Match is outdated, fetch the item again
+	ProblemTypeItemModified = "/problems/item-modified"
+	// the Idempotency-Key was sent with another request before
+	ProblemTypeIdempotencyKeyMismatch = "/problems/idempotency-key-mismatch"
+	// the first request with the Idempotency-Key hasn't finished yet
+	ProblemTypeIdempotencyKeyInProgress = "/problems/idempotency-key-in-progress"
+	// reversals, failed and already reversed transactions
+	ProblemTypeNotReversible = "/problems/not-reversible"
+)
@@ -0,0 +1,37 @@
+package schemas
+
+type EventType string
+
+const (
+	EventItemCreated        EventType = "item.created"
+	EventItemUpdated        EventType = "item.updated"
+	EventItemDeleted        EventType = "item.deleted"
+	EventTransactionCreated EventType = "transaction.created"
+)
+
+type EventsRequest struct {
+	ItemUUID string      `validate:"omitempty,uuid" json:"item_uuid" query:"item_uuid"`
+	Types    []EventType `validate:"dive,oneof=item.created item.updated item.deleted transaction.created" json:"type" query:"type"`
+	// for clients that can't set the Last-Event-ID header, which wins
+	LastEventID *int64 `validate:"omitempty,min=0" json:"last_event_id" query:"last_event_id"`
+}
+
+type Event struct {
+	// increasing, resume with it in Last-Event-ID
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	ItemUUID  string    `json:"item_uuid"`
+	CreatedAt int64     `json:"created_at"`
+	// as they were right after the change, only the uuid is left of a
+	// deleted item
+	Item        *Item        `json:"item,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
+
+type EventsTicketResponse struct {
+	// for the ticket parameter of the streams, once they're open it
+	// doesn't matter that it expires
+	Ticket string `json:"ticket"`
+	// unix time the ticket expires at
+	Expires int64 `json:"expires"`
+}
@@ -0,0 +1,9 @@
+package schemas
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatNDJSON ExportFormat = "ndjson"
+	ExportFormatXLSX   ExportFormat = "xlsx"
+)
@@ -0,0 +1,21 @@
+package schemas
+
+type GraphQLRequest struct {
+	Query         string         `validate:"required" json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type GraphQLResponse struct {
+	// missing if the query couldn't run at all
+	Data   any            `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+	// of the field that failed, e.g. ["items", "nodes", 0, "owner"]
+	Path []any `json:"path,omitempty"`
+	// the problem the REST API would answer with
+	Extensions *Problem `json:"extensions,omitempty"`
+}
@@ -0,0 +1,60 @@
+package schemas
+
+const (
+	// files with more rows are imported in the background
+	ImportSyncMaxRows = 200
+	ImportMaxRows     = 10000
+)
+
+type ImportAction string
+
+const (
+	ImportActionCreated   ImportAction = "created"
+	ImportActionUpdated   ImportAction = "updated"
+	ImportActionUnchanged ImportAction = "unchanged"
+)
+
+type ImportRow struct {
+	// as in the spreadsheet, the header is row 1
+	Row int `json:"row"`
+	// what was done, or would be done on a dry run or without the errors
+	Action   ImportAction `json:"action,omitempty"`
+	ItemUUID string       `json:"item_uuid,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// e.g. an opening quantity of an item that exists already
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ImportResult of POST /items/import, nothing is written if NFailed isn't
+// zero.
+type ImportResult struct {
+	DryRun     bool        `json:"dry_run"`
+	NRows      int         `json:"n_rows"`
+	NCreated   int         `json:"n_created"`
+	NUpdated   int         `json:"n_updated"`
+	NUnchanged int         `json:"n_unchanged"`
+	NFailed    int         `json:"n_failed"`
+	Rows       []ImportRow `json:"rows"`
+}
+
+type ImportJobStatus string
+
+const (
+	ImportJobStatusPending   ImportJobStatus = "pending"
+	ImportJobStatusRunning   ImportJobStatus = "running"
+	ImportJobStatusSucceeded ImportJobStatus = "succeeded"
+	ImportJobStatusFailed    ImportJobStatus = "failed"
+)
+
+type ImportJob struct {
+	UUID     string          `json:"uuid"`
+	Filename string          `json:"filename"`
+	DryRun   bool            `json:"dry_run"`
+	Status   ImportJobStatus `json:"status"`
+	NRows    int             `json:"n_rows"`
+	// set once the job is finished
+	Result     *ImportResult `json:"result,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  int64         `json:"created_at"`
+	FinishedAt int64         `json:"finished_at,omitempty"`
+}
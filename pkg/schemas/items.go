@@ -0,0 +1,88 @@
+package schemas
+
+const (
+	GetItemsRequestDefaultLimit = 50
+)
+
+type CreateItemRequest struct {
+	Name string `validate:"required" json:"name"`
+	SKU  string `json:"sku"`
+	// TODO: anything else?
+}
+
+type CreateItemResponse struct {
+	UUID      string
+	Name      string
+	SKU       string
+	CreatedAt int64
+}
+
+type GetItemsRequest struct {
+	Limit  int `validate:"min=0,max=100" json:"limit" query:"limit"`
+	Offset int `validate:"min=0" json:"offset" query:"offset"`
+	// next_cursor or prev_cursor of a previous response, replaces offset
+	Cursor string `json:"cursor" query:"cursor"`
+	// exclude (default), include or only
+	Archived string `validate:"omitempty,oneof=exclude include only" json:"archived" query:"archived"`
+	// case-insensitive substrings
+	Name string `json:"name" query:"name"`
+	SKU  string `json:"sku" query:"sku"`
+	// full-text search over name and SKU, tolerating typos
+	Query       string `json:"q" query:"q"`
+	MinQuantity *int   `json:"min_quantity" query:"min_quantity"`
+	MaxQuantity *int   `json:"max_quantity" query:"max_quantity"`
+	// unix timestamps, after is inclusive and before is exclusive
+	CreatedAfter  int64 `validate:"min=0" json:"created_after" query:"created_after"`
+	CreatedBefore int64 `validate:"min=0" json:"created_before" query:"created_before"`
+	UpdatedAfter  int64 `validate:"min=0" json:"updated_after" query:"updated_after"`
+	UpdatedBefore int64 `validate:"min=0" json:"updated_before" query:"updated_before"`
+	// comma separated fields, "-" in front for descending order, e.g. "-quantity,name".
+	// Defaults to relevance when searching and to creation order otherwise
+	Sort string `json:"sort" query:"sort"`
+}
+
+type Item struct {
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	SKU      string `json:"sku,omitempty"`
+	Quantity int    `json:"quantity"`
+	// set for variants only
+	ProductUUID string            `json:"product_uuid,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+	ArchivedAt  int64             `json:"archived_at,omitempty"`
+	// the same as in the ETag header
+	Version int `json:"version,omitempty"`
+}
+
+type GetItemsResponse struct {
+	NResults int    `json:"n_results"`
+	Items    []Item `json:"items"`
+	// parents of the variants found in Items, with stock of all their variants
+	Products   []ProductStock `json:"products,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+type PatchRequest struct {
+	Name     *string `json:"name"`
+	SKU      *string `json:"sku"`
+	Quantity *int32  `validate:"omitempty,min=0" json:"quantity"`
+}
+
+// kind of the stock movements made by setting the quantity directly, the
+// rest are named after their transaction type
+const StockMovementAdjustment = "adjustment"
+
+type GetItemStockRequest struct {
+	// unix timestamp, now if zero
+	AsOf int64 `validate:"min=0" json:"as_of" form:"as_of" query:"as_of"`
+}
+
+// ItemStock is the quantity summed up from the stock ledger.
+type ItemStock struct {
+	ItemUUID       string `json:"item_uuid"`
+	AsOf           int64  `json:"as_of"`
+	Quantity       int    `json:"quantity"`
+	NMovements     int    `json:"n_movements"`
+	LastMovementAt int64  `json:"last_movement_at,omitempty"`
+}
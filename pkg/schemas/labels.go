@@ -0,0 +1,22 @@
+package schemas
+
+const (
+	LabelSheetMaxLabels = 500
+)
+
+type GetLabelRequest struct {
+	// code128 or qr, code128 by default
+	Symbology string `validate:"omitempty,oneof=code128 qr" query:"symbology"`
+	// png or svg, png by default
+	Format string `validate:"omitempty,oneof=png svg" query:"format"`
+	// pixels per module
+	Scale int `validate:"omitempty,min=1,max=20" query:"scale"`
+}
+
+type LabelSheetRequest struct {
+	// a4 or thermal_4x6, a4 by default
+	Layout    string   `validate:"omitempty,oneof=a4 thermal_4x6" json:"layout"`
+	Symbology string   `validate:"omitempty,oneof=code128 qr" json:"symbology"`
+	Items     []string `validate:"dive,uuid" json:"items"`
+	Locations []string `validate:"dive,required" json:"locations"`
+}
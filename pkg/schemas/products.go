@@ -0,0 +1,47 @@
+package schemas
+
+const (
+	GetProductsRequestDefaultLimit = 50
+	// upper bound for the size of the generated variant matrix
+	ProductMaxVariants = 100
+)
+
+type ProductOption struct {
+	Name   string   `validate:"required" json:"name"`
+	Values []string `validate:"required,min=1,max=100,dive,required" json:"values"`
+}
+
+type CreateProductRequest struct {
+	Name string `validate:"required" json:"name"`
+	// used as a prefix for SKUs of the variants
+	SKU     string          `json:"sku"`
+	Options []ProductOption `validate:"required,min=1,max=5,dive" json:"options"`
+}
+
+type GetProductsRequest struct {
+	Limit  int `validate:"min=0,max=100" json:"limit" query:"limit"`
+	Offset int `validate:"min=0" json:"offset" query:"offset"`
+}
+
+type Product struct {
+	UUID      string          `json:"uuid"`
+	Name      string          `json:"name"`
+	SKU       string          `json:"sku,omitempty"`
+	Options   []ProductOption `json:"options"`
+	NVariants int             `json:"n_variants"`
+	Quantity  int             `json:"quantity"`
+	Variants  []Item          `json:"variants"`
+	CreatedAt int64           `json:"created_at"`
+}
+
+type GetProductsResponse struct {
+	NResults int       `json:"n_results"`
+	Products []Product `json:"products"`
+}
+
+type ProductStock struct {
+	UUID      string `json:"uuid"`
+	Name      string `json:"name"`
+	NVariants int    `json:"n_variants"`
+	Quantity  int    `json:"quantity"`
+}
@@ -0,0 +1,105 @@
+package schemas
+
+type TransactionType string
+
+const (
+	TransactionTypeRestock  TransactionType = "restock"
+	TransactionTypeWithdraw TransactionType = "withdraw"
+)
+
+type TransactionStatus string
+
+const (
+	TransactionStatusSucceeded TransactionStatus = "succeeded"
+	TransactionStatusFailed    TransactionStatus = "failed"
+	// undone by another transaction
+	TransactionStatusReversed TransactionStatus = "reversed"
+)
+
+type CreateTransactionRequest struct {
+	Type TransactionType `validate:"required,oneof=restock withdraw" json:"type"`
+	// either the item or one of its barcodes
+	ItemUUID string `validate:"omitempty,uuid" json:"item_uuid"`
+	Barcode  string `validate:"required_without=ItemUUID,excluded_with=ItemUUID" json:"barcode"`
+	// stored as an integer
+	Amount int `validate:"required,min=1,max=2147483647" json:"amount"`
+}
+
+type BatchMode string
+
+const (
+	// the first failed line rolls back the whole batch
+	BatchModeAtomic BatchMode = "atomic"
+	// every line succeeds or fails on its own
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+type CreateTransactionBatchRequest struct {
+	// atomic if empty
+	Mode  BatchMode                  `validate:"omitempty,oneof=atomic best_effort" json:"mode"`
+	Lines []CreateTransactionRequest `validate:"required,min=1,max=500,dive" json:"lines"`
+}
+
+type CreateTransactionBatchResponse struct {
+	Mode       BatchMode              `json:"mode"`
+	NSucceeded int                    `json:"n_succeeded"`
+	NFailed    int                    `json:"n_failed"`
+	Lines      []TransactionBatchLine `json:"lines"`
+}
+
+type TransactionBatchLine struct {
+	Index int `json:"index"`
+	// what the line would get as a single request
+	Status int `json:"status"`
+	// also set for failed withdrawals, they are recorded
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Error       *Problem     `json:"error,omitempty"`
+}
+
+const GetAllTransactionsRequestDefaultLimit = 50
+
+type GetAllTransactionsRequest struct {
+	Offset int `validate:"min=0" json:"offset" form:"offset" query:"offset"`
+	Limit  int `validate:"min=0,max=100" json:"limit" form:"limit" query:"limit"`
+	// next_cursor or prev_cursor of a previous response, replaces offset
+	Cursor string `json:"cursor" form:"cursor" query:"cursor"`
+
+	Type     TransactionType   `validate:"omitempty,oneof=restock withdraw" json:"type" form:"type" query:"type"`
+	Status   TransactionStatus `validate:"omitempty,oneof=succeeded failed reversed" json:"status" form:"status" query:"status"`
+	UserUUID string            `validate:"omitempty,uuid" json:"user_uuid" form:"user_uuid" query:"user_uuid"`
+	ItemUUID string            `validate:"omitempty,uuid" json:"item_uuid" form:"item_uuid" query:"item_uuid"`
+	// unix timestamps, after is inclusive and before is exclusive
+	CreatedAfter  int64 `validate:"min=0" json:"created_after" form:"created_after" query:"created_after"`
+	CreatedBefore int64 `validate:"min=0" json:"created_before" form:"created_before" query:"created_before"`
+	MinAmount     *int  `validate:"omitempty,min=0" json:"min_amount" form:"min_amount" query:"min_amount"`
+	MaxAmount     *int  `validate:"omitempty,min=0" json:"max_amount" form:"max_amount" query:"max_amount"`
+}
+
+type GetAllTransactionsResponse struct {
+	NResult      int           `json:"n_result"`
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+	PrevCursor   string        `json:"prev_cursor,omitempty"`
+}
+
+type Transaction struct {
+	Type      TransactionType   `json:"type"`
+	UUID      string            `json:"uuid"`
+	OwnerUUID string            `json:"owner_uuid"`
+	ItemUUID  string            `json:"item_uuid"`
+	Amount    int               `json:"amount"`
+	Status    TransactionStatus `json:"status"`
+	Reason    string            `json:"reason,omitempty"` // why it failed or was reversed
+	CreatedAt int64             `json:"created_at"`
+	// set for the compensating entries
+	ReversalOf string `json:"reversal_of,omitempty"`
+}
+
+type ReverseTransactionRequest struct {
+	Reason string `validate:"required,max=500" json:"reason"`
+}
+
+type ReverseTransactionResponse struct {
+	Original Transaction `json:"original"`
+	Reversal Transaction `json:"reversal"`
+}
@@ -0,0 +1,75 @@
+package schemas
+
+const GetWebhookDeliveriesRequestDefaultLimit = 50
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// gave up after the last attempt
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+type CreateWebhookRequest struct {
+	URL string `validate:"required,http_url,max=2048" json:"url"`
+	// all of them if empty
+	EventTypes []EventType `validate:"dive,oneof=item.created item.updated item.deleted transaction.created" json:"event_types"`
+	// generated if empty
+	Secret string `validate:"omitempty,min=16,max=256" json:"secret"`
+}
+
+type PatchWebhookRequest struct {
+	URL *string `validate:"omitempty,http_url,max=2048" json:"url"`
+	// an empty list for all of them
+	EventTypes *[]EventType `validate:"omitempty,dive,oneof=item.created item.updated item.deleted transaction.created" json:"event_types"`
+	Secret     *string      `validate:"omitempty,min=16,max=256" json:"secret"`
+	// enabling again resets the failures
+	Enabled *bool `json:"enabled"`
+}
+
+type Webhook struct {
+	UUID       string      `json:"uuid"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"event_types"`
+	// only in the response to the creation
+	Secret  string `json:"secret,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// failed attempts in a row; a delivery given up on disables the webhook
+	Failures   int   `json:"failures"`
+	DisabledAt int64 `json:"disabled_at,omitempty"`
+	CreatedAt  int64 `json:"created_at"`
+}
+
+type GetWebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type GetWebhookDeliveriesRequest struct {
+	Limit  int                   `validate:"min=0,max=100" json:"limit" query:"limit"`
+	Offset int                   `validate:"min=0" json:"offset" query:"offset"`
+	Status WebhookDeliveryStatus `validate:"omitempty,oneof=pending succeeded failed" json:"status" query:"status"`
+}
+
+type WebhookDelivery struct {
+	UUID        string                `json:"uuid"`
+	WebhookUUID string                `json:"webhook_uuid"`
+	Status      WebhookDeliveryStatus `json:"status"`
+	Attempts    int                   `json:"attempts"`
+	// of a pending delivery
+	NextAttemptAt int64 `json:"next_attempt_at,omitempty"`
+	// of the last attempt, no status if the receiver couldn't be reached
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	// the delivery this one replays
+	ReplayOf    string `json:"replay_of,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	DeliveredAt int64  `json:"delivered_at,omitempty"`
+	// the body that is sent
+	Event Event `json:"event"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	NResults   int               `json:"n_results"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}